@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"acy.com/api/src/db"
+	"acy.com/api/src/dependencies"
+	"github.com/urfave/cli/v2"
+)
+
+// api key management for service-to-service clients, run from the project root so .env is found:
+//
+//	go run ./src/cmd/apikeys create --name nightly-import --scope editor --expires-in 720h
+//	go run ./src/cmd/apikeys list
+//	go run ./src/cmd/apikeys revoke 3
+func main() {
+	app := &cli.App{
+		Name:  "apikeys",
+		Usage: "manage api keys of service-to-service clients",
+		Commands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "create an api key and print it once",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Required: true, Usage: "name of the client using the key"},
+					&cli.StringSliceFlag{Name: "scope", Required: true, Usage: "reader, editor or admin, can be repeated"},
+					&cli.DurationFlag{Name: "expires-in", Usage: "lifetime of the key, no expiry when omitted"},
+				},
+				Action: withMigrations(createApiKey),
+			},
+			{
+				Name:   "list",
+				Usage:  "list api keys",
+				Action: withMigrations(listApiKeys),
+			},
+			{
+				Name:      "revoke",
+				Usage:     "revoke an api key",
+				ArgsUsage: "<id>",
+				Action:    withMigrations(revokeApiKey),
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// withMigrations brings the schema up to date before running action
+func withMigrations(action cli.ActionFunc) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if err := db.MigratePostgres(db.PostgresDbProvider()); err != nil {
+			return err
+		}
+		return action(c)
+	}
+}
+
+func createApiKey(c *cli.Context) error {
+	scopes := c.StringSlice("scope")
+	for _, scope := range scopes {
+		if scope != "reader" && scope != "editor" && scope != "admin" {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	var expiresAt *time.Time
+	if c.Duration("expires-in") > 0 {
+		expiry := time.Now().Add(c.Duration("expires-in"))
+		expiresAt = &expiry
+	}
+
+	apiKeyService := dependencies.InitializeApiKeyService()
+	apiKey, key, err := (*apiKeyService).Create(c.String("name"), scopes, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created api key %d (%s), store it now, it can not be shown again:\n%s\n", apiKey.Id, apiKey.Name, key)
+	return nil
+}
+
+func listApiKeys(c *cli.Context) error {
+	apiKeyService := dependencies.InitializeApiKeyService()
+	apiKeys, err := (*apiKeyService).FindAll()
+	if err != nil {
+		return err
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES\tLAST USED\tREVOKED")
+	for _, apiKey := range apiKeys {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", apiKey.Id, apiKey.Name, apiKey.Prefix, strings.Join(apiKey.Scopes, ","),
+			formatTime(apiKey.ExpiresAt), formatTime(apiKey.LastUsedAt), formatTime(apiKey.RevokedAt))
+	}
+	return writer.Flush()
+}
+
+func revokeApiKey(c *cli.Context) error {
+	var id uint
+	if _, err := fmt.Sscan(c.Args().First(), &id); err != nil || id == 0 {
+		return errors.New("usage: apikeys revoke <id>")
+	}
+
+	apiKeyService := dependencies.InitializeApiKeyService()
+	if err := (*apiKeyService).Revoke(id); err != nil {
+		return err
+	}
+	fmt.Printf("revoked api key %d\n", id)
+	return nil
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+var albumBatchService = dependencies.InitializeAlbumBatchService()
+
+// BatchAlbums @Summary Create, update and delete albums in batch
+// @ID batch-albums
+// @Description Apply up to 100 create/update/delete operations in one request.
+// @Description In all_or_nothing mode (default) any failure rolls back the whole batch, in best_effort mode only the failing operations are discarded.
+// @Description Every operation is reported in results with its own status code.
+// @Tags Album
+// @Accept  json
+// @Produce json
+// @Param data body models.BatchAlbumDto true "batch operations"
+// @Success 200 {object} models.BatchAlbumResponse "all operations applied"
+// @Success 207 {object} models.BatchAlbumResponse "best_effort batch with failed operations"
+// @Failure 400 {object} models.Error
+// @Failure 422 {object} models.BatchAlbumResponse "all_or_nothing batch rolled back"
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/batch [post]
+func BatchAlbums(c *gin.Context) {
+	var batch models.BatchAlbumDto
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := (*albumBatchService).Execute(&batch, auditActor(c))
+
+	switch {
+	case response.Failed == 0:
+		c.IndentedJSON(http.StatusOK, response)
+	case response.Mode == models.BatchModeBestEffort && response.Succeeded > 0:
+		c.IndentedJSON(http.StatusMultiStatus, response)
+	default:
+		c.IndentedJSON(http.StatusUnprocessableEntity, response)
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var contentTypes = map[string]string{
+	libs.ContentFormatMarkdown: "text/markdown; charset=utf-8",
+	libs.ContentFormatHtml:     "text/html; charset=utf-8",
+	libs.ContentFormatText:     "text/plain; charset=utf-8",
+}
+
+// GetAlbumContent @Summary Get album content
+// @ID get-album-content
+// @Description Stream the content of an album as markdown, sanitized html or plain text, with Range requests for parts of it
+// @Tags Album
+// @Produce text/markdown
+// @Produce text/html
+// @Produce text/plain
+// @Param id path string true "album Id"
+// @Param content_format query string false "markdown, html or text" default(markdown)
+// @Param Range header string false "byte range, e.g. bytes=0-1023"
+// @Success 200 {string} string
+// @Success 206 {string} string
+// @Success 304
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 416 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content [get]
+func GetAlbumContent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+	format := c.DefaultQuery("content_format", libs.ContentFormatMarkdown)
+	if !libs.IsContentFormat(format) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: fmt.Errorf("%w: %s", errInvalidContentFormat, format).Error()})
+		return
+	}
+
+	content, album, err := (*albumMongoService).OpenContent(uint(id), format)
+	if errors.Is(err, repositories.ErrAlbumContentNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	defer content.Close()
+
+	// the etag changes with every write, so a range of one version is never joined to a range of another
+	c.Header("ETag", fmt.Sprintf(`"%x-%s"`, album.UpdatedAt.UnixNano(), format))
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Content-Type", contentTypes[format])
+	http.ServeContent(c.Writer, c.Request, "", album.UpdatedAt, content)
+}
+
+// PutAlbumContent @Summary Replace album content
+// @ID put-album-content
+// @Description Replace the content of an album with the markdown in the request body. The body is streamed, content
+// @Description larger than CONTENT_INLINE_MAX_BYTES is stored in GridFS while it is received.
+// @Tags Album
+// @Accept text/markdown
+// @Accept text/plain
+// @Produce json
+// @Param id path string true "album Id"
+// @Param data body string true "markdown content"
+// @Success 200 {object} models.AlbumContentResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content [put]
+func PutAlbumContent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+	album, err := (*albumService).FindById(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	if album.Id == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: "Album not found"})
+		return
+	}
+
+	before := (*albumMongoService).FindById(album.Id)
+	content := entities.AlbumMongoDB{AlbumId: album.Id, Name: album.Title}
+	if err := (*albumMongoService).WriteContent(&content, c.Request.Body, middlewares.Subject(c)); middlewares.IsBodyTooLarge(err) {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.Error{Message: fmt.Sprintf("Request body exceeds %d bytes", libs.ContentStorageProvider().MaxBytes)})
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	if err := (*contentRevisionService).Record(before, content, middlewares.Subject(c)); err != nil {
+		c.Error(err)
+	}
+	var beforeContent *entities.AlbumMongoDB
+	if before.AlbumId != 0 {
+		beforeContent = &before
+	}
+	// the content is written already, an album that is not marked updated is logged with the request
+	if _, err := (*albumService).ContentWritten(album.Id, beforeContent, &content, entities.AuditActionUpdate, auditActor(c)); err != nil {
+		c.Error(err)
+	}
+
+	c.IndentedJSON(http.StatusOK, models.AlbumContentResponse{AlbumId: album.Id, ContentSize: content.ContentSize, UpdatedAt: content.UpdatedAt, UpdatedBy: content.UpdatedBy})
+}
@@ -1,14 +1,23 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"acy.com/api/src/dependencies"
 	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
 	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -23,6 +32,8 @@ var albumService = dependencies.InitializeAlbumService()
 // var albumMongoService services.IAlbumMongoService = services.AlbumMongoService(&albumMongoDBRepository)
 var albumMongoService = dependencies.InitializeAlbumMongoDBService()
 
+var errInvalidContentFormat = errors.New("content_format must be markdown, html or text")
+
 // GetAlbums @Summary Get Albums list
 // @ID get-albums-list
 // @Description Get Albums list with pagination
@@ -31,10 +42,29 @@ var albumMongoService = dependencies.InitializeAlbumMongoDBService()
 // @Produce json
 // @Param page query int true "pagination current page" default(0)
 // @Param page_size query int true "pagination page_size" default(0)
+// @Param updated_since query string false "only albums updated after this RFC 3339 time, ordered by updated_at"
+// @Param cursor query string false "continue the listing ordered by updated_at after a page, from the X-Next-Cursor header; page is ignored"
+// @Param genre query []string false "genre slug, repeat to require several" collectionFormat(multi)
+// @Param tag query []string false "tag, repeat to require several" collectionFormat(multi)
+// @Param artist_id query int false "artist Id"
+// @Param price_currency query string false "ISO 4217 code, only albums priced in it; min_price and max_price are amounts of it and need it"
+// @Param min_price query number false "lowest price, inclusive"
+// @Param max_price query number false "highest price, exclusive"
+// @Param include query string false "comma separated related resources to embed: artist, tracks"
+// @Param currency query string false "ISO 4217 code to convert prices to, see /exchange-rates"
+// @Param Accept-Currency header string false "currencies to convert prices to in order of preference, e.g. EUR, GBP;q=0.5"
+// @Param content_format query string false "representation of the content: markdown (the source), sanitized html or plain text" Enums(markdown, html, text) default(markdown)
 // @Success 200 {object} []models.AlbumResponse
+// @Header 200 {string} X-Next-Cursor "cursor of the next page of a listing ordered by updated_at, absent on the last page"
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /albums [get]
 func GetAlbums(c *gin.Context) {
-	var response []models.AlbumResponse
 	page, _ := strconv.Atoi(c.Query("page"))
 
     if page == 0 {
@@ -43,27 +73,78 @@ func GetAlbums(c *gin.Context) {
 
 	pageSize, _ := strconv.Atoi(c.Query("page_size"))
 
-	albums, err := (*albumService).FindAll(page, pageSize)
+	filter, err := albumFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := repositories.ParseAlbumCursor(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+			return
+		}
+		filter.After = &cursor
+	}
+
+	albums, err := (*albumService).FindAll(filter, page, pageSize)
 	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
 	}
-	albumsInMongo := (*albumMongoService).FindAll()
 
-	// create a lookup map
-	albumsInMongoLookup := map[uint]string{}
-	// convert the albumsInMongo into a map, the key is ObjectId(in postgres it is ContentId), value is content in mongodb
-	for _, v := range albumsInMongo {
-		albumsInMongoLookup[v.AlbumId] = v.Content
+	// a full page of a listing ordered by updated_at may have a next one
+	if (filter.UpdatedSince != nil || filter.After != nil) && pageSize > 0 && len(albums) == pageSize {
+		c.Header("X-Next-Cursor", repositories.NewAlbumCursor(albums[len(albums)-1]).String())
 	}
 
-	for _, v := range albums {
-		if val, ok := albumsInMongoLookup[v.Id]; ok {
-			response = append(response, models.AlbumResponse{Id: v.Id, Title: v.Title, Artist: v.Artist, Price: v.Price, HasRead: v.HasRead, Content: val})
-		} else {
-			response = append(response, models.AlbumResponse{Id: v.Id, Title: v.Title, Artist: v.Artist, Price: v.Price, HasRead: v.HasRead})
-		}
+	response, err := albumResponses(c, albums)
+	if err != nil {
+		albumResponsesError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// GetAlbumFacets @Summary Get Album facets
+// @ID get-album-facets
+// @Description Count the albums matching the filters per genre, tag, artist and price bucket
+// @Tags Album
+// @Produce json
+// @Param updated_since query string false "only albums updated after this RFC 3339 time"
+// @Param genre query []string false "genre slug, repeat to require several" collectionFormat(multi)
+// @Param tag query []string false "tag, repeat to require several" collectionFormat(multi)
+// @Param artist_id query int false "artist Id"
+// @Param price_currency query string false "ISO 4217 code, only albums priced in it; min_price and max_price are amounts of it and need it"
+// @Param min_price query number false "lowest price, inclusive"
+// @Param max_price query number false "highest price, exclusive"
+// @Success 200 {object} models.AlbumFacetsResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/facets [get]
+func GetAlbumFacets(c *gin.Context) {
+	filter, err := albumFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
 	}
 
+	facets, err := (*albumService).Facets(filter)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := models.AlbumFacetsResponse{Total: facets.Total}
+	response.Genres = facetCounts(facets.Genres)
+	response.Tags = facetCounts(facets.Tags)
+	response.Artists = facetCounts(facets.Artists)
+	response.Prices = currencyPriceBuckets(facets.Prices)
 	c.IndentedJSON(http.StatusOK, response)
 }
 
@@ -73,8 +154,18 @@ func GetAlbums(c *gin.Context) {
 // @Tags Album
 // @Produce json
 // @Param id path string true "album Id"
+// @Param include query string false "comma separated related resources to embed: artist, tracks"
+// @Param currency query string false "ISO 4217 code to convert prices to, see /exchange-rates"
+// @Param Accept-Currency header string false "currencies to convert prices to in order of preference, e.g. EUR, GBP;q=0.5"
+// @Param content_format query string false "representation of the content: markdown (the source), sanitized html or plain text" Enums(markdown, html, text) default(markdown)
 // @Success 200 {object} models.AlbumResponse
+// @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /albums/{id} [get]
 func GetAlbumById(c *gin.Context) {
 	value := c.Param("id")
@@ -83,31 +174,43 @@ func GetAlbumById(c *gin.Context) {
 	if err != nil {
 		// log.Fatalln("err",err)
 		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
 	}
 
-	album, _ := (*albumService).FindById(uint(id))
-	// objectId, err := primitive.ObjectIDFromHex(album.ContentId);
-
-	albumInMongoDb := (*albumMongoService).FindById(album.Id)
-
-	response := models.AlbumResponse{Id: album.Id, Title: album.Title, Artist: album.Artist, Price: album.Price, HasRead: album.HasRead, Content: albumInMongoDb.Content}
+	album, err := (*albumService).FindById(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	if album.Id == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: repositories.ErrAlbumNotFound.Error()})
+		return
+	}
 
+	responses, err := albumResponses(c, []entities.Album{album})
 	if err != nil {
-		// log.Fatalln("err",err)
-		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		albumResponsesError(c, err)
+		return
 	}
 
-	c.IndentedJSON(http.StatusOK, response)
+	c.IndentedJSON(http.StatusOK, responses[0])
 }
 
 // CreateAlbum @Summary Create new album
 // @ID create-new-album
-// @Description Create new album
+// @Description Create new album, unknown genres are rejected and artists are created from their name when needed
 // @Tags Album
 // @Produce json
 // @Param data body models.CreateAlbumDto true "album data"
 // @Success 200 {object} entities.Album
+// @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /albums [post]
 func CreateAlbum(c *gin.Context) {
 	var newAlbum models.CreateAlbumDto
@@ -118,22 +221,132 @@ func CreateAlbum(c *gin.Context) {
 		return
 	}
 
-	album := entities.Album{Title: newAlbum.Title, Artist: newAlbum.Artist, Price: newAlbum.Price}
-	album, err := (*albumService).Create(&album)
+	if newAlbum.Currency == "" {
+		newAlbum.Currency = libs.DefaultCurrency()
+	}
+	if err := libs.ValidatePrice(*newAlbum.Price, newAlbum.Currency); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	genres, err := (*genreService).FindBySlugs(newAlbum.Genres)
+	if errors.Is(err, repositories.ErrGenreNotFound) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	artist, err := (*artistService).Resolve(newAlbum.ArtistId, newAlbum.Artist, middlewares.Subject(c))
+	if errors.Is(err, repositories.ErrArtistNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
 	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
 	}
 
-	contentId := (*albumMongoService).Create(&entities.AlbumMongoDB{ ID: primitive.NewObjectID(), Name: newAlbum.Title, Content: newAlbum.Content, AlbumId: album.Id})
+	tracks := []entities.Track{}
+	for _, track := range newAlbum.Tracks {
+		tracks = append(tracks, entities.Track{Title: track.Title, DurationSeconds: track.DurationSeconds, Number: track.Number, DiscNumber: track.DiscNumber})
+	}
 
-	if contentId == "" {
-		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Unable to save content to db"})
+	album := entities.Album{Title: newAlbum.Title, ArtistId: artist.Id, Price: *newAlbum.Price, Currency: newAlbum.Currency, Genres: genres, Tags: newAlbum.Tags, Tracks: tracks}
+	content := entities.AlbumMongoDB{ ID: primitive.NewObjectID(), Name: newAlbum.Title, Content: newAlbum.Content}
+	album, err = (*albumService).Create(&album, &content, auditActor(c))
+	album.Artist = &artist
+	if errors.Is(err, services.ErrAlbumContentNotSaved) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
 		return
 	}
 
+	if err := (*contentRevisionService).Record(entities.AlbumMongoDB{}, content, middlewares.Subject(c)); err != nil {
+		c.Error(err)
+	}
+
 	c.IndentedJSON(http.StatusCreated, album)
 }
 
+// UpdateAlbum @Summary Update album
+// @ID update-album
+// @Description Replace the title, artist, price, genres and tags of an album, unknown genres are rejected and artists are created from their name when needed
+// @Tags Album
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param data body models.UpdateAlbumDto true "album data"
+// @Success 200 {object} entities.Album
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id} [put]
+func UpdateAlbum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+
+	var changes models.UpdateAlbumDto
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	if changes.Currency == "" {
+		changes.Currency = libs.DefaultCurrency()
+	}
+	if err := libs.ValidatePrice(*changes.Price, changes.Currency); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	genres, err := (*genreService).FindBySlugs(changes.Genres)
+	if errors.Is(err, repositories.ErrGenreNotFound) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	artist, err := (*artistService).Resolve(changes.ArtistId, changes.Artist, middlewares.Subject(c))
+	if errors.Is(err, repositories.ErrArtistNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	album := entities.Album{Id: uint(id), Title: changes.Title, ArtistId: artist.Id, Price: *changes.Price, Currency: changes.Currency, Genres: genres, Tags: changes.Tags}
+	album, err = (*albumService).Update(&album, auditActor(c))
+	if errors.Is(err, repositories.ErrAlbumNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	album.Artist = &artist
+	c.IndentedJSON(http.StatusOK, album)
+}
+
 // DeleteAlbumById @Summary Delete Album By Id
 // @ID delete-albums-by-id
 // @Description Delete Album By Id
@@ -142,35 +355,292 @@ func CreateAlbum(c *gin.Context) {
 // @Param id path string true "album Id"
 // @Success 200
 // @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /albums/{id} [delete]
 func DeleteAlbumById(c *gin.Context) {
 	value := c.Param("id")
-	fmt.Println("value", value)
 	id, err := strconv.ParseInt(value, 10, 0)
 
 	if err != nil {
 		// log.Fatalln("err",err)
 		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+
+	// the audit entry is written with the delete, the reviews and cover images go after it
+	err = (*albumService).Delete(uint(id), auditActor(c))
+	if errors.Is(err, repositories.ErrAlbumNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
 	}
 
-	// albumInDb, err := (*albumService).FindById(uint(id))
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+// albumResponses joins albums with their content, the read state of the caller and the resources requested with ?include=
+func albumResponses(c *gin.Context, albums []entities.Album) ([]models.AlbumResponse, error) {
+	albumIds := []uint{}
+	for _, v := range albums {
+		albumIds = append(albumIds, v.Id)
+	}
+
+	contentFormat := c.DefaultQuery("content_format", libs.ContentFormatMarkdown)
+	if !libs.IsContentFormat(contentFormat) {
+		return nil, fmt.Errorf("%w: %s", errInvalidContentFormat, contentFormat)
+	}
 
-	// if err != nil {
-	// 	c.IndentedJSON(http.StatusNotFound,  models.Error{Message: err.Error()})
-	// }
+	// create a lookup map, the key is album id, value is the content document in mongodb, found without content kept in GridFS
+	albumsInMongoLookup := map[uint]entities.AlbumMongoDB{}
+	for _, v := range (*albumMongoService).FindByAlbumIds(albumIds) {
+		albumsInMongoLookup[v.AlbumId] = v
+	}
 
-	err = (*albumService).Delete(uint(id))
+	// read state of the caller, the key is album id, value is when the caller read it
+	readLookup, err := (*albumReadService).ReadState(middlewares.Subject(c), albumIds)
+	if err != nil {
+		return nil, err
+	}
 
+	totalDurations, err := (*trackService).TotalDurations(albumIds)
 	if err != nil {
-		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return nil, err
+	}
+
+	ratings, err := (*reviewService).Ratings(albumIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// prices that are due but not applied by the scheduler yet are in effect already
+	duePrices, err := (*priceService).CurrentPrices(albumIds)
+	if err != nil {
+		return nil, err
+	}
+
+	covers, err := (*coverService).FindByAlbumIds(albumIds)
+	if err != nil {
+		return nil, err
 	}
 
-	// objectId, _ := primitive.ObjectIDFromHex(albumInDb.ContentId);
-	isDeleteOk := (*albumMongoService).Delete(uint(id))
+	currency, rates, err := responseCurrency(c)
+	if err != nil {
+		return nil, err
+	}
+	rounding := libs.PriceRoundingRules()
+
+	include := includes(c)
+	tracksLookup := map[uint][]entities.Track{}
+	if include["tracks"] {
+		tracks, err := (*trackService).FindByAlbumIds(albumIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range tracks {
+			tracksLookup[track.AlbumId] = append(tracksLookup[track.AlbumId], track)
+		}
+	}
 
-	if isDeleteOk {
-		c.IndentedJSON(http.StatusAccepted, nil)
+	response := []models.AlbumResponse{}
+	for _, v := range albums {
+		if price, ok := duePrices[v.Id]; ok {
+			v.Price, v.Currency = price.Price, price.Currency
+		}
+		item := models.AlbumResponse{Id: v.Id, Title: v.Title, ArtistId: v.ArtistId, Price: libs.FormatPrice(v.Price, v.Currency), Currency: v.Currency, TotalDurationSeconds: totalDurations[v.Id],
+			Genres: []string{}, Tags: []string{}, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt, CreatedBy: v.CreatedBy, UpdatedBy: v.UpdatedBy}
+		for _, genre := range v.Genres {
+			item.Genres = append(item.Genres, genre.Slug)
+		}
+		item.Tags = append(item.Tags, v.Tags...)
+		if currency != "" {
+			// albums priced in a currency without a rate are left unconverted
+			if converted, err := rates.Convert(v.Price, v.Currency, currency, rounding); err == nil {
+				item.ConvertedPrice = &models.ConvertedPriceResponse{Price: libs.FormatPrice(converted.Price, converted.Currency), Currency: converted.Currency,
+					Rate: converted.Rate.String(), RateAsOf: converted.AsOf}
+			}
+		}
+		if rating, ok := ratings[v.Id]; ok {
+			item.ReviewCount = rating.Count
+			if average := rating.Average(); average != nil {
+				rounded := math.Round(*average*100) / 100
+				item.AverageRating = &rounded
+			}
+		}
+		if include["artist"] || include["tracks"] {
+			item.Embedded = &models.AlbumEmbedded{}
+		}
+		if v.Artist != nil {
+			item.Artist = v.Artist.Name
+			if include["artist"] {
+				item.Embedded.Artist = v.Artist
+			}
+		}
+		if include["tracks"] {
+			item.Embedded.Tracks = tracksLookup[v.Id]
+		}
+		if val, ok := albumsInMongoLookup[v.Id]; ok {
+			item.ContentSize = val.ContentSize
+			if contentInGridFS(val, contentFormat) {
+				item.ContentUrl = fmt.Sprintf("/api/v1/albums/%d/content?content_format=%s", v.Id, contentFormat)
+			} else {
+				item.Content = formatContent(val, contentFormat)
+			}
+		}
+		item.ContentFormat = contentFormat
+		if cover, ok := covers[v.Id]; ok {
+			item.CoverUrls = services.CoverUrls(cover)
+		}
+		if readAt, ok := readLookup[v.Id]; ok {
+			item.HasRead, item.ReadAt = true, &readAt
+		}
+		response = append(response, item)
+	}
+	return response, nil
+}
+
+// responseCurrency is the currency prices are converted to, taken from ?currency= or else from the first currency
+// of Accept-Currency that has a rate. It is empty when the client asked for none or none of its currencies has a rate,
+// a ?currency= without a rate is an error.
+func responseCurrency(c *gin.Context) (string, services.ExchangeRateTable, error) {
+	query, header := strings.ToUpper(strings.TrimSpace(c.Query("currency"))), c.GetHeader("Accept-Currency")
+	if query == "" && header == "" {
+		return "", nil, nil
+	}
+
+	rates, err := (*exchangeRateService).Table()
+	if err != nil {
+		return "", nil, err
+	}
+	if query != "" {
+		if _, ok := rates[query]; !ok {
+			return "", nil, fmt.Errorf("%w: %s", services.ErrUnknownCurrency, query)
+		}
+		return query, rates, nil
+	}
+	for _, currency := range services.AcceptedCurrencies(header) {
+		if _, ok := rates[currency]; ok {
+			return currency, rates, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// formatContent is the content of a document as markdown source, sanitized html or plain text.
+// Documents written before content was rendered are rendered on the fly.
+func formatContent(content entities.AlbumMongoDB, format string) string {
+	if format == libs.ContentFormatMarkdown {
+		return content.Content
+	}
+	rendered := content.ContentHtml
+	if rendered == "" && content.Content != "" {
+		rendered, _ = libs.RenderMarkdown(content.Content)
+	}
+	if format == libs.ContentFormatText {
+		return libs.ContentText(rendered)
+	}
+	return rendered
+}
+
+// contentInGridFS tells whether the content of a document is kept in GridFS in the format asked for, it is too
+// large to be embedded and is streamed from GET /albums/{id}/content instead
+func contentInGridFS(content entities.AlbumMongoDB, format string) bool {
+	if format == libs.ContentFormatMarkdown || (content.ContentHtml == "" && content.ContentHtmlFileId == nil) {
+		return content.ContentFileId != nil
+	}
+	return content.ContentHtmlFileId != nil
+}
+
+// albumResponsesError answers a failed albumResponses, asking for a currency without an exchange rate
+// or for an unknown content format is a bad request
+func albumResponsesError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnknownCurrency) || errors.Is(err, errInvalidContentFormat) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
 		return
 	}
-	c.IndentedJSON(http.StatusNotFound, models.Error{Message: "Invalid Content Id"})
+	c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
 }
+
+// includes parses the comma separated ?include= list
+func includes(c *gin.Context) map[string]bool {
+	include := map[string]bool{}
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			include[name] = true
+		}
+	}
+	return include
+}
+
+
+// albumFilter parses the album filters shared by the list and the facets
+func albumFilter(c *gin.Context) (repositories.AlbumFilter, error) {
+	filter := repositories.AlbumFilter{Genres: c.QueryArray("genre"), Tags: c.QueryArray("tag")}
+	if value := c.Query("updated_since"); value != "" {
+		updatedSince, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return filter, errors.New("updated_since must be an RFC 3339 time")
+		}
+		filter.UpdatedSince = &updatedSince
+	}
+	if value := c.Query("artist_id"); value != "" {
+		artistId, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return filter, errors.New("artist_id must be an artist Id")
+		}
+		filter.ArtistId = uint(artistId)
+	}
+	var err error
+	if filter.MinPrice, err = queryPrice(c, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = queryPrice(c, "max_price"); err != nil {
+		return filter, err
+	}
+	// prices of different currencies are not comparable, a range is a range of prices of one currency
+	filter.Currency = strings.ToUpper(strings.TrimSpace(c.Query("price_currency")))
+	if filter.Currency == "" && (filter.MinPrice != nil || filter.MaxPrice != nil) {
+		return filter, errors.New("min_price and max_price need the price_currency of the range")
+	}
+	return filter, nil
+}
+
+// queryPrice parses an optional price query parameter
+func queryPrice(c *gin.Context, name string) (*decimal.Decimal, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	price, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &price, nil
+}
+
+// currencyPriceBuckets are the price buckets of the facets and the stats, every currency on its own
+func currencyPriceBuckets(currencies []repositories.CurrencyPriceBuckets) []models.CurrencyPriceBucketsResponse {
+	response := []models.CurrencyPriceBucketsResponse{}
+	for _, currency := range currencies {
+		item := models.CurrencyPriceBucketsResponse{Currency: currency.Currency, Buckets: []models.PriceBucketResponse{}}
+		for _, bucket := range currency.Buckets {
+			item.Buckets = append(item.Buckets, models.PriceBucketResponse{From: bucket.From, To: bucket.To, Count: bucket.Count})
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+func facetCounts(counts []repositories.FacetCount) []models.FacetCountResponse {
+	response := []models.FacetCountResponse{}
+	for _, count := range counts {
+		response = append(response, models.FacetCountResponse{Value: count.Value, Name: count.Name, Count: count.Count})
+	}
+	return response
+}
\ No newline at end of file
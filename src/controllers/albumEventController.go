@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// comments keep idle connections open through proxies
+const albumEventsHeartbeat = 15 * time.Second
+
+var eventBus = libs.EventBusProvider()
+
+// StreamAlbumEvents @Summary Stream album events
+// @ID stream-album-events
+// @Description Server-Sent Events stream of album.created, album.updated, album.deleted and album.read events.
+// @Description Send Last-Event-ID to resume after a disconnect, missed events are replayed from a bounded buffer.
+// @Description When they are no longer buffered an album.resync event is sent first and the client should reload the albums.
+// @Description Callers only receive album.read events for their own reads, admins receive all of them.
+// @Tags Album
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "id of the last event received"
+// @Success 200 {object} models.AlbumEventResponse "one event per message"
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/events [get]
+func StreamAlbumEvents(c *gin.Context) {
+	subscription := eventBus.SubscribeAfter(c.GetHeader("Last-Event-ID"))
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx buffers responses unless told otherwise
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	subject := middlewares.Subject(c)
+	seeAllReads := middlewares.HasRole(c, middlewares.RoleAdmin)
+	send := func(event libs.Event) {
+		if data, ok := event.Data.(models.AlbumEventResponse); ok && event.Type == models.AlbumEventRead && data.UserId != subject && !seeAllReads {
+			return
+		}
+		c.Render(-1, sse.Event{Id: eventBus.EventId(event), Event: event.Type, Data: event.Data})
+	}
+
+	if subscription.Missed {
+		c.Render(-1, sse.Event{Event: models.AlbumEventResync, Data: gin.H{}})
+	}
+	for _, event := range subscription.Replay {
+		send(event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(albumEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// dropped for falling behind, the client reconnects and resumes from its last event id
+				return
+			}
+			send(event)
+		case <-heartbeat.C:
+			c.Writer.WriteString(":\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
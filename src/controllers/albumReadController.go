@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+var albumReadService = dependencies.InitializeAlbumReadService()
+
+// MarkAlbumRead @Summary Mark album as read
+// @ID mark-album-read
+// @Description Mark the album as read for the authenticated caller, marking it again keeps the first read time
+// @Tags Album
+// @Produce json
+// @Param id path string true "album Id"
+// @Success 200 {object} models.AlbumReadResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/read [post]
+func MarkAlbumRead(c *gin.Context) {
+	id, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	read, err := (*albumReadService).MarkRead(middlewares.Subject(c), id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.AlbumReadResponse{AlbumId: id, HasRead: true, ReadAt: &read.ReadAt})
+}
+
+// MarkAlbumUnread @Summary Mark album as unread
+// @ID mark-album-unread
+// @Description Remove the read state of the album for the authenticated caller
+// @Tags Album
+// @Produce json
+// @Param id path string true "album Id"
+// @Success 200 {object} models.AlbumReadResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/read [delete]
+func MarkAlbumUnread(c *gin.Context) {
+	id, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	if err := (*albumReadService).MarkUnread(middlewares.Subject(c), id); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.AlbumReadResponse{AlbumId: id, HasRead: false})
+}
+
+// findReadableAlbumId parses the album id of the route and makes sure the album exists
+func findReadableAlbumId(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return 0, false
+	}
+
+	album, err := (*albumService).FindById(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return 0, false
+	}
+	if album.Id == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: "Album not found"})
+		return 0, false
+	}
+	return album.Id, true
+}
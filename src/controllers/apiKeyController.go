@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var apiKeyService = dependencies.InitializeApiKeyService()
+
+// GetApiKeys @Summary Get api keys
+// @ID get-api-keys
+// @Description Get all api keys, the plain keys are never returned
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} []models.ApiKeyResponse
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys [get]
+func GetApiKeys(c *gin.Context) {
+	apiKeys, err := (*apiKeyService).FindAll()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := []models.ApiKeyResponse{}
+	for _, apiKey := range apiKeys {
+		response = append(response, models.ApiKeyResponse{ApiKey: apiKey})
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// CreateApiKey @Summary Create api key
+// @ID create-api-key
+// @Description Create an api key for a service-to-service client. The plain key is only part of this response.
+// @Tags Admin
+// @Accept  json
+// @Produce json
+// @Param data body models.CreateApiKeyDto true "api key data"
+// @Success 201 {object} models.ApiKeyResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys [post]
+func CreateApiKey(c *gin.Context) {
+	var newApiKey models.CreateApiKeyDto
+
+	if err := c.ShouldBindJSON(&newApiKey); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	if newApiKey.ExpiresAt != nil && newApiKey.ExpiresAt.Before(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "expires_at must be in the future"})
+		return
+	}
+
+	apiKey, key, err := (*apiKeyService).Create(newApiKey.Name, newApiKey.Scopes, newApiKey.ExpiresAt)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, models.ApiKeyResponse{ApiKey: apiKey, Key: key})
+}
+
+// RevokeApiKey @Summary Revoke api key
+// @ID revoke-api-key
+// @Description Revoke an api key, requests using it are rejected from now on
+// @Tags Admin
+// @Produce json
+// @Param id path string true "api key Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/api-keys/{id} [delete]
+func RevokeApiKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Api Key Id"})
+		return
+	}
+
+	err = (*apiKeyService).Revoke(uint(id))
+	if errors.Is(err, repositories.ErrApiKeyNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
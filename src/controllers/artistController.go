@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var artistService = dependencies.InitializeArtistService()
+
+// GetArtists @Summary Get artists list
+// @ID get-artists-list
+// @Description Get artists ordered by name with pagination
+// @Tags Artist
+// @Produce json
+// @Param name query string false "part of the artist name, case insensitive"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(0)
+// @Success 200 {object} []entities.Artist
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists [get]
+func GetArtists(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	artists, err := (*artistService).FindAll(c.Query("name"), page, pageSize)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, artists)
+}
+
+// GetArtistById @Summary Get Artist By Id
+// @ID get-artist-by-id
+// @Description Get Artist By Id
+// @Tags Artist
+// @Produce json
+// @Param id path string true "artist Id"
+// @Success 200 {object} entities.Artist
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists/{id} [get]
+func GetArtistById(c *gin.Context) {
+	id, ok := artistId(c)
+	if !ok {
+		return
+	}
+
+	artist, err := (*artistService).FindById(id)
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, artist)
+}
+
+// CreateArtist @Summary Create new artist
+// @ID create-new-artist
+// @Description Create new artist, names are unique regardless of case and whitespace
+// @Tags Artist
+// @Accept  json
+// @Produce json
+// @Param data body models.CreateArtistDto true "artist data"
+// @Success 201 {object} entities.Artist
+// @Failure 400 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists [post]
+func CreateArtist(c *gin.Context) {
+	var newArtist models.CreateArtistDto
+	if err := c.ShouldBindJSON(&newArtist); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	artist, err := (*artistService).Create(newArtist.Name, middlewares.Subject(c))
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, artist)
+}
+
+// UpdateArtist @Summary Rename artist
+// @ID update-artist
+// @Description Rename an artist, the albums referencing it follow
+// @Tags Artist
+// @Accept  json
+// @Produce json
+// @Param id path string true "artist Id"
+// @Param data body models.CreateArtistDto true "artist data"
+// @Success 200 {object} entities.Artist
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists/{id} [put]
+func UpdateArtist(c *gin.Context) {
+	id, ok := artistId(c)
+	if !ok {
+		return
+	}
+
+	var artistData models.CreateArtistDto
+	if err := c.ShouldBindJSON(&artistData); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	artist, err := (*artistService).Update(id, artistData.Name, middlewares.Subject(c))
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, artist)
+}
+
+// DeleteArtistById @Summary Delete Artist By Id
+// @ID delete-artist-by-id
+// @Description Delete an artist that has no albums
+// @Tags Artist
+// @Produce json
+// @Param id path string true "artist Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists/{id} [delete]
+func DeleteArtistById(c *gin.Context) {
+	id, ok := artistId(c)
+	if !ok {
+		return
+	}
+
+	if err := (*artistService).Delete(id); err != nil {
+		artistError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+// GetArtistAlbums @Summary Get albums of an artist
+// @ID get-artist-albums
+// @Description Get the albums of an artist with pagination
+// @Tags Artist
+// @Produce json
+// @Param id path string true "artist Id"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(0)
+// @Param include query string false "comma separated related resources to embed: artist, tracks"
+// @Param currency query string false "ISO 4217 code to convert prices to, see /exchange-rates"
+// @Param Accept-Currency header string false "currencies to convert prices to in order of preference, e.g. EUR, GBP;q=0.5"
+// @Param content_format query string false "representation of the content: markdown (the source), sanitized html or plain text" Enums(markdown, html, text) default(markdown)
+// @Success 200 {object} []models.AlbumResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /artists/{id}/albums [get]
+func GetArtistAlbums(c *gin.Context) {
+	id, ok := artistId(c)
+	if !ok {
+		return
+	}
+	if _, err := (*artistService).FindById(id); err != nil {
+		artistError(c, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	albums, err := (*albumService).FindAll(repositories.AlbumFilter{ArtistId: id}, page, pageSize)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response, err := albumResponses(c, albums)
+	if err != nil {
+		albumResponsesError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func artistId(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Artist Id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func artistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrArtistNotFound):
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	case errors.Is(err, repositories.ErrArtistExists), errors.Is(err, repositories.ErrArtistInUse):
+		c.IndentedJSON(http.StatusConflict, models.Error{Message: err.Error()})
+	default:
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
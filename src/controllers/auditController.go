@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/entities"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var auditService = dependencies.InitializeAuditService()
+
+// GetAuditEntries @Summary Get audit log
+// @ID get-audit-entries
+// @Description Get the audit log entries of an entity, oldest first, with pagination
+// @Tags Admin
+// @Produce json
+// @Param entity query string false "entity type, e.g. album"
+// @Param id query string false "entity Id"
+// @Param action query string false "create, update, delete or restore"
+// @Param actor query string false "subject that made the change"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(100)
+// @Success 200 {object} []entities.AuditEntry
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/audit [get]
+func GetAuditEntries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	entries, err := (*auditService).Find(auditFilter(c), page, pageSize)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, entries)
+}
+
+// ExportAuditEntries @Summary Export audit log
+// @ID export-audit-entries
+// @Description Stream every matching audit log entry, oldest first, as newline delimited json
+// @Tags Admin
+// @Produce application/x-ndjson
+// @Param entity query string false "entity type, e.g. album"
+// @Param id query string false "entity Id"
+// @Param action query string false "create, update, delete or restore"
+// @Param actor query string false "subject that made the change"
+// @Success 200 {object} entities.AuditEntry "one entry per line"
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/audit/export [get]
+func ExportAuditEntries(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	err := (*auditService).Export(auditFilter(c), func(entry entities.AuditEntry) error {
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		// the status is already sent, the truncated export is only visible in the access log
+		c.Error(err)
+	}
+}
+
+func auditFilter(c *gin.Context) repositories.AuditFilter {
+	return repositories.AuditFilter{
+		Entity:   c.Query("entity"),
+		EntityId: c.Query("id"),
+		Action:   c.Query("action"),
+		Actor:    c.Query("actor"),
+	}
+}
+
+// auditActor describes the caller of the request for the audit log
+func auditActor(c *gin.Context) services.AuditActor {
+	return services.AuditActor{
+		Actor:     middlewares.Subject(c),
+		RequestId: middlewares.RequestIdOf(c),
+		SourceIp:  c.ClientIP(),
+	}
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var contentRevisionService = dependencies.InitializeContentRevisionService()
+
+// GetAlbumContentRevisions @Summary Get album content revisions
+// @ID get-album-content-revisions
+// @Description Get the revisions of the content of an album without their content, newest first, with pagination
+// @Tags ContentRevision
+// @Produce json
+// @Param id path string true "album Id"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(20)
+// @Success 200 {object} []entities.AlbumContentRevisionMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content/revisions [get]
+func GetAlbumContentRevisions(c *gin.Context) {
+	albumId, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	revisions, err := (*contentRevisionService).FindByAlbum(albumId, page, pageSize)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, revisions)
+}
+
+// GetAlbumContentRevision @Summary Get an album content revision
+// @ID get-album-content-revision
+// @Description Get a revision of the content of an album with its content
+// @Tags ContentRevision
+// @Produce json
+// @Param id path string true "album Id"
+// @Param rev path int true "revision number"
+// @Success 200 {object} entities.AlbumContentRevisionMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content/revisions/{rev} [get]
+func GetAlbumContentRevision(c *gin.Context) {
+	albumId, revision, ok := contentRevisionIds(c)
+	if !ok {
+		return
+	}
+
+	found, err := (*contentRevisionService).FindByRevision(albumId, revision)
+	if err != nil {
+		contentRevisionError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, found)
+}
+
+// GetAlbumContentDiff @Summary Diff album content revisions
+// @ID get-album-content-diff
+// @Description Compare a revision of the content of an album with an earlier one, by default the one before it
+// @Tags ContentRevision
+// @Produce json
+// @Param id path string true "album Id"
+// @Param rev path int true "revision number"
+// @Param from query int false "revision to compare with, the previous revision when left out"
+// @Success 200 {object} models.ContentDiffResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content/revisions/{rev}/diff [get]
+func GetAlbumContentDiff(c *gin.Context) {
+	albumId, revision, ok := contentRevisionIds(c)
+	if !ok {
+		return
+	}
+	from := revision - 1
+	if value := c.Query("from"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid from revision"})
+			return
+		}
+		from = parsed
+	}
+
+	diff, err := (*contentRevisionService).Diff(albumId, from, revision)
+	if err != nil {
+		contentRevisionError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.ContentDiffResponse{AlbumId: albumId, From: diff.From, To: diff.To, Additions: diff.Additions, Deletions: diff.Deletions, Diff: diff.Unified})
+}
+
+// RestoreAlbumContentRevision @Summary Restore an album content revision
+// @ID restore-album-content-revision
+// @Description Write the content of a revision back to the album, the restore is recorded as a new revision
+// @Tags ContentRevision
+// @Produce json
+// @Param id path string true "album Id"
+// @Param rev path int true "revision number"
+// @Success 201 {object} entities.AlbumContentRevisionMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/content/revisions/{rev}/restore [post]
+func RestoreAlbumContentRevision(c *gin.Context) {
+	albumId, revision, ok := contentRevisionIds(c)
+	if !ok {
+		return
+	}
+
+	album, err := (*albumService).FindById(albumId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	if album.Id == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: "Album not found"})
+		return
+	}
+
+	restored, err := (*contentRevisionService).Restore(album, revision, auditActor(c))
+	if err != nil {
+		contentRevisionError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, restored)
+}
+
+func contentRevisionIds(c *gin.Context) (uint, int, bool) {
+	albumId, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return 0, 0, false
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || revision <= 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid revision"})
+		return 0, 0, false
+	}
+	return uint(albumId), revision, true
+}
+
+func contentRevisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrContentRevisionNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	case errors.Is(err, services.ErrContentUnchanged):
+		c.AbortWithStatusJSON(http.StatusConflict, models.Error{Message: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
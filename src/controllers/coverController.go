@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var coverService = dependencies.InitializeCoverService()
+
+// PutAlbumCover @Summary Upload an album cover
+// @ID put-album-cover
+// @Description Upload the cover image of an album as the multipart field "file", a jpeg, png, gif or webp image.
+// @Description The image is stored with small, medium and large thumbnails and replaces the previous cover.
+// @Tags Cover
+// @Accept  multipart/form-data
+// @Produce json
+// @Param id path string true "album Id"
+// @Param file formData file true "cover image"
+// @Success 200 {object} models.AlbumCoverResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 415 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/cover [put]
+func PutAlbumCover(c *gin.Context) {
+	// no point in decoding an image for an album that does not exist
+	id, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "file is required: " + err.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	defer file.Close()
+
+	cover, err := (*coverService).Upload(id, file, middlewares.Subject(c))
+	if err != nil {
+		coverError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, coverResponse(cover))
+}
+
+// GetAlbumCover @Summary Get an album cover
+// @ID get-album-cover
+// @Description Get the cover image of an album, ?size= is original (default), small, medium or large.
+// @Description A url with the current ?v= version is cached for good, other urls are revalidated with their ETag.
+// @Tags Cover
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/gif
+// @Produce image/webp
+// @Param id path string true "album Id"
+// @Param size query string false "original, small, medium or large"
+// @Param v query string false "cover version"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/cover [get]
+func GetAlbumCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+
+	cover, err := (*coverService).FindByAlbumId(uint(id))
+	if err != nil {
+		coverError(c, err)
+		return
+	}
+	size := c.DefaultQuery("size", services.CoverSizeOriginal)
+	blob, info, contentType, err := (*coverService).Open(cover, size)
+	if err != nil {
+		coverError(c, err)
+		return
+	}
+	defer blob.Close()
+
+	// a versioned url always names the same bytes, the others follow the latest upload
+	if c.Query("v") == cover.Version {
+		c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	c.Header("ETag", fmt.Sprintf(`"%s-%s"`, cover.Version, size))
+	c.Header("Content-Type", contentType)
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, blob)
+}
+
+// DeleteAlbumCover @Summary Delete an album cover
+// @ID delete-album-cover
+// @Description Delete the cover image of an album with its thumbnails
+// @Tags Cover
+// @Produce json
+// @Param id path string true "album Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/cover [delete]
+func DeleteAlbumCover(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+
+	if err := (*coverService).Delete(uint(id)); err != nil {
+		coverError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+func coverResponse(cover entities.AlbumCover) models.AlbumCoverResponse {
+	return models.AlbumCoverResponse{AlbumId: cover.AlbumId, Version: cover.Version, ContentType: cover.ContentType, Width: cover.Width,
+		Height: cover.Height, SizeBytes: cover.SizeBytes, Urls: services.CoverUrls(cover), UpdatedAt: cover.UpdatedAt, UpdatedBy: cover.UpdatedBy}
+}
+
+func coverError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrAlbumNotFound), errors.Is(err, repositories.ErrCoverNotFound), errors.Is(err, libs.ErrBlobNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	case errors.Is(err, services.ErrCoverTooLarge):
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.Error{Message: err.Error()})
+	case errors.Is(err, services.ErrUnsupportedCoverType):
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, models.Error{Message: err.Error()})
+	case errors.Is(err, services.ErrInvalidCover), errors.Is(err, services.ErrInvalidCoverSize):
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
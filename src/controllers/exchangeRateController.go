@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var exchangeRateService = dependencies.InitializeExchangeRateService()
+
+// GetExchangeRates @Summary Get exchange rates
+// @ID get-exchange-rates
+// @Description Get the rate table prices are converted with, every currency in it can be asked for with ?currency= or Accept-Currency
+// @Tags ExchangeRate
+// @Produce json
+// @Success 200 {object} []entities.ExchangeRate
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /exchange-rates [get]
+func GetExchangeRates(c *gin.Context) {
+	rates, err := (*exchangeRateService).FindAll()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, rates)
+}
+
+// ReplaceExchangeRates @Summary Replace exchange rates
+// @ID replace-exchange-rates
+// @Description Replace the whole rate table, as json or as csv with a currency,rate,as_of header when the content type is text/csv
+// @Tags ExchangeRate
+// @Accept  json
+// @Accept  text/csv
+// @Produce json
+// @Param data body models.ExchangeRatesDto true "rate table"
+// @Success 200 {object} []entities.ExchangeRate
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/exchange-rates [put]
+func ReplaceExchangeRates(c *gin.Context) {
+	format := "json"
+	if c.ContentType() == "text/csv" {
+		format = "csv"
+	}
+	parsed, err := services.ParseExchangeRates(c.Request.Body, format)
+	if err != nil {
+		exchangeRateError(c, err)
+		return
+	}
+
+	rates, err := (*exchangeRateService).Replace(parsed, middlewares.Subject(c))
+	if err != nil {
+		exchangeRateError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, rates)
+}
+
+func exchangeRateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidExchangeRates):
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var genreService = dependencies.InitializeGenreService()
+
+// GetGenres @Summary Get genres list
+// @ID get-genres-list
+// @Description Get the genres albums can be filed under, ordered by slug
+// @Tags Genre
+// @Produce json
+// @Success 200 {object} []entities.Genre
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /genres [get]
+func GetGenres(c *gin.Context) {
+	genres, err := (*genreService).FindAll()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, genres)
+}
+
+// CreateGenre @Summary Create new genre
+// @ID create-new-genre
+// @Description Create new genre, its slug is derived from the name
+// @Tags Genre
+// @Accept  json
+// @Produce json
+// @Param data body models.CreateGenreDto true "genre data"
+// @Success 201 {object} entities.Genre
+// @Failure 400 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /genres [post]
+func CreateGenre(c *gin.Context) {
+	var newGenre models.CreateGenreDto
+	if err := c.ShouldBindJSON(&newGenre); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	genre, err := (*genreService).Create(newGenre.Name, middlewares.Subject(c))
+	if err != nil {
+		genreError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, genre)
+}
+
+// DeleteGenre @Summary Delete genre
+// @ID delete-genre
+// @Description Delete a genre that no album is filed under
+// @Tags Genre
+// @Produce json
+// @Param slug path string true "genre slug"
+// @Success 202
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /genres/{slug} [delete]
+func DeleteGenre(c *gin.Context) {
+	if err := (*genreService).Delete(c.Param("slug")); err != nil {
+		genreError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+func genreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGenreName):
+		c.IndentedJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+	case errors.Is(err, repositories.ErrGenreNotFound):
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	case errors.Is(err, repositories.ErrGenreExists), errors.Is(err, repositories.ErrGenreInUse):
+		c.IndentedJSON(http.StatusConflict, models.Error{Message: err.Error()})
+	default:
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var priceService = dependencies.InitializePriceService()
+
+// GetAlbumPrices @Summary Get album prices
+// @ID get-album-prices
+// @Description Get the current price of an album, its price history and the prices scheduled for it
+// @Tags Price
+// @Produce json
+// @Param id path string true "album Id"
+// @Success 200 {object} models.AlbumPricesResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/prices [get]
+func GetAlbumPrices(c *gin.Context) {
+	album, ok := findPricedAlbum(c)
+	if !ok {
+		return
+	}
+
+	history, err := (*priceService).FindHistory(album.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	scheduled, err := (*priceService).FindScheduled(album.Id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := models.AlbumPricesResponse{AlbumId: album.Id, Price: libs.FormatPrice(album.Price, album.Currency), Currency: album.Currency,
+		History: []models.PriceChangeResponse{}, Scheduled: []models.ScheduledPriceResponse{}}
+	for _, change := range history {
+		item := models.PriceChangeResponse{Price: libs.FormatPrice(change.Price, change.Currency), Currency: change.Currency, ChangedAt: change.ChangedAt, ChangedBy: change.ChangedBy}
+		if change.PreviousPrice != nil && change.PreviousCurrency != nil {
+			previous := libs.FormatPrice(*change.PreviousPrice, *change.PreviousCurrency)
+			item.PreviousPrice, item.PreviousCurrency = &previous, *change.PreviousCurrency
+		}
+		response.History = append(response.History, item)
+	}
+	for _, price := range scheduled {
+		response.Scheduled = append(response.Scheduled, scheduledPriceResponse(price))
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// ScheduleAlbumPrice @Summary Schedule an album price
+// @ID schedule-album-price
+// @Description Schedule a price an album takes from a given time on, the price history records it once it is applied
+// @Tags Price
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param data body models.ScheduleAlbumPriceDto true "scheduled price"
+// @Success 201 {object} models.ScheduledPriceResponse
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/prices [post]
+func ScheduleAlbumPrice(c *gin.Context) {
+	album, ok := findPricedAlbum(c)
+	if !ok {
+		return
+	}
+
+	var newPrice models.ScheduleAlbumPriceDto
+	if err := c.ShouldBindJSON(&newPrice); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if newPrice.Currency == "" {
+		newPrice.Currency = album.Currency
+	}
+	if err := libs.ValidatePrice(*newPrice.Price, newPrice.Currency); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	price := entities.ScheduledPrice{AlbumId: album.Id, Price: *newPrice.Price, Currency: newPrice.Currency, EffectiveFrom: newPrice.EffectiveFrom}
+	price, err := (*priceService).Schedule(&price, middlewares.Subject(c))
+	if err != nil {
+		priceError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, scheduledPriceResponse(price))
+}
+
+// CancelAlbumPrice @Summary Cancel a scheduled album price
+// @ID cancel-album-price
+// @Description Cancel a scheduled price that has not been applied yet
+// @Tags Price
+// @Produce json
+// @Param id path string true "album Id"
+// @Param priceId path string true "scheduled price Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/prices/{priceId} [delete]
+func CancelAlbumPrice(c *gin.Context) {
+	albumId, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+	priceId, err := strconv.ParseUint(c.Param("priceId"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Price Id"})
+		return
+	}
+
+	if err := (*priceService).Cancel(uint(albumId), uint(priceId)); err != nil {
+		priceError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+// findPricedAlbum loads the album of the route with the price it has now, a due scheduled price the scheduler
+// has not applied yet is already in effect
+func findPricedAlbum(c *gin.Context) (entities.Album, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return entities.Album{}, false
+	}
+
+	album, err := (*albumService).FindById(uint(id))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return album, false
+	}
+	if album.Id == 0 {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: "Album not found"})
+		return album, false
+	}
+
+	due, err := (*priceService).CurrentPrices([]uint{album.Id})
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return album, false
+	}
+	if price, ok := due[album.Id]; ok {
+		album.Price, album.Currency = price.Price, price.Currency
+	}
+	return album, true
+}
+
+func scheduledPriceResponse(price entities.ScheduledPrice) models.ScheduledPriceResponse {
+	return models.ScheduledPriceResponse{Id: price.Id, Price: libs.FormatPrice(price.Price, price.Currency), Currency: price.Currency,
+		EffectiveFrom: price.EffectiveFrom, Status: price.Status, CreatedAt: price.CreatedAt, CreatedBy: price.CreatedBy}
+}
+
+func priceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrAlbumNotFound), errors.Is(err, repositories.ErrScheduledPriceNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/entities"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var reviewService = dependencies.InitializeReviewService()
+
+// GetAlbumReviews @Summary Get album reviews
+// @ID get-album-reviews
+// @Description Get the reviews of an album, newest first, with pagination
+// @Tags Review
+// @Produce json
+// @Param id path string true "album Id"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(20)
+// @Success 200 {object} []entities.ReviewMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/reviews [get]
+func GetAlbumReviews(c *gin.Context) {
+	albumId, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reviews, err := (*reviewService).FindByAlbum(albumId, page, pageSize)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, reviews)
+}
+
+// CreateAlbumReview @Summary Review an album
+// @ID create-album-review
+// @Description Review an album as the authenticated caller, a caller reviews an album at most once
+// @Tags Review
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param data body models.CreateReviewDto true "review data"
+// @Success 201 {object} entities.ReviewMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/reviews [post]
+func CreateAlbumReview(c *gin.Context) {
+	albumId, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	var newReview models.CreateReviewDto
+	if err := c.ShouldBindJSON(&newReview); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	review := entities.ReviewMongoDB{AlbumId: albumId, Author: middlewares.Subject(c), Rating: newReview.Rating, Title: newReview.Title, Body: newReview.Body}
+	review, err := (*reviewService).Create(&review)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, review)
+}
+
+// UpdateAlbumReview @Summary Update a review
+// @ID update-album-review
+// @Description Update a review, only its author can
+// @Tags Review
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param reviewId path string true "review Id"
+// @Param data body models.CreateReviewDto true "review data"
+// @Success 200 {object} entities.ReviewMongoDB
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/reviews/{reviewId} [put]
+func UpdateAlbumReview(c *gin.Context) {
+	review, ok := findOwnReview(c, false)
+	if !ok {
+		return
+	}
+
+	var reviewData models.CreateReviewDto
+	if err := c.ShouldBindJSON(&reviewData); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	review.Rating, review.Title, review.Body = reviewData.Rating, reviewData.Title, reviewData.Body
+	review, err := (*reviewService).Update(&review)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, review)
+}
+
+// DeleteAlbumReview @Summary Delete a review
+// @ID delete-album-review
+// @Description Delete a review, its author and admins can
+// @Tags Review
+// @Produce json
+// @Param id path string true "album Id"
+// @Param reviewId path string true "review Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/reviews/{reviewId} [delete]
+func DeleteAlbumReview(c *gin.Context) {
+	review, ok := findOwnReview(c, true)
+	if !ok {
+		return
+	}
+
+	if err := (*reviewService).Delete(review.AlbumId, review.ID); err != nil {
+		reviewError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+// findOwnReview loads the review of the route and makes sure the caller wrote it, or is an admin when adminAllowed
+func findOwnReview(c *gin.Context, adminAllowed bool) (entities.ReviewMongoDB, bool) {
+	albumId, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return entities.ReviewMongoDB{}, false
+	}
+	reviewId, err := primitive.ObjectIDFromHex(c.Param("reviewId"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Review Id"})
+		return entities.ReviewMongoDB{}, false
+	}
+
+	review, err := (*reviewService).FindById(uint(albumId), reviewId)
+	if err != nil {
+		reviewError(c, err)
+		return review, false
+	}
+	if review.Author != middlewares.Subject(c) && !(adminAllowed && middlewares.HasRole(c, middlewares.RoleAdmin)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, models.Error{Message: "Only the author can change this review"})
+		return review, false
+	}
+	return review, true
+}
+
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrReviewNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+	case errors.Is(err, repositories.ErrReviewExists):
+		c.AbortWithStatusJSON(http.StatusConflict, models.Error{Message: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+	}
+}
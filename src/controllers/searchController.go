@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+var searchService = dependencies.InitializeSearchService()
+
+// search modes
+const (
+	searchModeFulltext = "fulltext"
+	searchModeFuzzy    = "fuzzy"
+)
+
+// Search @Summary Search albums
+// @ID search
+// @Description Search album titles, artist names and album content. Every word has to match, "quoted phrases" match
+// @Description words in a row and a word ending with * matches words starting with it, e.g. `"love supreme" colt*`.
+// @Description Results are ranked by relevance, title and artist matches above content matches, with highlighted snippets.
+// @Description With mode=fuzzy titles and artist names are matched by similarity instead, so misspelt words are found too,
+// @Description and results are ranked by how similar they are.
+// @Tags Search
+// @Produce json
+// @Param q query string true "search query"
+// @Param mode query string false "fulltext or fuzzy" Enums(fulltext, fuzzy) default(fulltext)
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(20)
+// @Success 200 {object} models.SearchResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /search [get]
+func Search(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	if page*pageSize > services.SearchWindow {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "results are paged within the first " + strconv.Itoa(services.SearchWindow)})
+		return
+	}
+
+	var results services.SearchResults
+	var err error
+	switch c.DefaultQuery("mode", searchModeFulltext) {
+	case searchModeFulltext:
+		results, err = (*searchService).Search(c.Query("q"), page, pageSize)
+	case searchModeFuzzy:
+		results, err = (*searchService).Fuzzy(c.Query("q"), page, pageSize)
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "mode must be fulltext or fuzzy"})
+		return
+	}
+	if errors.Is(err, libs.ErrInvalidSearchQuery) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := models.SearchResponse{Total: results.Total, Page: page, PageSize: pageSize, Results: []models.SearchResultResponse{}}
+	for _, result := range results.Results {
+		item := models.SearchResultResponse{AlbumId: result.Album.Id, Title: result.Album.Title, ArtistId: result.Album.ArtistId,
+			Score: result.Score, Highlights: result.Highlights}
+		if result.Album.Artist != nil {
+			item.Artist = result.Album.Artist.Name
+		}
+		response.Results = append(response.Results, item)
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// SuggestAlbums @Summary Suggest album titles and artist names
+// @ID suggest-albums
+// @Description Complete what has been typed in a search box with album titles and artist names having all of its words,
+// @Description the last word only as a prefix. Titles and names starting with the first word come first, then the shortest.
+// @Tags Search
+// @Produce json
+// @Param prefix query string true "what has been typed, a word being typed is completed from its third letter"
+// @Param limit query int false "most suggestions returned, at most 25" default(10)
+// @Success 200 {array} models.SuggestionResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/suggest [get]
+func SuggestAlbums(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 25 {
+		limit = 10
+	}
+
+	suggestions, err := (*searchService).Suggest(c.Query("prefix"), limit)
+	if errors.Is(err, libs.ErrInvalidSearchQuery) || errors.Is(err, libs.ErrShortSearchPrefix) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := []models.SuggestionResponse{}
+	for _, suggestion := range suggestions {
+		response = append(response, models.SuggestionResponse{Type: suggestion.Type, Id: suggestion.Id, Text: suggestion.Text, Highlight: suggestion.Highlight})
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+var statsService = dependencies.InitializeStatsService()
+
+// GetStats @Summary Get catalogue statistics
+// @ID get-stats
+// @Description Album count, price figures per currency, the share of albums the caller has read and the distribution of
+// @Description content lengths, with the albums counted per artist or per price bucket of each currency. Figures are aggregated by the
+// @Description databases and cached, computed_at tells how old they are.
+// @Tags Stats
+// @Produce json
+// @Param group_by query string false "what albums are counted by" Enums(artist, price_bucket) default(artist)
+// @Success 200 {object} models.StatsResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /stats [get]
+func GetStats(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", repositories.StatsGroupByArtist)
+	stats, err := (*statsService).Stats(groupBy, middlewares.Subject(c))
+	if errors.Is(err, services.ErrInvalidStatsGroupBy) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := models.StatsResponse{Albums: stats.Albums.Total, ArtistCount: stats.Albums.ArtistCount,
+		Prices: []models.CurrencyPriceStatsResponse{}, GroupBy: groupBy, ComputedAt: stats.ComputedAt}
+	for _, prices := range stats.Albums.Prices {
+		item := models.CurrencyPriceStatsResponse{Currency: prices.Currency, Count: prices.Count, Min: prices.Min, Max: prices.Max,
+			Avg: prices.Avg, Percentiles: map[string]decimal.Decimal{}}
+		for i, percentile := range repositories.PricePercentiles {
+			if i >= len(prices.Percentiles) {
+				break
+			}
+			if price, err := decimal.NewFromString(prices.Percentiles[i]); err == nil {
+				item.Percentiles[fmt.Sprintf("p%d", percentile)] = price
+			}
+		}
+		response.Prices = append(response.Prices, item)
+	}
+
+	response.Reads = models.ReadStatsResponse{Read: stats.Read, Unread: stats.Albums.Total - stats.Read}
+	if stats.Albums.Total > 0 {
+		response.Reads.Ratio = float64(stats.Read) / float64(stats.Albums.Total)
+	}
+
+	response.Content = models.ContentStatsResponse{Albums: stats.Content.WithContent, TotalBytes: stats.Content.TotalBytes,
+		MaxBytes: stats.Content.MaxBytes, Lengths: []models.ContentLengthBucketResponse{}}
+	if stats.Content.WithContent > 0 {
+		response.Content.AvgBytes = stats.Content.TotalBytes / stats.Content.WithContent
+	}
+	for _, length := range stats.Content.Lengths {
+		response.Content.Lengths = append(response.Content.Lengths, models.ContentLengthBucketResponse{From: length.From, To: length.To, Count: length.Count})
+	}
+
+	switch groupBy {
+	case repositories.StatsGroupByArtist:
+		response.Artists = facetCounts(stats.Albums.Artists)
+	case repositories.StatsGroupByPriceBucket:
+		response.PriceBuckets = currencyPriceBuckets(stats.Albums.PriceBuckets)
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
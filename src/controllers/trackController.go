@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/entities"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var trackService = dependencies.InitializeTrackService()
+
+// GetAlbumTracks @Summary Get album tracks
+// @ID get-album-tracks
+// @Description Get the track listing of an album ordered by disc and number
+// @Tags Track
+// @Produce json
+// @Param id path string true "album Id"
+// @Success 200 {object} []entities.Track
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/tracks [get]
+func GetAlbumTracks(c *gin.Context) {
+	albumId, ok := findReadableAlbumId(c)
+	if !ok {
+		return
+	}
+
+	tracks, err := (*trackService).FindByAlbum(albumId)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, tracks)
+}
+
+// GetAlbumTrackById @Summary Get album track By Id
+// @ID get-album-track-by-id
+// @Description Get a track of an album
+// @Tags Track
+// @Produce json
+// @Param id path string true "album Id"
+// @Param trackId path string true "track Id"
+// @Success 200 {object} entities.Track
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/tracks/{trackId} [get]
+func GetAlbumTrackById(c *gin.Context) {
+	albumId, trackId, ok := trackIds(c)
+	if !ok {
+		return
+	}
+
+	track, err := (*trackService).FindById(albumId, trackId)
+	if err != nil {
+		trackError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, track)
+}
+
+// CreateAlbumTrack @Summary Add a track to an album
+// @ID create-album-track
+// @Description Insert a track at its number, the following tracks of the disc move down by one. Without a number the track is appended.
+// @Tags Track
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param data body models.CreateTrackDto true "track data"
+// @Success 201 {object} entities.Track
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/tracks [post]
+func CreateAlbumTrack(c *gin.Context) {
+	albumId, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return
+	}
+
+	var newTrack models.CreateTrackDto
+	if err := c.ShouldBindJSON(&newTrack); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	track := entities.Track{AlbumId: uint(albumId), Title: newTrack.Title, DurationSeconds: newTrack.DurationSeconds, Number: newTrack.Number, DiscNumber: newTrack.DiscNumber}
+	track, err = (*trackService).Create(&track, middlewares.Subject(c))
+	if err != nil {
+		trackError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, track)
+}
+
+// UpdateAlbumTrack @Summary Update an album track
+// @ID update-album-track
+// @Description Update a track, changing its disc or number moves it and renumbers the tracks around it. A number left out keeps the current position.
+// @Tags Track
+// @Accept  json
+// @Produce json
+// @Param id path string true "album Id"
+// @Param trackId path string true "track Id"
+// @Param data body models.CreateTrackDto true "track data"
+// @Success 200 {object} entities.Track
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/tracks/{trackId} [put]
+func UpdateAlbumTrack(c *gin.Context) {
+	albumId, trackId, ok := trackIds(c)
+	if !ok {
+		return
+	}
+
+	var trackData models.CreateTrackDto
+	if err := c.ShouldBindJSON(&trackData); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	track := entities.Track{Id: trackId, AlbumId: albumId, Title: trackData.Title, DurationSeconds: trackData.DurationSeconds, Number: trackData.Number, DiscNumber: trackData.DiscNumber}
+	track, err := (*trackService).Update(&track, middlewares.Subject(c))
+	if err != nil {
+		trackError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, track)
+}
+
+// DeleteAlbumTrack @Summary Remove a track from an album
+// @ID delete-album-track
+// @Description Remove a track, the following tracks of the disc move up by one
+// @Tags Track
+// @Produce json
+// @Param id path string true "album Id"
+// @Param trackId path string true "track Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /albums/{id}/tracks/{trackId} [delete]
+func DeleteAlbumTrack(c *gin.Context) {
+	albumId, trackId, ok := trackIds(c)
+	if !ok {
+		return
+	}
+
+	if err := (*trackService).Delete(albumId, trackId); err != nil {
+		trackError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+func trackIds(c *gin.Context) (uint, uint, bool) {
+	albumId, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Album Id"})
+		return 0, 0, false
+	}
+	trackId, err := strconv.ParseUint(c.Param("trackId"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Track Id"})
+		return 0, 0, false
+	}
+	return uint(albumId), uint(trackId), true
+}
+
+func trackError(c *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrTrackNotFound) || errors.Is(err, repositories.ErrAlbumNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"acy.com/api/src/dependencies"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+var webhookService = dependencies.InitializeWebhookService()
+
+// GetWebhooks @Summary Get webhooks
+// @ID get-webhooks
+// @Description Get all webhook subscriptions, the secrets are never returned
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} []models.WebhookResponse
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	subscriptions, err := (*webhookService).FindAll()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	response := []models.WebhookResponse{}
+	for _, subscription := range subscriptions {
+		response = append(response, models.WebhookResponse{WebhookSubscription: subscription})
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// CreateWebhook @Summary Create webhook
+// @ID create-webhook
+// @Description Subscribe a url to album events. Every delivery is a POST of a json payload signed in the
+// @Description X-Webhook-Signature header as "sha256=" + hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>".
+// @Description Event type "*" subscribes to every event. The secret is only part of this response.
+// @Tags Admin
+// @Accept  json
+// @Produce json
+// @Param data body models.CreateWebhookDto true "webhook data"
+// @Success 201 {object} models.WebhookResponse
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 413 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var newWebhook models.CreateWebhookDto
+
+	if err := c.ShouldBindJSON(&newWebhook); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		return
+	}
+
+	if target, err := url.Parse(newWebhook.Url); err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "url must be an http or https url"})
+		return
+	}
+
+	subscription, secret, err := (*webhookService).Create(newWebhook.Url, newWebhook.EventTypes, newWebhook.Secret, middlewares.Subject(c))
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, models.WebhookResponse{WebhookSubscription: subscription, Secret: secret})
+}
+
+// DeleteWebhook @Summary Delete webhook
+// @ID delete-webhook
+// @Description Delete a webhook subscription together with its delivery history
+// @Tags Admin
+// @Produce json
+// @Param id path string true "webhook Id"
+// @Success 202
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Webhook Id"})
+		return
+	}
+
+	err = (*webhookService).Delete(uint(id))
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusAccepted, nil)
+}
+
+// GetWebhookDeliveries @Summary Get webhook deliveries
+// @ID get-webhook-deliveries
+// @Description Get the delivery history of a webhook, newest first, with pagination
+// @Tags Admin
+// @Produce json
+// @Param id path string true "webhook Id"
+// @Param page query int false "pagination current page" default(1)
+// @Param page_size query int false "pagination page_size" default(50)
+// @Success 200 {object} []entities.WebhookDelivery
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Webhook Id"})
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+
+	deliveries, err := (*webhookService).Deliveries(uint(id), page, pageSize)
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook @Summary Redeliver webhook delivery
+// @ID redeliver-webhook
+// @Description Queue a delivery again with a fresh set of attempts, e.g. after it was dead-lettered
+// @Tags Admin
+// @Produce json
+// @Param id path string true "webhook Id"
+// @Param deliveryId path string true "delivery Id"
+// @Success 202 {object} entities.WebhookDelivery
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Webhook Id"})
+		return
+	}
+	deliveryId, err := strconv.ParseUint(c.Param("deliveryId"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: "Invalid Delivery Id"})
+		return
+	}
+
+	delivery, err := (*webhookService).Redeliver(uint(id), uint(deliveryId))
+	if errors.Is(err, repositories.ErrWebhookDeliveryNotFound) {
+		c.IndentedJSON(http.StatusNotFound, models.Error{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigratePostgres applies the scripts in db/migrations that are not recorded in schema_migrations yet.
+// Scripts run in file name order, each one in its own transaction. A script reads DEFAULT_CURRENCY with
+// current_setting('app.default_currency').
+func MigratePostgres(conn *sql.DB) error {
+	if _, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) NOT NULL PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := name[len("migrations/"):]
+
+		var applied bool
+		if err := conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		script, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`SELECT set_config('app.default_currency', $1, true)`, libs.DefaultCurrency()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mongoMigration is a step of MigrateMongo, a step that fails halfway runs again so it has to be idempotent
+type mongoMigration struct {
+	version string
+	migrate func(db *mongo.Database) error
+}
+
+var mongoMigrations = []mongoMigration{
+	// full-text search on album names and inline content, names weigh more. A collection has one text index at most.
+	{version: "0001_create_albums_text_index", migrate: func(db *mongo.Database) error {
+		index := mongo.IndexModel{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().SetName("albums_text").SetDefaultLanguage("english").
+				SetWeights(bson.D{{Key: "name", Value: 5}, {Key: "content", Value: 1}}),
+		}
+		_, err := db.Collection("albums").Indexes().CreateOne(context.TODO(), index)
+		return err
+	}},
+	// the text of content kept in GridFS joins the text index, and the words of every document get an index for
+	// prefixes. Documents written before are indexed here.
+	{version: "0002_index_album_content_text_and_terms", migrate: func(db *mongo.Database) error {
+		albums := db.Collection("albums")
+		if _, err := albums.Indexes().DropOne(context.TODO(), "albums_text"); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+		indexes := []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "name", Value: "text"}, {Key: "content", Value: "text"}, {Key: "contentText", Value: "text"}},
+				Options: options.Index().SetName("albums_text").SetDefaultLanguage("english").
+					SetWeights(bson.D{{Key: "name", Value: 5}, {Key: "content", Value: 1}, {Key: "contentText", Value: 1}}),
+			},
+			{Keys: bson.D{{Key: "terms", Value: 1}}, Options: options.Index().SetName("albums_terms")},
+		}
+		if _, err := albums.Indexes().CreateMany(context.TODO(), indexes); err != nil {
+			return err
+		}
+
+		// the bucket the album repository keeps content files in
+		bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("album_content"))
+		if err != nil {
+			return err
+		}
+		cursor, err := albums.Find(context.TODO(), bson.M{"terms": bson.M{"$exists": false}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(context.TODO())
+		for cursor.Next(context.TODO()) {
+			var album entities.AlbumMongoDB
+			if err := cursor.Decode(&album); err != nil {
+				return err
+			}
+			rendered := album.ContentHtml
+			if album.ContentHtmlFileId != nil {
+				var file strings.Builder
+				if _, err := bucket.DownloadToStream(*album.ContentHtmlFileId, &file); err != nil {
+					return err
+				}
+				rendered = file.String()
+			} else if rendered == "" {
+				rendered, _ = libs.RenderMarkdown(album.Content)
+			}
+			text := libs.ContentText(rendered)
+			if maxBytes := libs.ContentStorageProvider().InlineMaxBytes; len(text) > maxBytes {
+				text = strings.ToValidUTF8(text[:maxBytes], "")
+			}
+
+			set := bson.M{"terms": libs.SearchTerms(album.Name, text)}
+			if album.ContentFileId != nil {
+				set["contentText"] = text
+			}
+			if _, err := albums.UpdateOne(context.TODO(), bson.M{"_id": album.ID}, bson.M{"$set": set}); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	}},
+}
+
+// isIndexNotFound tells whether err is mongodb answering that an index to drop does not exist
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == 27
+}
+
+// MigrateMongo applies the steps of mongoMigrations that are not recorded in the schema_migrations collection yet, in order
+func MigrateMongo(db *mongo.Database) error {
+	applied := db.Collection("schema_migrations")
+	for _, migration := range mongoMigrations {
+		count, err := applied.CountDocuments(context.TODO(), bson.M{"_id": migration.version})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := migration.migrate(db); err != nil {
+			return err
+		}
+		if _, err := applied.InsertOne(context.TODO(), bson.M{"_id": migration.version, "appliedAt": time.Now()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
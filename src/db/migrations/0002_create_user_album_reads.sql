@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_album_reads (
+    user_id VARCHAR(255) NOT NULL,
+    album_id INTEGER NOT NULL,
+    read_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "PK_tbl_user_album_reads" PRIMARY KEY (user_id, album_id),
+    CONSTRAINT "FK_tbl_user_album_reads_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE
+);
+
+-- read state is tracked per user now, the shared flag is meaningless
+ALTER TABLE albums DROP COLUMN IF EXISTS has_read;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial NOT NULL,
+    entity VARCHAR(64) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    source_ip VARCHAR(64) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    changes JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "PK_tbl_audit_log" PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS "IX_tbl_audit_log_entity" ON audit_log (entity, entity_id, id);
+
+-- the audit log is append-only, even for the application's own database user
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only();
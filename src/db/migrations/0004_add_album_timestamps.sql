@@ -0,0 +1,7 @@
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS created_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- incremental sync reads albums by updated_at
+CREATE INDEX IF NOT EXISTS "IX_tbl_albums_updated_at" ON albums (updated_at, id);
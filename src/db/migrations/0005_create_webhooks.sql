@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id serial NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    secret VARCHAR(255) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_webhook_subscriptions" PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial NOT NULL,
+    subscription_id INTEGER NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ,
+    CONSTRAINT "PK_tbl_webhook_deliveries" PRIMARY KEY (id),
+    CONSTRAINT "FK_tbl_webhook_deliveries_subscription_id" FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (id) ON DELETE CASCADE
+);
+
+-- the delivery worker polls for due pending deliveries
+CREATE INDEX IF NOT EXISTS "IX_tbl_webhook_deliveries_due" ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS "IX_tbl_webhook_deliveries_subscription_id" ON webhook_deliveries (subscription_id, id);
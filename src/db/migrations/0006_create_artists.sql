@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS artists (
+    id serial NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    name_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_artists" PRIMARY KEY (id),
+    CONSTRAINT "UQ_tbl_artists_name_key" UNIQUE (name_key)
+);
+
+-- one artist per normalized spelling (trimmed, whitespace collapsed, lower case), named by its most used spelling
+INSERT INTO artists (name, name_key)
+SELECT DISTINCT ON (name_key) name, name_key
+FROM (
+    SELECT regexp_replace(trim(artist), '\s+', ' ', 'g') AS name,
+           lower(regexp_replace(trim(artist), '\s+', ' ', 'g')) AS name_key,
+           count(*) AS uses
+    FROM albums
+    WHERE trim(artist) <> ''
+    GROUP BY 1, 2
+) spellings
+ORDER BY name_key, uses DESC, name
+ON CONFLICT (name_key) DO NOTHING;
+
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS artist_id INTEGER;
+
+UPDATE albums SET artist_id = artists.id
+FROM artists
+WHERE artists.name_key = lower(regexp_replace(trim(albums.artist), '\s+', ' ', 'g'));
+
+-- albums without an artist name
+INSERT INTO artists (name, name_key)
+SELECT 'Unknown artist', 'unknown artist'
+WHERE EXISTS (SELECT 1 FROM albums WHERE artist_id IS NULL)
+ON CONFLICT (name_key) DO NOTHING;
+
+UPDATE albums SET artist_id = (SELECT id FROM artists WHERE name_key = 'unknown artist')
+WHERE artist_id IS NULL;
+
+ALTER TABLE albums ALTER COLUMN artist_id SET NOT NULL;
+ALTER TABLE albums ADD CONSTRAINT "FK_tbl_albums_artist_id" FOREIGN KEY (artist_id) REFERENCES artists (id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS "IX_tbl_albums_artist_id" ON albums (artist_id);
+
+-- the spellings merged into each artist stay as its aliases
+CREATE TABLE IF NOT EXISTS artist_aliases (
+    artist_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    CONSTRAINT "PK_tbl_artist_aliases" PRIMARY KEY (artist_id, name),
+    CONSTRAINT "FK_tbl_artist_aliases_artist_id" FOREIGN KEY (artist_id) REFERENCES artists (id) ON DELETE CASCADE
+);
+
+INSERT INTO artist_aliases (artist_id, name)
+SELECT DISTINCT artist_id, artist FROM albums
+WHERE artist IS NOT NULL AND trim(artist) <> ''
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE albums DROP COLUMN artist;
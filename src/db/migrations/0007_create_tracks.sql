@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS tracks (
+    id serial NOT NULL,
+    album_id INTEGER NOT NULL,
+    disc_number INTEGER NOT NULL DEFAULT 1,
+    number INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_tracks" PRIMARY KEY (id),
+    CONSTRAINT "FK_tbl_tracks_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE,
+    CONSTRAINT "CK_tbl_tracks_position" CHECK (disc_number >= 1 AND number >= 1),
+    CONSTRAINT "CK_tbl_tracks_duration_seconds" CHECK (duration_seconds >= 0),
+    -- deferred so inserting, moving or removing a track can renumber the following ones in a single statement
+    CONSTRAINT "UQ_tbl_tracks_position" UNIQUE (album_id, disc_number, number) DEFERRABLE INITIALLY DEFERRED
+);
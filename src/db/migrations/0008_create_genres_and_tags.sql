@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id serial NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_genres" PRIMARY KEY (id),
+    CONSTRAINT "UQ_tbl_genres_slug" UNIQUE (slug)
+);
+
+CREATE TABLE IF NOT EXISTS album_genres (
+    album_id INTEGER NOT NULL,
+    genre_id INTEGER NOT NULL,
+    CONSTRAINT "PK_tbl_album_genres" PRIMARY KEY (album_id, genre_id),
+    CONSTRAINT "FK_tbl_album_genres_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE,
+    -- a genre still used by albums cannot be deleted
+    CONSTRAINT "FK_tbl_album_genres_genre_id" FOREIGN KEY (genre_id) REFERENCES genres (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS "IX_tbl_album_genres_genre_id" ON album_genres (genre_id);
+
+-- free-form tags, normalized to lower case by the application
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS "IX_tbl_albums_tags" ON albums USING GIN (tags);
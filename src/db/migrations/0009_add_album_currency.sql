@@ -0,0 +1,21 @@
+-- price is NUMERIC already, it keeps its exact value. The API never wrote a NULL price, a hand-inserted row
+-- without one has an unknown price that is not made up here: it has to be set before the migration runs.
+DO $$
+DECLARE
+    unpriced TEXT;
+BEGIN
+    SELECT string_agg(id::TEXT, ', ' ORDER BY id) INTO unpriced FROM albums WHERE price IS NULL;
+    IF unpriced IS NOT NULL THEN
+        RAISE EXCEPTION 'albums % have no price, set their price before migrating', unpriced;
+    END IF;
+END $$;
+ALTER TABLE albums ALTER COLUMN price SET NOT NULL;
+
+-- existing albums are priced in DEFAULT_CURRENCY, MigratePostgres sets app.default_currency
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS currency CHAR(3);
+UPDATE albums SET currency = current_setting('app.default_currency') WHERE currency IS NULL;
+ALTER TABLE albums ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE albums ADD CONSTRAINT "CK_tbl_albums_currency" CHECK (currency ~ '^[A-Z]{3}$');
+-- NOT VALID leaves existing rows alone, new and updated rows are checked
+ALTER TABLE albums ADD CONSTRAINT "CK_tbl_albums_price" CHECK (price >= 0) NOT VALID;
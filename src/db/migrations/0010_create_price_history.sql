@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS price_history (
+    id bigserial NOT NULL,
+    album_id INTEGER NOT NULL,
+    price NUMERIC NOT NULL,
+    currency CHAR(3) NOT NULL,
+    previous_price NUMERIC,
+    previous_currency CHAR(3),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_price_history" PRIMARY KEY (id),
+    CONSTRAINT "FK_tbl_price_history_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "IX_tbl_price_history_album_id" ON price_history (album_id, changed_at);
+
+-- the prices albums have today are where their history starts
+INSERT INTO price_history (album_id, price, currency, changed_at, changed_by)
+SELECT id, price, currency, updated_at, updated_by FROM albums
+WHERE NOT EXISTS (SELECT 1 FROM price_history WHERE price_history.album_id = albums.id);
+
+-- every write of a price is recorded, whichever code path or tool makes it
+CREATE OR REPLACE FUNCTION price_history_record() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO price_history (album_id, price, currency, changed_at, changed_by)
+        VALUES (NEW.id, NEW.price, NEW.currency, now(), NEW.updated_by);
+    ELSIF NEW.price IS DISTINCT FROM OLD.price OR NEW.currency IS DISTINCT FROM OLD.currency THEN
+        INSERT INTO price_history (album_id, price, currency, previous_price, previous_currency, changed_at, changed_by)
+        VALUES (NEW.id, NEW.price, NEW.currency, OLD.price, OLD.currency, now(), NEW.updated_by);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS price_history_record ON albums;
+CREATE TRIGGER price_history_record
+    AFTER INSERT OR UPDATE OF price, currency ON albums
+    FOR EACH ROW EXECUTE PROCEDURE price_history_record();
+
+CREATE TABLE IF NOT EXISTS scheduled_prices (
+    id serial NOT NULL,
+    album_id INTEGER NOT NULL,
+    price NUMERIC NOT NULL,
+    currency CHAR(3) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    applied_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_scheduled_prices" PRIMARY KEY (id),
+    CONSTRAINT "FK_tbl_scheduled_prices_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE,
+    CONSTRAINT "CK_tbl_scheduled_prices_price" CHECK (price >= 0),
+    CONSTRAINT "CK_tbl_scheduled_prices_currency" CHECK (currency ~ '^[A-Z]{3}$')
+);
+
+-- the scheduler polls for due pending prices, reads resolve the due price of their albums
+CREATE INDEX IF NOT EXISTS "IX_tbl_scheduled_prices_due" ON scheduled_prices (effective_from) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS "IX_tbl_scheduled_prices_album_id" ON scheduled_prices (album_id, effective_from) WHERE status = 'pending';
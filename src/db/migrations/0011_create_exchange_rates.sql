@@ -0,0 +1,12 @@
+-- rate is how many units of the currency one unit of a common base currency buys, the base itself has rate 1.
+-- Any currency converts to any other through the base.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    as_of TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_exchange_rates" PRIMARY KEY (currency),
+    CONSTRAINT "CK_tbl_exchange_rates_rate" CHECK (rate > 0),
+    CONSTRAINT "CK_tbl_exchange_rates_currency" CHECK (currency ~ '^[A-Z]{3}$')
+);
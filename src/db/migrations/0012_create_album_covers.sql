@@ -0,0 +1,13 @@
+-- the images themselves are in blob storage under covers/<album_id>/<version>/, version is a hash of the upload
+CREATE TABLE IF NOT EXISTS album_covers (
+    album_id INTEGER NOT NULL,
+    version VARCHAR(64) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT "PK_tbl_album_covers" PRIMARY KEY (album_id),
+    CONSTRAINT "FK_tbl_album_covers_album_id" FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE
+);
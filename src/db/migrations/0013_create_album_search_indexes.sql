@@ -0,0 +1,4 @@
+-- full-text search on album titles and artist names, the search queries use the same expressions.
+-- The simple configuration does not stem, so prefix queries match what was typed.
+CREATE INDEX IF NOT EXISTS "IX_tbl_albums_title_search" ON albums USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS "IX_tbl_artists_name_search" ON artists USING GIN (to_tsvector('simple', name));
@@ -0,0 +1,18 @@
+-- typo tolerant search on album titles and artist names with pg_trgm. Creating the extension needs privileges a
+-- hosted database may not give, without it the indexes are skipped and fuzzy search runs in the application.
+DO $$
+BEGIN
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN insufficient_privilege OR undefined_file THEN
+	RAISE NOTICE 'pg_trgm is not available, fuzzy search falls back to the application';
+END
+$$;
+
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+		CREATE INDEX IF NOT EXISTS "IX_tbl_albums_title_trgm" ON albums USING GIN (lower(title) gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS "IX_tbl_artists_name_trgm" ON artists USING GIN (lower(name) gin_trgm_ops);
+	END IF;
+END
+$$;
@@ -0,0 +1,18 @@
+-- a price written to an album replaces the scheduled prices that were due already, otherwise reads and the
+-- scheduler would put an older scheduled price over it. The scheduler sets app.applying_scheduled_prices
+-- while it writes the prices it applies, it settles their status itself.
+CREATE OR REPLACE FUNCTION scheduled_prices_supersede_due() RETURNS trigger AS $$
+BEGIN
+    IF current_setting('app.applying_scheduled_prices', true) IS DISTINCT FROM 'on'
+        AND (NEW.price IS DISTINCT FROM OLD.price OR NEW.currency IS DISTINCT FROM OLD.currency) THEN
+        UPDATE scheduled_prices SET status = 'superseded'
+        WHERE album_id = NEW.id AND status = 'pending' AND effective_from <= now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS scheduled_prices_supersede_due ON albums;
+CREATE TRIGGER scheduled_prices_supersede_due
+    AFTER UPDATE OF price, currency ON albums
+    FOR EACH ROW EXECUTE PROCEDURE scheduled_prices_supersede_due();
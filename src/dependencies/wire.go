@@ -2,17 +2,92 @@ package dependencies
 
 // import (
 // 	"acy.com/api/src/db"
+// 	"acy.com/api/src/lib"
 // 	"acy.com/api/src/repositories"
 // 	"acy.com/api/src/services"
 // 	"github.com/google/wire"
 // )
 
 // func InitializeAlbumService() *services.AlbumService {
-//     wire.Build(repositories.AlbumRepository, services.AlbumService, db.PostgresDbProvider)
+//     wire.Build(repositories.AlbumRepository, repositories.AlbumMongoDBRepository, InitializeReviewService, InitializeCoverService, InitializeAuditService, services.AlbumService, db.PostgresDbProvider, db.GetMongoDb, lib.EventBusProvider, lib.ContentStorageProvider)
 //     return &services.AlbumService{}
 // }
 
 // func InitializeAlbumMongoDBService() *services.AlbumMongoService {
-//     wire.Build(repositories.AlbumMongoDBRepository, services.AlbumMongoService, db.GetMongoDb)
+//     wire.Build(repositories.AlbumMongoDBRepository, services.AlbumMongoService, db.GetMongoDb, lib.EventBusProvider, lib.ContentStorageProvider)
 //     return &services.AlbumMongoService{}
-// }
\ No newline at end of file
+// }
+
+// func InitializeAlbumBatchService() *services.AlbumBatchService {
+//     wire.Build(repositories.NewAlbumRepository, repositories.AlbumMongoDBRepository, InitializeArtistService, InitializeGenreService, InitializeAuditService, InitializeContentRevisionService, InitializeReviewService, InitializeCoverService, services.AlbumBatchService, db.PostgresDbProvider, db.GetMongoDb, lib.EventBusProvider, lib.ContentStorageProvider)
+//     return &services.AlbumBatchService{}
+// }
+
+// func InitializeApiKeyService() *services.ApiKeyService {
+//     wire.Build(repositories.NewApiKeyRepository, services.ApiKeyService, db.PostgresDbProvider)
+//     return &services.ApiKeyService{}
+// }
+
+// func InitializeAlbumReadService() *services.AlbumReadService {
+//     wire.Build(repositories.NewUserAlbumReadRepository, services.AlbumReadService, db.PostgresDbProvider, lib.EventBusProvider)
+//     return &services.AlbumReadService{}
+// }
+
+// func InitializeAuditService() *services.AuditService {
+//     wire.Build(repositories.NewAuditRepository, services.AuditService, db.PostgresDbProvider)
+//     return &services.AuditService{}
+// }
+
+// func InitializeWebhookService() *services.WebhookService {
+//     wire.Build(repositories.NewWebhookRepository, services.WebhookService, services.WebhookConfigProvider, db.PostgresDbProvider)
+//     return &services.WebhookService{}
+// }
+
+// func InitializeArtistService() *services.ArtistService {
+//     wire.Build(repositories.NewArtistRepository, services.ArtistService, db.PostgresDbProvider)
+//     return &services.ArtistService{}
+// }
+
+// func InitializeTrackService() *services.TrackService {
+//     wire.Build(repositories.NewTrackRepository, services.TrackService, db.PostgresDbProvider, lib.EventBusProvider)
+//     return &services.TrackService{}
+// }
+
+// func InitializeGenreService() *services.GenreService {
+//     wire.Build(repositories.NewGenreRepository, services.GenreService, db.PostgresDbProvider)
+//     return &services.GenreService{}
+// }
+
+// func InitializeReviewService() *services.ReviewService {
+//     wire.Build(repositories.ReviewMongoDBRepository, services.ReviewService, db.GetMongoDb)
+//     return &services.ReviewService{}
+// }
+// func InitializePriceService() *services.PriceService {
+//     wire.Build(repositories.NewPriceRepository, repositories.NewAlbumRepository, services.PriceService, services.PriceSchedulerConfigProvider, db.PostgresDbProvider, lib.EventBusProvider)
+//     return &services.PriceService{}
+// }
+
+// func InitializeExchangeRateService() *services.ExchangeRateService {
+//     wire.Build(repositories.NewExchangeRateRepository, services.ExchangeRateService, services.ExchangeRateCacheProvider, db.PostgresDbProvider)
+//     return &services.ExchangeRateService{}
+// }
+
+// func InitializeContentRevisionService() *services.ContentRevisionService {
+//     wire.Build(repositories.ContentRevisionMongoDBRepository, repositories.AlbumMongoDBRepository, InitializeAlbumService, services.ContentRevisionService, db.GetMongoDb, lib.EventBusProvider, lib.ContentStorageProvider)
+//     return &services.ContentRevisionService{}
+// }
+
+// func InitializeCoverService() *services.CoverService {
+//     wire.Build(repositories.NewCoverRepository, services.CoverService, services.CoverConfigProvider, db.PostgresDbProvider, lib.BlobStorageProvider, lib.EventBusProvider)
+//     return &services.CoverService{}
+// }
+
+// func InitializeSearchService() *services.SearchService {
+//     wire.Build(repositories.NewAlbumSearchRepository, repositories.AlbumSearchMongoDBRepository, repositories.NewAlbumRepository, services.SearchService, services.FuzzySearchConfigProvider, db.PostgresDbProvider, db.GetMongoDb)
+//     return &services.SearchService{}
+// }
+
+// func InitializeStatsService() *services.StatsService {
+//     wire.Build(repositories.NewStatsRepository, repositories.StatsMongoDBRepository, repositories.NewUserAlbumReadRepository, services.StatsService, services.StatsConfigProvider, db.PostgresDbProvider, db.GetMongoDb)
+//     return &services.StatsService{}
+// }
@@ -6,6 +6,7 @@ package dependencies
 
 import (
 	"acy.com/api/src/db"
+	"acy.com/api/src/lib"
 	"acy.com/api/src/repositories"
 	"acy.com/api/src/services"
 )
@@ -15,13 +16,145 @@ import (
 func InitializeAlbumService() *services.IAlbumService {
 	conn := db.PostgresDbProvider()
 	var albumRepository repositories.IAlbumRepository = repositories.NewAlbumRepository(conn)
-	var albumService services.IAlbumService = services.AlbumService(&albumRepository)
+	database := db.GetMongoDb()
+	var albumMongoDBRepository repositories.IAlbumMongoDBRepository = repositories.AlbumMongoDBRepository(database, lib.ContentStorageProvider())
+	reviewService := InitializeReviewService()
+	coverService := InitializeCoverService()
+	auditService := InitializeAuditService()
+	var albumService services.IAlbumService = services.AlbumService(&albumRepository, &albumMongoDBRepository, reviewService, coverService, auditService, lib.EventBusProvider())
 	return &albumService
 }
 
 func InitializeAlbumMongoDBService() *services.IAlbumMongoService {
 	database := db.GetMongoDb()
-	var albumMongoDBRepository repositories.IAlbumMongoDBRepository = repositories.AlbumMongoDBRepository(database)
-	var albumMongoService services.IAlbumMongoService = services.AlbumMongoService(&albumMongoDBRepository)
+	var albumMongoDBRepository repositories.IAlbumMongoDBRepository = repositories.AlbumMongoDBRepository(database, lib.ContentStorageProvider())
+	var albumMongoService services.IAlbumMongoService = services.AlbumMongoService(&albumMongoDBRepository, lib.EventBusProvider())
 	return &albumMongoService
 }
+
+func InitializeAlbumBatchService() *services.IAlbumBatchService {
+	conn := db.PostgresDbProvider()
+	var albumRepository repositories.IAlbumRepository = repositories.NewAlbumRepository(conn)
+	database := db.GetMongoDb()
+	var albumMongoDBRepository repositories.IAlbumMongoDBRepository = repositories.AlbumMongoDBRepository(database, lib.ContentStorageProvider())
+	artistService := InitializeArtistService()
+	genreService := InitializeGenreService()
+	auditService := InitializeAuditService()
+	contentRevisionService := InitializeContentRevisionService()
+	reviewService := InitializeReviewService()
+	coverService := InitializeCoverService()
+	var albumBatchService services.IAlbumBatchService = services.AlbumBatchService(&albumRepository, &albumMongoDBRepository, artistService, genreService, auditService, contentRevisionService, reviewService, coverService, lib.EventBusProvider())
+	return &albumBatchService
+}
+
+func InitializeApiKeyService() *services.IApiKeyService {
+	conn := db.PostgresDbProvider()
+	var apiKeyRepository repositories.IApiKeyRepository = repositories.NewApiKeyRepository(conn)
+	var apiKeyService services.IApiKeyService = services.ApiKeyService(&apiKeyRepository)
+	return &apiKeyService
+}
+
+func InitializeAlbumReadService() *services.IAlbumReadService {
+	conn := db.PostgresDbProvider()
+	var userAlbumReadRepository repositories.IUserAlbumReadRepository = repositories.NewUserAlbumReadRepository(conn)
+	var albumReadService services.IAlbumReadService = services.AlbumReadService(&userAlbumReadRepository, lib.EventBusProvider())
+	return &albumReadService
+}
+
+func InitializeAuditService() *services.IAuditService {
+	conn := db.PostgresDbProvider()
+	var auditRepository repositories.IAuditRepository = repositories.NewAuditRepository(conn)
+	var auditService services.IAuditService = services.AuditService(&auditRepository)
+	return &auditService
+}
+
+func InitializeWebhookService() *services.IWebhookService {
+	conn := db.PostgresDbProvider()
+	var webhookRepository repositories.IWebhookRepository = repositories.NewWebhookRepository(conn)
+	webhookConfig := services.WebhookConfigProvider()
+	var webhookService services.IWebhookService = services.WebhookService(&webhookRepository, webhookConfig)
+	return &webhookService
+}
+
+func InitializeArtistService() *services.IArtistService {
+	conn := db.PostgresDbProvider()
+	var artistRepository repositories.IArtistRepository = repositories.NewArtistRepository(conn)
+	var artistService services.IArtistService = services.ArtistService(&artistRepository)
+	return &artistService
+}
+
+func InitializeTrackService() *services.ITrackService {
+	conn := db.PostgresDbProvider()
+	var trackRepository repositories.ITrackRepository = repositories.NewTrackRepository(conn)
+	var trackService services.ITrackService = services.TrackService(&trackRepository, lib.EventBusProvider())
+	return &trackService
+}
+
+func InitializeGenreService() *services.IGenreService {
+	conn := db.PostgresDbProvider()
+	var genreRepository repositories.IGenreRepository = repositories.NewGenreRepository(conn)
+	var genreService services.IGenreService = services.GenreService(&genreRepository)
+	return &genreService
+}
+
+func InitializeReviewService() *services.IReviewService {
+	database := db.GetMongoDb()
+	var reviewMongoDBRepository repositories.IReviewMongoDBRepository = repositories.ReviewMongoDBRepository(database)
+	var reviewService services.IReviewService = services.ReviewService(&reviewMongoDBRepository)
+	return &reviewService
+}
+
+func InitializePriceService() *services.IPriceService {
+	conn := db.PostgresDbProvider()
+	var priceRepository repositories.IPriceRepository = repositories.NewPriceRepository(conn)
+	var albumRepository repositories.IAlbumRepository = repositories.NewAlbumRepository(conn)
+	priceSchedulerConfig := services.PriceSchedulerConfigProvider()
+	var priceService services.IPriceService = services.PriceService(&priceRepository, &albumRepository, priceSchedulerConfig, lib.EventBusProvider())
+	return &priceService
+}
+
+func InitializeExchangeRateService() *services.IExchangeRateService {
+	conn := db.PostgresDbProvider()
+	var exchangeRateRepository repositories.IExchangeRateRepository = repositories.NewExchangeRateRepository(conn)
+	var exchangeRateService services.IExchangeRateService = services.ExchangeRateService(&exchangeRateRepository, services.ExchangeRateCacheProvider())
+	return &exchangeRateService
+}
+
+func InitializeContentRevisionService() *services.IContentRevisionService {
+	database := db.GetMongoDb()
+	var contentRevisionMongoDBRepository repositories.IContentRevisionMongoDBRepository = repositories.ContentRevisionMongoDBRepository(database, lib.ContentStorageProvider())
+	var albumMongoDBRepository repositories.IAlbumMongoDBRepository = repositories.AlbumMongoDBRepository(database, lib.ContentStorageProvider())
+	albumService := InitializeAlbumService()
+	var contentRevisionService services.IContentRevisionService = services.ContentRevisionService(&contentRevisionMongoDBRepository, &albumMongoDBRepository, albumService, lib.EventBusProvider())
+	return &contentRevisionService
+}
+
+func InitializeCoverService() *services.ICoverService {
+	conn := db.PostgresDbProvider()
+	var coverRepository repositories.ICoverRepository = repositories.NewCoverRepository(conn)
+	coverConfig := services.CoverConfigProvider()
+	var coverService services.ICoverService = services.CoverService(&coverRepository, lib.BlobStorageProvider(), coverConfig, lib.EventBusProvider())
+	return &coverService
+}
+
+func InitializeSearchService() *services.ISearchService {
+	conn := db.PostgresDbProvider()
+	var albumSearchRepository repositories.IAlbumSearchRepository = repositories.NewAlbumSearchRepository(conn)
+	database := db.GetMongoDb()
+	var albumSearchMongoDBRepository repositories.IAlbumSearchMongoDBRepository = repositories.AlbumSearchMongoDBRepository(database)
+	var albumRepository repositories.IAlbumRepository = repositories.NewAlbumRepository(conn)
+	fuzzySearchConfig := services.FuzzySearchConfigProvider()
+	var searchService services.ISearchService = services.SearchService(&albumSearchRepository, &albumSearchMongoDBRepository, &albumRepository, fuzzySearchConfig)
+	return &searchService
+}
+
+func InitializeStatsService() *services.IStatsService {
+	conn := db.PostgresDbProvider()
+	var statsRepository repositories.IStatsRepository = repositories.NewStatsRepository(conn)
+	database := db.GetMongoDb()
+	var statsMongoDBRepository repositories.IStatsMongoDBRepository = repositories.StatsMongoDBRepository(database)
+	var userAlbumReadRepository repositories.IUserAlbumReadRepository = repositories.NewUserAlbumReadRepository(conn)
+	statsConfig := services.StatsConfigProvider()
+	var statsService services.IStatsService = services.StatsService(&statsRepository, &statsMongoDBRepository, &userAlbumReadRepository, statsConfig)
+	return &statsService
+}
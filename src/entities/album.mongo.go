@@ -1,10 +1,32 @@
 package entities
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type AlbumMongoDB struct {
 	ID     primitive.ObjectID    `bson:"_id"`
 	Name  string                 `bson:"name,omitempty"`
+	// Content is the markdown source, ContentHtml is it rendered and sanitized by the repository
 	Content string               `bson:"content,omitempty"`
+	ContentHtml string           `bson:"contentHtml,omitempty"`
+	// ContentFileId and ContentHtmlFileId are set instead of Content and ContentHtml when those are larger than
+	// CONTENT_INLINE_MAX_BYTES, documents are found without them and the repository's ReadContent reads them from GridFS
+	ContentFileId *primitive.ObjectID     `bson:"contentFileId,omitempty"`
+	ContentHtmlFileId *primitive.ObjectID `bson:"contentHtmlFileId,omitempty"`
+	// ContentSize is the length of Content in bytes
+	ContentSize int64            `bson:"contentSize,omitempty"`
+	// ContentText is the plain text of content kept in GridFS, for the text index and search highlights, up to
+	// CONTENT_INLINE_MAX_BYTES. Terms are the words of the name and the content that prefixes are looked up in.
+	// Both are written by the repository and left out of the documents it finds.
+	ContentText string           `bson:"contentText,omitempty"`
+	Terms []string               `bson:"terms,omitempty"`
 	AlbumId uint  				 `bson:"albumId,omitempty"`
+	// managed by the repository
+	CreatedAt time.Time          `bson:"createdAt,omitempty"`
+	UpdatedAt time.Time          `bson:"updatedAt,omitempty"`
+	CreatedBy string             `bson:"createdBy,omitempty"`
+	UpdatedBy string             `bson:"updatedBy,omitempty"`
 }
\ No newline at end of file
@@ -1,9 +1,30 @@
 package entities
 
+import (
+    "time"
+
+    "github.com/lib/pq"
+    "github.com/shopspring/decimal"
+)
+
 type Album struct {
     Id     uint  `json:"id" binding:"required,numeric,min=1" gorm:"primaryKey;autoIncrement;notnull"`
     Title  string  `json:"title" binding:"required"`
-    Artist string  `json:"artist" binding:"required"`
-    Price  float64 `json:"price" binding:"required,numeric,min=0"`
-    HasRead bool `json:"has_read"`
+    ArtistId uint  `json:"artist_id" binding:"required,numeric,min=1"`
+    // Artist is loaded together with the album
+    Artist *Artist `json:"artist,omitempty" gorm:"foreignKey:ArtistId"`
+    // Price is exact, it is written as a string in JSON
+    Price  decimal.Decimal `json:"price" swaggertype:"string" example:"56.99"`
+    // Currency is an ISO 4217 code
+    Currency string `json:"currency" example:"USD"`
+    Genres []Genre `json:"genres" gorm:"many2many:album_genres"`
+    // Tags are free-form, lower case and sorted
+    Tags pq.StringArray `json:"tags" gorm:"type:text[]" swaggertype:"array,string"`
+    // Tracks are only set when the album is created with its track listing
+    Tracks []Track `json:"tracks,omitempty" gorm:"foreignKey:AlbumId"`
+    // managed by the repository
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    CreatedBy string    `json:"created_by"`
+    UpdatedBy string    `json:"updated_by"`
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// AlbumCover describes the cover image of an album, the images are in blob storage
+type AlbumCover struct {
+	AlbumId uint `json:"album_id" gorm:"primaryKey"`
+	// Version changes with every upload, it is part of the blob keys and of the cover urls
+	Version     string    `json:"version"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   string    `json:"updated_by"`
+}
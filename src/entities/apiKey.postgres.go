@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ApiKey struct {
+	Id         uint           `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	KeyHash    string         `json:"-"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]" swaggertype:"array,string"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+type Artist struct {
+	Id   uint   `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	Name string `json:"name"`
+	// NameKey is the normalized name, artists are unique by it
+	NameKey   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedBy string    `json:"updated_by"`
+}
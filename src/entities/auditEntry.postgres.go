@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditEntityAlbum = "album"
+
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// AuditEntry is an append-only record of a mutation, the table rejects updates and deletes
+type AuditEntry struct {
+	Id        uint         `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	Entity    string       `json:"entity"`
+	EntityId  string       `json:"entity_id"`
+	Action    string       `json:"action"`
+	Actor     string       `json:"actor"`
+	RequestId string       `json:"request_id"`
+	SourceIp  string       `json:"source_ip"`
+	Before    JSONDocument `json:"before" gorm:"type:jsonb" swaggertype:"object"`
+	After     JSONDocument `json:"after" gorm:"type:jsonb" swaggertype:"object"`
+	Changes   JSONDocument `json:"changes" gorm:"type:jsonb" swaggertype:"object"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// JSONDocument is stored in a jsonb column, nil is stored as NULL
+type JSONDocument map[string]interface{}
+
+func (document JSONDocument) Value() (driver.Value, error) {
+	if document == nil {
+		return nil, nil
+	}
+	value, err := json.Marshal(document)
+	return string(value), err
+}
+
+func (document *JSONDocument) Scan(value interface{}) error {
+	switch typed := value.(type) {
+	case nil:
+		*document = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(typed, document)
+	case string:
+		return json.Unmarshal([]byte(typed), document)
+	default:
+		return fmt.Errorf("unsupported json document type %T", value)
+	}
+}
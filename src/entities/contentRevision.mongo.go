@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AlbumContentRevisionMongoDB is a version of the content of an album, revisions are numbered from 1 on each album
+type AlbumContentRevisionMongoDB struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id" swaggertype:"string"`
+	AlbumId  uint               `bson:"albumId" json:"album_id"`
+	Revision int                `bson:"revision" json:"revision"`
+	// Content is left out of revision lists
+	Content string `bson:"content" json:"content,omitempty"`
+	// ContentFileId is set instead of Content when the content is larger than CONTENT_INLINE_MAX_BYTES
+	ContentFileId *primitive.ObjectID `bson:"contentFileId,omitempty" json:"-"`
+	// RestoredFrom is the revision this one brought back, it is not set on edits
+	RestoredFrom int       `bson:"restoredFrom,omitempty" json:"restored_from,omitempty"`
+	Author       string    `bson:"author" json:"author"`
+	CreatedAt    time.Time `bson:"createdAt" json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate is how many units of Currency one unit of the base currency of the rate table buys
+type ExchangeRate struct {
+	Currency string          `json:"currency" gorm:"primaryKey"`
+	Rate     decimal.Decimal `json:"rate" swaggertype:"string" example:"0.9215"`
+	// AsOf is when the rate was quoted
+	AsOf      time.Time `json:"as_of"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+}
@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+type Genre struct {
+	Id   uint   `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	Name string `json:"name"`
+	// Slug identifies the genre in album payloads and filters, e.g. "hip-hop"
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ScheduledPricePending = "pending"
+	ScheduledPriceApplied = "applied"
+	// ScheduledPriceSuperseded is a price that was due together with a later one for the same album, or that was due
+	// when another price was written to the album, it was never applied
+	ScheduledPriceSuperseded = "superseded"
+	ScheduledPriceCancelled  = "cancelled"
+)
+
+// PriceChange is a row of the price history of an album, it is written by the database whenever a price is written.
+// The first change of an album has no previous price.
+type PriceChange struct {
+	Id               uint64           `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	AlbumId          uint             `json:"album_id"`
+	Price            decimal.Decimal  `json:"price" swaggertype:"string"`
+	Currency         string           `json:"currency"`
+	PreviousPrice    *decimal.Decimal `json:"previous_price" swaggertype:"string"`
+	PreviousCurrency *string          `json:"previous_currency"`
+	ChangedAt        time.Time        `json:"changed_at"`
+	ChangedBy        string           `json:"changed_by"`
+}
+
+func (PriceChange) TableName() string {
+	return "price_history"
+}
+
+// ScheduledPrice is a price an album takes from EffectiveFrom on
+type ScheduledPrice struct {
+	Id            uint            `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	AlbumId       uint            `json:"album_id"`
+	Price         decimal.Decimal `json:"price" swaggertype:"string"`
+	Currency      string          `json:"currency"`
+	EffectiveFrom time.Time       `json:"effective_from"`
+	Status        string          `json:"status"`
+	AppliedAt     *time.Time      `json:"applied_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	CreatedBy     string          `json:"created_by"`
+}
@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewMongoDB is the review of an album by one user, a user reviews an album at most once
+type ReviewMongoDB struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id" swaggertype:"string"`
+	AlbumId   uint               `bson:"albumId" json:"album_id"`
+	Author    string             `bson:"author" json:"author"`
+	Rating    int                `bson:"rating" json:"rating"`
+	Title     string             `bson:"title" json:"title"`
+	Body      string             `bson:"body" json:"body"`
+	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updated_at"`
+}
+
+// AlbumRatingMongoDB sums up the reviews of an album, it is recomputed from the reviews whenever one is written.
+// Every recount takes the next Version before it counts, Count and Sum are from the recount of CountedVersion.
+type AlbumRatingMongoDB struct {
+	AlbumId        uint  `bson:"albumId"`
+	Count          int64 `bson:"count"`
+	Sum            int64 `bson:"sum"`
+	Version        int64 `bson:"version"`
+	CountedVersion int64 `bson:"countedVersion"`
+}
+
+// Average is the mean rating, nil when the album has no reviews
+func (rating AlbumRatingMongoDB) Average() *float64 {
+	if rating.Count <= 0 {
+		return nil
+	}
+	average := float64(rating.Sum) / float64(rating.Count)
+	return &average
+}
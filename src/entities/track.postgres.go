@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// Track belongs to an album, tracks are numbered from 1 without gaps on each disc
+type Track struct {
+	Id              uint      `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	AlbumId         uint      `json:"album_id"`
+	DiscNumber      uint      `json:"disc_number"`
+	Number          uint      `json:"number"`
+	Title           string    `json:"title"`
+	DurationSeconds uint      `json:"duration_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	CreatedBy       string    `json:"created_by"`
+	UpdatedBy       string    `json:"updated_by"`
+}
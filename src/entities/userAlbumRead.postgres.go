@@ -0,0 +1,9 @@
+package entities
+
+import "time"
+
+type UserAlbumRead struct {
+	UserId  string    `json:"user_id" gorm:"primaryKey"`
+	AlbumId uint      `json:"album_id" gorm:"primaryKey"`
+	ReadAt  time.Time `json:"read_at"`
+}
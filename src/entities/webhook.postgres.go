@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryDead is a delivery that ran out of attempts, it is only sent again when redelivered by an admin
+	WebhookDeliveryDead = "dead"
+)
+
+type WebhookSubscription struct {
+	Id         uint           `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	Url        string         `json:"url"`
+	EventTypes pq.StringArray `json:"event_types" gorm:"type:text[]" swaggertype:"array,string"`
+	Secret     string         `json:"-"`
+	Active     bool           `json:"active"`
+	CreatedAt  time.Time      `json:"created_at"`
+	CreatedBy  string         `json:"created_by"`
+}
+
+type WebhookDelivery struct {
+	Id             uint       `json:"id" gorm:"primaryKey;autoIncrement;notnull"`
+	SubscriptionId uint       `json:"subscription_id"`
+	EventId        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
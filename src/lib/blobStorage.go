@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"acy.com/api/src/utils"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// IBlobStorage stores opaque blobs under slash separated keys, e.g. "covers/12/ab34/small.jpg"
+type IBlobStorage interface {
+	// Put stores the content of reader under key, replacing what was there. Readers never see a partial blob.
+	Put(key string, reader io.Reader) error
+	// Open returns the blob under key for reading, ErrBlobNotFound when there is none
+	Open(key string) (io.ReadSeekCloser, BlobInfo, error)
+	// Delete removes the blobs under prefix, the prefix is a key or a directory of keys
+	Delete(prefix string) error
+}
+
+// BlobStorageProvider returns the storage selected by BLOB_STORAGE_BACKEND. "local", the only backend so far and the
+// default, is a directory set by BLOB_STORAGE_PATH, instances behind a load balancer need to share that directory.
+func BlobStorageProvider() IBlobStorage {
+	utils.InitEnv()
+	switch os.Getenv("BLOB_STORAGE_BACKEND") {
+	default:
+		path := os.Getenv("BLOB_STORAGE_PATH")
+		if path == "" {
+			path = "storage"
+		}
+		return NewLocalBlobStorage(path)
+	}
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type localBlobStorage struct {
+	root string
+}
+
+// NewLocalBlobStorage stores blobs as files below root
+func NewLocalBlobStorage(root string) *localBlobStorage {
+	return &localBlobStorage{root: root}
+}
+
+// path maps a key to its file, keys that would leave root are rejected
+func (storage *localBlobStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidBlobKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidBlobKey
+		}
+	}
+	return filepath.Join(storage.root, filepath.FromSlash(key)), nil
+}
+
+func (storage *localBlobStorage) Put(key string, reader io.Reader) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write next to the target and rename, so a reader sees the old or the new blob and nothing in between
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (storage *localBlobStorage) Open(key string) (io.ReadSeekCloser, BlobInfo, error) {
+	path, err := storage.path(key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, BlobInfo{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, BlobInfo{}, err
+	}
+	return file, BlobInfo{Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+func (storage *localBlobStorage) Delete(prefix string) error {
+	path, err := storage.path(prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
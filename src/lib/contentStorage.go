@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"acy.com/api/src/utils"
+)
+
+// ContentStorage says where album content is kept and how large it may get
+type ContentStorage struct {
+	// InlineMaxBytes is the largest markdown or html kept in a document, larger content goes to GridFS.
+	// Both have to fit in one document next to each other, so it is at most 8MB.
+	InlineMaxBytes int
+	// MaxBytes is the largest content the content upload accepts
+	MaxBytes int64
+}
+
+var (
+	contentStorage     ContentStorage
+	contentStorageOnce sync.Once
+)
+
+// ContentStorageProvider reads CONTENT_INLINE_MAX_BYTES, 1MB when it is not set, and CONTENT_MAX_BYTES, 64MB when it is not set
+func ContentStorageProvider() ContentStorage {
+	contentStorageOnce.Do(func() {
+		utils.InitEnv()
+		contentStorage = ContentStorage{InlineMaxBytes: 1 << 20, MaxBytes: 64 << 20}
+		if value, err := strconv.Atoi(os.Getenv("CONTENT_INLINE_MAX_BYTES")); err == nil && value >= 0 {
+			contentStorage.InlineMaxBytes = value
+		}
+		if contentStorage.InlineMaxBytes > 8<<20 {
+			contentStorage.InlineMaxBytes = 8 << 20
+		}
+		if value, err := strconv.ParseInt(os.Getenv("CONTENT_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+			contentStorage.MaxBytes = value
+		}
+	})
+	return contentStorage
+}
+
+// NopReadSeekCloser serves content held in memory like content in a file
+func NopReadSeekCloser(reader io.ReadSeeker) io.ReadSeekCloser {
+	return nopReadSeekCloser{reader}
+}
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error {
+	return nil
+}
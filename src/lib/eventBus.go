@@ -0,0 +1,154 @@
+package lib
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"acy.com/api/src/utils"
+)
+
+const (
+	defaultEventReplayBuffer = 1000
+	// events a subscriber may fall behind before it is dropped, it can resume from its last event id
+	eventSubscriberBuffer = 64
+)
+
+// Event is a message published on the EventBus. Ids increase by one per event and restart with the process,
+// EventId tells them apart across restarts.
+type Event struct {
+	Id   uint64
+	Type string
+	Data interface{}
+	Time time.Time
+}
+
+// EventBus is an in-process publish/subscribe bus that keeps the latest events for subscribers that resume
+type EventBus struct {
+	mu sync.Mutex
+	// epoch is when the bus was made in nanoseconds, it prefixes the ids sent to clients
+	epoch       string
+	lastId      uint64
+	replay      []Event
+	replaySize  int
+	subscribers map[*EventSubscription]struct{}
+}
+
+// EventSubscription receives every event published after Subscribe.
+// Events is closed when the subscriber falls too far behind or the subscription is closed.
+type EventSubscription struct {
+	// Replay holds the buffered events after the requested last event id
+	Replay []Event
+	// Missed is set when events after the requested last event id are no longer buffered
+	Missed bool
+	Events <-chan Event
+
+	bus    *EventBus
+	events chan Event
+}
+
+var (
+	eventBus     *EventBus
+	eventBusOnce sync.Once
+)
+
+func NewEventBus(replaySize int) *EventBus {
+	epoch := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return &EventBus{epoch: epoch, replaySize: replaySize, subscribers: map[*EventSubscription]struct{}{}}
+}
+
+// EventBusProvider returns the bus shared by the whole process, EVENT_REPLAY_BUFFER sets how many events it keeps
+func EventBusProvider() *EventBus {
+	eventBusOnce.Do(func() {
+		utils.InitEnv()
+		replaySize, err := strconv.Atoi(os.Getenv("EVENT_REPLAY_BUFFER"))
+		if err != nil || replaySize <= 0 {
+			replaySize = defaultEventReplayBuffer
+		}
+		eventBus = NewEventBus(replaySize)
+	})
+	return eventBus
+}
+
+// Publish assigns the next id to the event and delivers it without blocking on slow subscribers
+func (bus *EventBus) Publish(eventType string, data interface{}) Event {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.lastId++
+	event := Event{Id: bus.lastId, Type: eventType, Data: data, Time: time.Now()}
+
+	bus.replay = append(bus.replay, event)
+	if len(bus.replay) > bus.replaySize {
+		bus.replay = bus.replay[len(bus.replay)-bus.replaySize:]
+	}
+
+	for subscription := range bus.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			bus.drop(subscription)
+		}
+	}
+	return event
+}
+
+// Subscribe starts a subscription. A lastEventId above 0 resumes after that event from the replay buffer.
+func (bus *EventBus) Subscribe(lastEventId uint64) *EventSubscription {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	events := make(chan Event, eventSubscriberBuffer)
+	subscription := &EventSubscription{Events: events, bus: bus, events: events}
+
+	if lastEventId > 0 {
+		// an id from before a restart is ahead of lastId, nothing after it can be replayed
+		subscription.Missed = lastEventId > bus.lastId || (len(bus.replay) > 0 && bus.replay[0].Id > lastEventId+1)
+		for _, event := range bus.replay {
+			if event.Id > lastEventId {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+
+	bus.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// EventId is the id of event for clients, "<epoch>-<id>", an id of another process can never be taken for one of this bus
+func (bus *EventBus) EventId(event Event) string {
+	return bus.epoch + "-" + strconv.FormatUint(event.Id, 10)
+}
+
+// SubscribeAfter starts a subscription that resumes after lastEventId, an id made by EventId. An id of another
+// process, or one that can not be read, misses whatever came after it.
+func (bus *EventBus) SubscribeAfter(lastEventId string) *EventSubscription {
+	if lastEventId == "" {
+		return bus.Subscribe(0)
+	}
+	parts := strings.SplitN(lastEventId, "-", 2)
+	if len(parts) == 2 && parts[0] == bus.epoch {
+		if lastId, err := strconv.ParseUint(parts[1], 10, 64); err == nil {
+			return bus.Subscribe(lastId)
+		}
+	}
+	subscription := bus.Subscribe(0)
+	subscription.Missed = true
+	return subscription
+}
+
+// Close ends the subscription, it is safe to call more than once
+func (subscription *EventSubscription) Close() {
+	subscription.bus.mu.Lock()
+	defer subscription.bus.mu.Unlock()
+	subscription.bus.drop(subscription)
+}
+
+func (bus *EventBus) drop(subscription *EventSubscription) {
+	if _, ok := bus.subscribers[subscription]; ok {
+		delete(bus.subscribers, subscription)
+		close(subscription.events)
+	}
+}
@@ -0,0 +1,161 @@
+package lib
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"acy.com/api/src/utils"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JwtClaims are the claims read from a bearer token, Subject is inherited from the registered claims
+type JwtClaims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// JwtKeySet is the local set of keys bearer tokens are verified against.
+// HS256 keys are kept as []byte and RS256 keys as *rsa.PublicKey, both indexed by key id.
+type JwtKeySet struct {
+	keys     map[string]interface{}
+	issuer   string
+	audience string
+}
+
+// jsonWebKey is the subset of RFC 7517 needed for "oct" and "RSA" keys
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// minHmacKeyBytes is the shortest HS256 secret accepted, RFC 7518 asks for a key as long as the hash
+const minHmacKeyBytes = 32
+
+// retiredKeyIds are ids of keys that were leaked, a key set using one is refused so that tokens signed with the
+// leaked key are never accepted again
+var retiredKeyIds = map[string]bool{"local-dev": true}
+
+// ParseJwtKeySet reads a JWK set document ({"keys": [...]}) containing "oct" and "RSA" keys, a set without keys is an error
+func ParseJwtKeySet(data []byte, issuer string, audience string) (*JwtKeySet, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	keySet := &JwtKeySet{keys: map[string]interface{}{}, issuer: issuer, audience: audience}
+	for _, key := range document.Keys {
+		if retiredKeyIds[key.Kid] {
+			return nil, fmt.Errorf("key %q was retired, generate a new key under another id", key.Kid)
+		}
+		switch key.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key.Kid, err)
+			}
+			if len(secret) < minHmacKeyBytes {
+				return nil, fmt.Errorf("key %q: an HS256 secret needs at least %d bytes", key.Kid, minHmacKeyBytes)
+			}
+			keySet.keys[key.Kid] = secret
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(key.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(key.E)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key.Kid, err)
+			}
+			keySet.keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		default:
+			return nil, fmt.Errorf("key %q: unsupported key type %q", key.Kid, key.Kty)
+		}
+	}
+	if len(keySet.keys) == 0 {
+		return nil, errors.New("the key set has no keys")
+	}
+	return keySet, nil
+}
+
+// JwtKeySetProvider loads the key set from the file in JWT_KEYSET_FILE, the server does not start without one.
+// jwks.example.json shows the format, the key set itself is not kept in the repository.
+func JwtKeySetProvider() *JwtKeySet {
+	utils.InitEnv()
+	path := os.Getenv("JWT_KEYSET_FILE")
+	if path == "" {
+		panic("JWT_KEYSET_FILE is not set, bearer tokens can not be verified without a key set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(err.Error())
+	}
+	keySet, err := ParseJwtKeySet(data, os.Getenv("JWT_ISSUER"), os.Getenv("JWT_AUDIENCE"))
+	if err != nil {
+		panic(err.Error())
+	}
+	return keySet
+}
+
+// Verify checks the signature and the time based claims of token, plus issuer and audience when configured
+func (keySet *JwtKeySet) Verify(token string) (*JwtClaims, error) {
+	claims := &JwtClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}))
+	if _, err := parser.ParseWithClaims(token, claims, keySet.keyFunc); err != nil {
+		return nil, err
+	}
+	if keySet.issuer != "" && !claims.VerifyIssuer(keySet.issuer, true) {
+		return nil, errors.New("token has an invalid issuer")
+	}
+	if keySet.audience != "" && !claims.VerifyAudience(keySet.audience, true) {
+		return nil, errors.New("token has an invalid audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+// keyFunc picks the key named by the "kid" header, or the only key of the right type when there is no "kid".
+// The key type has to match the signing method, so an RSA public key can never be used as an HMAC secret.
+func (keySet *JwtKeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	matches := func(key interface{}) bool {
+		switch key.(type) {
+		case []byte:
+			return token.Method == jwt.SigningMethodHS256
+		case *rsa.PublicKey:
+			return token.Method == jwt.SigningMethodRS256
+		}
+		return false
+	}
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok := keySet.keys[kid]; ok && matches(key) {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	var found interface{}
+	for _, key := range keySet.keys {
+		if matches(key) {
+			if found != nil {
+				return nil, errors.New("token has no key id")
+			}
+			found = key
+		}
+	}
+	if found == nil {
+		return nil, errors.New("no key for signing method " + token.Method.Alg())
+	}
+	return found, nil
+}
@@ -0,0 +1,181 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// content formats of album content
+const (
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHtml     = "html"
+	ContentFormatText     = "text"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// contentPolicy is what rendered content may keep: the markup markdown produces, links that can not run script
+	// and open with rel="nofollow noreferrer", and no inline styles, event handlers, frames or forms
+	contentPolicy = newContentPolicy()
+	textPolicy    = bluemonday.StrictPolicy()
+)
+
+func newContentPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[a-zA-Z0-9_+-]+$`)).OnElements("code")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.RequireNoReferrerOnLinks(true)
+	return policy
+}
+
+// RenderMarkdown renders markdown source, GitHub flavoured, to html that is safe to embed in a page.
+// Raw html in the source is dropped by the renderer and whatever gets through is sanitized.
+func RenderMarkdown(source string) (string, error) {
+	var rendered bytes.Buffer
+	if err := markdown.Convert([]byte(source), &rendered); err != nil {
+		return "", err
+	}
+	return contentPolicy.Sanitize(rendered.String()), nil
+}
+
+// RenderMarkdownTo renders markdown source like RenderMarkdown and writes the html to w as it is sanitized,
+// without holding the html in memory
+func RenderMarkdownTo(w io.Writer, source []byte) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(markdown.Convert(source, writer))
+	}()
+	err := contentPolicy.SanitizeReaderToWriter(reader, w)
+	// the renderer is stopped when the html can not be written
+	reader.CloseWithError(err)
+	return err
+}
+
+// ContentText is the plain text of rendered content, without markup and with entities decoded,
+// every block of the content is a line
+func ContentText(renderedHtml string) string {
+	var text strings.Builder
+	// reading from and writing to memory can not fail
+	WriteContentText(&text, strings.NewReader(renderedHtml))
+	return text.String()
+}
+
+// WriteContentText writes the plain text of rendered content to w like ContentText, line by line as it is read
+func WriteContentText(w io.Writer, renderedHtml io.Reader) error {
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		writer.CloseWithError(textPolicy.SanitizeReaderToWriter(renderedHtml, writer))
+		close(done)
+	}()
+	// the sanitizer is stopped when the text can not be written, renderedHtml is not read any more once this returns
+	defer func() {
+		reader.Close()
+		<-done
+	}()
+
+	sanitized, separator := bufio.NewReader(reader), ""
+	for {
+		line, readErr := sanitized.ReadString('\n')
+		// decoded entities can be line breaks too
+		for _, decoded := range strings.Split(html.UnescapeString(line), "\n") {
+			if decoded = strings.TrimSpace(decoded); decoded != "" {
+				if _, err := io.WriteString(w, separator+decoded); err != nil {
+					return err
+				}
+				separator = "\n"
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// OpenContentText opens the plain text of rendered content like ContentText for reading from any offset, without
+// holding the html or the text in memory. The html is read once to measure the text and again for every read after a
+// seek, closing the text closes the html.
+func OpenContentText(renderedHtml io.ReadSeekCloser) (io.ReadSeekCloser, error) {
+	size := &byteCounter{}
+	if err := WriteContentText(size, renderedHtml); err != nil {
+		renderedHtml.Close()
+		return nil, err
+	}
+	open := func(offset int64) (io.ReadCloser, error) {
+		if _, err := renderedHtml.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		reader, writer := io.Pipe()
+		text := &contentTextStream{PipeReader: reader, written: make(chan struct{})}
+		go func() {
+			writer.CloseWithError(WriteContentText(writer, renderedHtml))
+			close(text.written)
+		}()
+		if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+			text.Close()
+			return nil, err
+		}
+		return text, nil
+	}
+	first, err := open(0)
+	if err != nil {
+		renderedHtml.Close()
+		return nil, err
+	}
+	return &contentText{ReadSeekCloser: NewSeekableStream(first, size.n, open), html: renderedHtml}, nil
+}
+
+// contentText is the text of rendered content that closes the html with it
+type contentText struct {
+	io.ReadSeekCloser
+	html io.Closer
+}
+
+func (text *contentText) Close() error {
+	err := text.ReadSeekCloser.Close()
+	if htmlErr := text.html.Close(); err == nil {
+		err = htmlErr
+	}
+	return err
+}
+
+// contentTextStream is the text as it is written from the html, closing it stops the writer and waits for it so the
+// html can be read again
+type contentTextStream struct {
+	*io.PipeReader
+	written chan struct{}
+}
+
+func (stream *contentTextStream) Close() error {
+	err := stream.PipeReader.Close()
+	<-stream.written
+	return err
+}
+
+// byteCounter counts the bytes written to it without keeping them
+type byteCounter struct {
+	n int64
+}
+
+func (counter *byteCounter) Write(p []byte) (int, error) {
+	counter.n += int64(len(p))
+	return len(p), nil
+}
+
+// IsContentFormat tells whether format is one of the content formats
+func IsContentFormat(format string) bool {
+	return format == ContentFormatMarkdown || format == ContentFormatHtml || format == ContentFormatText
+}
@@ -0,0 +1,167 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"acy.com/api/src/utils"
+	"github.com/shopspring/decimal"
+)
+
+const fallbackCurrency = "USD"
+
+// currencies are the ISO 4217 currency and fund codes in use, without precious metals, bond market units, XTS and XXX
+var currencies = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL BSD BTN BWP BYN BZD
+		CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL
+		GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD
+		KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO
+		NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD
+		SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS VED VES VND VUV
+		WST XAF XCD XCG XDR XOF XPF XSU XUA YER ZAR ZMW ZWG`) {
+		currencies[code] = true
+	}
+}
+
+// currencyMinorUnits lists the ISO 4217 currencies that do not have 2 decimal places
+var currencyMinorUnits = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// rounding modes of converted prices
+const (
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even"
+	RoundUp       = "up"
+	RoundDown     = "down"
+)
+
+var (
+	ErrNegativePrice = errors.New("price cannot be negative")
+
+	defaultCurrency     string
+	defaultCurrencyOnce sync.Once
+
+	priceRounding     RoundingRules
+	priceRoundingOnce sync.Once
+)
+
+// RoundingRules say how a computed price, e.g. a converted one, is rounded to what its currency can express
+type RoundingRules struct {
+	// Mode is RoundHalfUp, RoundHalfEven, RoundUp or RoundDown, up and down are away from and toward zero
+	Mode string
+	// Increments are the steps prices of a currency move in when they are coarser than its minor unit, e.g. 0.05 CHF
+	Increments map[string]decimal.Decimal
+}
+
+// IsCurrency tells whether code is an ISO 4217 currency code, upper case
+func IsCurrency(code string) bool {
+	return currencies[code]
+}
+
+// CurrencyMinorUnits is the number of decimal places of an ISO 4217 currency
+func CurrencyMinorUnits(currency string) int32 {
+	if units, ok := currencyMinorUnits[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+// ValidatePrice rejects negative prices and prices with more decimal places than their currency has
+func ValidatePrice(price decimal.Decimal, currency string) error {
+	if price.IsNegative() {
+		return ErrNegativePrice
+	}
+	if units := CurrencyMinorUnits(currency); !price.Equal(price.Truncate(units)) {
+		return fmt.Errorf("a %s price has at most %d decimal places", currency, units)
+	}
+	return nil
+}
+
+// FormatPrice writes price with the decimal places of its currency, e.g. "56.90" or "1200". A price saved before
+// ValidatePrice checked decimal places keeps the places it has, it is never rounded.
+func FormatPrice(price decimal.Decimal, currency string) string {
+	units := CurrencyMinorUnits(currency)
+	if !price.Equal(price.Truncate(units)) {
+		return price.String()
+	}
+	return price.StringFixed(units)
+}
+
+// DefaultCurrency is the currency of prices given without one, DEFAULT_CURRENCY sets it and it is USD otherwise.
+// Migrations price existing albums in it, the server does not start with one that ParseDefaultCurrency refuses.
+func DefaultCurrency() string {
+	defaultCurrencyOnce.Do(func() {
+		utils.InitEnv()
+		var err error
+		if defaultCurrency, err = ParseDefaultCurrency(os.Getenv("DEFAULT_CURRENCY")); err != nil {
+			panic(err.Error())
+		}
+	})
+	return defaultCurrency
+}
+
+// ParseDefaultCurrency reads a DEFAULT_CURRENCY value, USD when it is empty
+func ParseDefaultCurrency(value string) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(value))
+	if currency == "" {
+		return fallbackCurrency, nil
+	}
+	if !IsCurrency(currency) {
+		return "", fmt.Errorf("DEFAULT_CURRENCY %q is not an ISO 4217 currency code", value)
+	}
+	return currency, nil
+}
+
+// Round rounds amount to the increment of currency, or to its minor unit when it has none
+func (rules RoundingRules) Round(amount decimal.Decimal, currency string) decimal.Decimal {
+	if increment, ok := rules.Increments[currency]; ok && increment.IsPositive() {
+		return rules.round(amount.Div(increment), 0).Mul(increment)
+	}
+	return rules.round(amount, CurrencyMinorUnits(currency))
+}
+
+func (rules RoundingRules) round(amount decimal.Decimal, places int32) decimal.Decimal {
+	switch rules.Mode {
+	case RoundHalfEven:
+		return amount.RoundBank(places)
+	case RoundUp:
+		return amount.RoundUp(places)
+	case RoundDown:
+		return amount.RoundDown(places)
+	default:
+		return amount.Round(places)
+	}
+}
+
+// PriceRoundingRules reads PRICE_ROUNDING_MODE, half_up when it is not set, and PRICE_ROUNDING_INCREMENTS,
+// a comma separated list of currency=increment pairs, e.g. "CHF=0.05,SEK=1"
+func PriceRoundingRules() RoundingRules {
+	priceRoundingOnce.Do(func() {
+		utils.InitEnv()
+		priceRounding = RoundingRules{Mode: RoundHalfUp, Increments: map[string]decimal.Decimal{}}
+		switch mode := strings.TrimSpace(os.Getenv("PRICE_ROUNDING_MODE")); mode {
+		case RoundHalfEven, RoundUp, RoundDown:
+			priceRounding.Mode = mode
+		}
+		for _, pair := range strings.Split(os.Getenv("PRICE_ROUNDING_INCREMENTS"), ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			if increment, err := decimal.NewFromString(strings.TrimSpace(parts[1])); err == nil && increment.IsPositive() {
+				priceRounding.Increments[strings.ToUpper(strings.TrimSpace(parts[0]))] = increment
+			}
+		}
+	})
+	return priceRounding
+}
@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"acy.com/api/src/utils"
+)
+
+// RateLimitPolicy is a token bucket holding up to Limit tokens that refills Limit tokens every Period
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next token is available, zero when the request was allowed
+	RetryAfter time.Duration
+}
+
+type IRateLimitStore interface {
+	// Take removes one token from the bucket of key
+	Take(key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// RateLimitStoreProvider returns the store selected by RATE_LIMIT_BACKEND, "memory" (default) or "redis".
+// The memory store only limits a single instance, instances behind a load balancer need to share redis.
+func RateLimitStoreProvider() IRateLimitStore {
+	utils.InitEnv()
+	if os.Getenv("RATE_LIMIT_BACKEND") == "redis" {
+		return NewRedisRateLimitStore(RedisClientProvider())
+	}
+	return NewMemoryRateLimitStore()
+}
+
+// refillRate is the number of tokens added per second
+func (policy RateLimitPolicy) refillRate() float64 {
+	return float64(policy.Limit) / policy.Period.Seconds()
+}
+
+// result converts the tokens left in a bucket to the limiter result
+func (policy RateLimitPolicy) result(allowed bool, tokens float64) RateLimitResult {
+	rate := policy.refillRate()
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Limit:      policy.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(policy.Limit) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+type tokenBucket struct {
+	tokens    float64
+	period    time.Duration
+	updatedAt time.Time
+}
+
+type memoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	sweptAt time.Time
+}
+
+const memoryRateLimitSweepInterval = time.Minute
+
+// NewMemoryRateLimitStore keeps the buckets in process memory
+func NewMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+func (store *memoryRateLimitStore) Take(key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	store.sweep(now)
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(policy.Limit), period: policy.Period, updatedAt: now}
+		store.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(policy.Limit), bucket.tokens+elapsed*policy.refillRate())
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return policy.result(allowed, bucket.tokens), nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, a missing bucket counts as full
+func (store *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(store.sweptAt) < memoryRateLimitSweepInterval {
+		return
+	}
+	store.sweptAt = now
+	for key, bucket := range store.buckets {
+		if now.Sub(bucket.updatedAt) > bucket.period {
+			delete(store.buckets, key)
+		}
+	}
+}
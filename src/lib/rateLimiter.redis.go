@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"acy.com/api/src/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// takeTokenScript refills and takes from a bucket atomically, using the redis clock so every
+// instance sees the same time. It returns whether a token was taken and the tokens left.
+var takeTokenScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or limit
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((limit - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimitStore keeps the buckets in redis so they are shared by every instance
+func NewRedisRateLimitStore(client *redis.Client) *redisRateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+// RedisClientProvider connects to REDIS_ADDR with REDIS_PASSWORD
+func RedisClientProvider() *redis.Client {
+	utils.InitEnv()
+	return redis.NewClient(&redis.Options{
+		Addr:     os.Getenv("REDIS_ADDR"),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+}
+
+func (store *redisRateLimitStore) Take(key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	// the script works in milliseconds
+	rate := policy.refillRate() / 1000
+	reply, err := takeTokenScript.Run(context.TODO(), store.client, []string{key}, policy.Limit, rate).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(reply) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return policy.result(allowed == 1, tokens), nil
+}
@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+
+	"acy.com/api/src/utils"
+)
+
+const (
+	// RedactDenyList redacts the listed names and keeps everything else
+	RedactDenyList = "deny"
+	// RedactAllowList keeps the listed names and redacts everything else
+	RedactAllowList = "allow"
+
+	RedactedValue = "[REDACTED]"
+)
+
+type RedactorConfig struct {
+	Mode        string
+	Headers     []string
+	QueryParams []string
+	BodyFields  []string
+}
+
+// Redactor removes secrets and personal data from requests before they are logged.
+// Names are matched case-insensitively.
+type Redactor struct {
+	allowList   bool
+	headers     map[string]bool
+	queryParams map[string]bool
+	bodyFields  map[string]bool
+}
+
+var defaultRedactorConfigs = map[string]RedactorConfig{
+	RedactDenyList: {
+		Mode:        RedactDenyList,
+		Headers:     []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"},
+		QueryParams: []string{"token", "access_token", "api_key", "apikey", "key", "password", "secret", "signature"},
+		BodyFields:  []string{"password", "secret", "token", "access_token", "refresh_token", "api_key", "key", "authorization", "email", "phone", "card_number"},
+	},
+	RedactAllowList: {
+		Mode:        RedactAllowList,
+		Headers:     []string{"Accept", "Accept-Encoding", "Content-Length", "Content-Type", "Origin", "User-Agent"},
+		QueryParams: []string{"page", "page_size"},
+		BodyFields:  []string{"id", "op", "mode", "title", "artist", "price"},
+	},
+}
+
+func NewRedactor(config RedactorConfig) *Redactor {
+	toSet := func(names []string) map[string]bool {
+		set := map[string]bool{}
+		for _, name := range names {
+			set[strings.ToLower(name)] = true
+		}
+		return set
+	}
+
+	return &Redactor{
+		allowList:   config.Mode == RedactAllowList,
+		headers:     toSet(config.Headers),
+		queryParams: toSet(config.QueryParams),
+		bodyFields:  toSet(config.BodyFields),
+	}
+}
+
+// RedactorProvider reads REDACT_MODE ("deny" by default or "allow"). REDACT_HEADERS, REDACT_QUERY_PARAMS
+// and REDACT_BODY_FIELDS are comma separated lists that replace the default list of the mode.
+func RedactorProvider() *Redactor {
+	utils.InitEnv()
+	mode := os.Getenv("REDACT_MODE")
+	if mode != RedactAllowList {
+		mode = RedactDenyList
+	}
+
+	config := defaultRedactorConfigs[mode]
+	override := func(key string, defaults []string) []string {
+		if value := os.Getenv(key); value != "" {
+			return strings.Split(value, ",")
+		}
+		return defaults
+	}
+	config.Headers = override("REDACT_HEADERS", config.Headers)
+	config.QueryParams = override("REDACT_QUERY_PARAMS", config.QueryParams)
+	config.BodyFields = override("REDACT_BODY_FIELDS", config.BodyFields)
+	return NewRedactor(config)
+}
+
+func (redactor *Redactor) redacts(names map[string]bool, name string) bool {
+	listed := names[strings.ToLower(strings.TrimSpace(name))]
+	if redactor.allowList {
+		return !listed
+	}
+	return listed
+}
+
+// Header returns a copy of header with the values of redacted headers replaced
+func (redactor *Redactor) Header(header http.Header) http.Header {
+	redacted := http.Header{}
+	for name, values := range header {
+		if redactor.redacts(redactor.headers, name) {
+			redacted[name] = []string{RedactedValue}
+		} else {
+			redacted[name] = values
+		}
+	}
+	return redacted
+}
+
+// Query redacts the values of a raw query string, a query that can not be parsed is dropped
+func (redactor *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return RedactedValue
+	}
+	for name := range values {
+		if redactor.redacts(redactor.queryParams, name) {
+			values[name] = []string{RedactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// JSONBody redacts the fields of a json document at any depth.
+// Anything that is not valid json, including a truncated document, is dropped as a whole.
+func (redactor *Redactor) JSONBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return RedactedValue
+	}
+	redacted, _ := json.Marshal(redactor.redactValue("", document))
+	return string(redacted)
+}
+
+// redactValue walks objects and arrays. In allow list mode a scalar is only kept when its own field name is listed.
+func (redactor *Redactor) redactValue(field string, value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			if !redactor.allowList && redactor.redacts(redactor.bodyFields, key) {
+				typed[key] = RedactedValue
+				continue
+			}
+			typed[key] = redactor.redactValue(key, item)
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = redactor.redactValue(field, item)
+		}
+		return typed
+	default:
+		if redactor.allowList && redactor.redacts(redactor.bodyFields, field) {
+			return RedactedValue
+		}
+		return typed
+	}
+}
+
+// DumpRequest is httputil.DumpRequest with redacted headers, query and json body.
+// body is what has been read of the request body so far, it is only dumped when it is json.
+func (redactor *Redactor) DumpRequest(request *http.Request, body []byte) string {
+	clone := request.Clone(request.Context())
+	clone.Header = redactor.Header(request.Header)
+	clone.URL.RawQuery = redactor.Query(request.URL.RawQuery)
+	clone.RequestURI = ""
+	clone.Body = nil
+	clone.ContentLength = 0
+	clone.TransferEncoding = nil
+
+	dump, err := httputil.DumpRequest(clone, false)
+	if err != nil {
+		return ""
+	}
+	if len(body) > 0 && strings.Contains(request.Header.Get("Content-Type"), "json") {
+		return string(dump) + redactor.JSONBody(body)
+	}
+	return string(dump)
+}
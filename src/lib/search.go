@@ -0,0 +1,245 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSearchTerms bounds the terms of a query, every term is a condition on every backend
+const maxSearchTerms = 16
+
+// MinPrefixLength is the fewest letters of a word being typed that are completed, a shorter prefix starts too many
+// words to look up
+const MinPrefixLength = 3
+
+var (
+	ErrInvalidSearchQuery = errors.New("search query needs at least one word")
+	ErrShortSearchPrefix  = fmt.Errorf("prefix needs at least %d letters", MinPrefixLength)
+)
+
+// SearchTerm is a word, a phrase of words in a row, or, with Prefix, words whose last one is only the start of a word.
+// Words are lower case letters and digits.
+type SearchTerm struct {
+	Words  []string
+	Prefix bool
+}
+
+// SearchQuery matches what has all of its terms
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+// ParseSearchQuery reads a query of words, "quoted phrases" and prefixes ending with *, e.g. `"love supreme" colt*`.
+// Punctuation separates words, so a word like rock-n-roll is the phrase "rock n roll".
+func ParseSearchQuery(q string) (SearchQuery, error) {
+	query := SearchQuery{}
+	add := func(text string, prefix bool) {
+		if words := searchWords(text); len(words) > 0 && len(query.Terms) < maxSearchTerms {
+			query.Terms = append(query.Terms, SearchTerm{Words: words, Prefix: prefix})
+		}
+	}
+
+	words := func(text string) {
+		for _, field := range strings.Fields(text) {
+			add(field, strings.HasSuffix(field, "*"))
+		}
+	}
+	for rest := q; rest != ""; {
+		open := strings.Index(rest, `"`)
+		if open < 0 {
+			words(rest)
+			break
+		}
+		words(rest[:open])
+		rest = rest[open+1:]
+
+		// an unclosed quote runs to the end
+		phrase := rest
+		rest = ""
+		if closing := strings.Index(phrase, `"`); closing >= 0 {
+			phrase, rest = phrase[:closing], phrase[closing+1:]
+		}
+		prefix := strings.HasPrefix(rest, "*")
+		rest = strings.TrimPrefix(rest, "*")
+		add(phrase, prefix)
+	}
+	if len(query.Terms) == 0 {
+		return query, ErrInvalidSearchQuery
+	}
+	return query, nil
+}
+
+// ParsePrefixQuery reads what has been typed so far, its last word is only the start of a word unless it is
+// followed by a space or punctuation. A start shorter than MinPrefixLength is left out of the words before it and
+// is ErrShortSearchPrefix on its own.
+func ParsePrefixQuery(prefix string) (SearchQuery, error) {
+	query := SearchQuery{}
+	words := searchWords(prefix)
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	for _, word := range words {
+		query.Terms = append(query.Terms, SearchTerm{Words: []string{word}})
+	}
+	if len(query.Terms) == 0 {
+		return query, ErrInvalidSearchQuery
+	}
+	if last, _ := utf8.DecodeLastRuneInString(prefix); unicode.IsLetter(last) || unicode.IsNumber(last) {
+		query.Terms[len(query.Terms)-1].Prefix = true
+	}
+	if last := query.Terms[len(query.Terms)-1]; last.Prefix && utf8.RuneCountInString(last.Words[0]) < MinPrefixLength {
+		if len(query.Terms) == 1 {
+			return query, ErrShortSearchPrefix
+		}
+		query.Terms = query.Terms[:len(query.Terms)-1]
+	}
+	return query, nil
+}
+
+// SearchTerms are the distinct words of texts in order, lower case, what prefixes are looked up in
+func SearchTerms(texts ...string) []string {
+	distinct := map[string]bool{}
+	for _, text := range texts {
+		for _, word := range searchWords(text) {
+			distinct[word] = true
+		}
+	}
+	terms := make([]string, 0, len(distinct))
+	for word := range distinct {
+		terms = append(terms, word)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchToken is a word of a text with where it is
+type searchToken struct {
+	word       string
+	start, end int
+}
+
+// HighlightSearch escapes text for html and wraps what matches the terms of query in <mark>, the bool tells
+// whether anything matched. A text longer than maxBytes is cut to a snippet around its first match, marked
+// with … where it is cut; maxBytes 0 keeps the whole text.
+func HighlightSearch(text string, query SearchQuery, maxBytes int) (string, bool) {
+	tokens := searchTokens(text)
+
+	// matches are [start, end) byte ranges of text, in order and not overlapping
+	matches := [][2]int{}
+	for i := 0; i < len(tokens); {
+		length := 0
+		for _, term := range query.Terms {
+			if n := len(term.Words); n > length && termMatches(term, tokens[i:]) {
+				length = n
+			}
+		}
+		if length == 0 {
+			i++
+			continue
+		}
+		matches = append(matches, [2]int{tokens[i].start, tokens[i+length-1].end})
+		i += length
+	}
+	return snippet(text, matches, maxBytes), len(matches) > 0
+}
+
+// HighlightSimilar is HighlightSearch for words of text similar to a word of q, with a WordSimilarity of at least threshold
+func HighlightSimilar(text, q string, threshold float64, maxBytes int) (string, bool) {
+	queryWords := searchWords(q)
+	matches := [][2]int{}
+	for _, token := range searchTokens(text) {
+		for _, word := range queryWords {
+			if WordSimilarity(word, token.word) >= threshold {
+				matches = append(matches, [2]int{token.start, token.end})
+				break
+			}
+		}
+	}
+	return snippet(text, matches, maxBytes), len(matches) > 0
+}
+
+// searchTokens are the words of text, lower case, with where they are
+func searchTokens(text string) []searchToken {
+	tokens := []searchToken{}
+	start := -1
+	for i, r := range text + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, searchToken{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// snippet escapes text for html with matches, [start, end) byte ranges in order and not overlapping, in <mark>,
+// cut to maxBytes around the first match
+func snippet(text string, matches [][2]int, maxBytes int) string {
+	from, to := 0, len(text)
+	if maxBytes > 0 && len(text) > maxBytes {
+		if len(matches) > 0 {
+			from = matches[0][0] - maxBytes/3
+		}
+		if from < 0 {
+			from = 0
+		}
+		if to = from + maxBytes; to > len(text) {
+			to = len(text)
+		}
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from++
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to--
+		}
+	}
+
+	var written strings.Builder
+	if from > 0 {
+		written.WriteString("…")
+	}
+	position := from
+	for _, match := range matches {
+		if match[0] < from || match[1] > to {
+			continue
+		}
+		written.WriteString(html.EscapeString(text[position:match[0]]))
+		written.WriteString("<mark>" + html.EscapeString(text[match[0]:match[1]]) + "</mark>")
+		position = match[1]
+	}
+	written.WriteString(html.EscapeString(text[position:to]))
+	if to < len(text) {
+		written.WriteString("…")
+	}
+	return written.String()
+}
+
+// termMatches tells whether the words of term are the first tokens
+func termMatches(term SearchTerm, tokens []searchToken) bool {
+	if len(tokens) < len(term.Words) {
+		return false
+	}
+	last := len(term.Words) - 1
+	for i, word := range term.Words {
+		if i == last && term.Prefix {
+			return strings.HasPrefix(tokens[i].word, word)
+		}
+		if tokens[i].word != word {
+			return false
+		}
+	}
+	return true
+}
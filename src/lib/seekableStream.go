@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"errors"
+	"io"
+)
+
+// NewSeekableStream reads a stream that only reads forward from any offset, e.g. to serve ranges of it. stream is
+// read from the start, a read after a seek closes it and reads from what reopen returns for the offset instead.
+func NewSeekableStream(stream io.ReadCloser, size int64, reopen func(offset int64) (io.ReadCloser, error)) io.ReadSeekCloser {
+	return &seekableStream{stream: stream, size: size, reopen: reopen}
+}
+
+type seekableStream struct {
+	stream io.ReadCloser
+	size   int64
+	reopen func(offset int64) (io.ReadCloser, error)
+	offset int64
+	// streamOffset is where stream is, it is read from when it is at offset
+	streamOffset int64
+}
+
+func (stream *seekableStream) Read(p []byte) (int, error) {
+	if stream.offset >= stream.size {
+		return 0, io.EOF
+	}
+	if stream.stream == nil || stream.streamOffset != stream.offset {
+		if stream.stream != nil {
+			stream.stream.Close()
+			stream.stream = nil
+		}
+		reopened, err := stream.reopen(stream.offset)
+		if err != nil {
+			return 0, err
+		}
+		stream.stream, stream.streamOffset = reopened, stream.offset
+	}
+
+	n, err := stream.stream.Read(p)
+	stream.offset += int64(n)
+	stream.streamOffset = stream.offset
+	return n, err
+}
+
+func (stream *seekableStream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += stream.offset
+	case io.SeekEnd:
+		offset += stream.size
+	default:
+		return stream.offset, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return stream.offset, errors.New("negative position")
+	}
+	stream.offset = offset
+	return offset, nil
+}
+
+func (stream *seekableStream) Close() error {
+	if stream.stream == nil {
+		return nil
+	}
+	err := stream.stream.Close()
+	stream.stream = nil
+	return err
+}
@@ -0,0 +1,43 @@
+package lib
+
+// wordTrigrams are the trigrams of a lower case word the way pg_trgm makes them, the word padded with two spaces
+// in front and one behind
+func wordTrigrams(word string, trigrams map[string]bool) {
+	padded := []rune("  " + word + " ")
+	for i := 0; i+3 <= len(padded); i++ {
+		trigrams[string(padded[i:i+3])] = true
+	}
+}
+
+// WordSimilarity is how much of query is found in the best run of consecutive words of text, from 0 to 1, like
+// word_similarity of pg_trgm: the share of the trigrams of query that the run has. Both are compared lower case
+// and word by word, so "coltrain" is 0.67 similar to "John Coltrane".
+func WordSimilarity(query, text string) float64 {
+	queryWords, textWords := searchWords(query), searchWords(text)
+	queryTrigrams := map[string]bool{}
+	for _, word := range queryWords {
+		wordTrigrams(word, queryTrigrams)
+	}
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+
+	best := 0
+	for from := range textWords {
+		runTrigrams := map[string]bool{}
+		// runs longer than the query only add trigrams it does not have
+		for to := from; to < len(textWords) && to < from+len(queryWords); to++ {
+			wordTrigrams(textWords[to], runTrigrams)
+			shared := 0
+			for trigram := range queryTrigrams {
+				if runTrigrams[trigram] {
+					shared++
+				}
+			}
+			if shared > best {
+				best = shared
+			}
+		}
+	}
+	return float64(best) / float64(len(queryTrigrams))
+}
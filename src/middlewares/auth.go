@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+
+	// gin context keys set by the authentication middlewares
+	SubjectKey = "subject"
+	RolesKey   = "roles"
+
+	ApiKeyHeader = "X-API-Key"
+)
+
+// roleRanks orders the roles, a caller holding a role is granted every role ranked below it
+var roleRanks = map[string]int{RoleReader: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// JwtAuth validates the bearer token of the request and stores its subject and roles in the gin context
+func JwtAuth(keySet *libs.JwtKeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.Error{Message: "Missing bearer token"})
+			return
+		}
+
+		claims, err := keySet.Verify(strings.TrimSpace(header[7:]))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.Error{Message: "Invalid bearer token"})
+			return
+		}
+
+		c.Set(SubjectKey, claims.Subject)
+		c.Set(RolesKey, claims.Roles)
+		c.Next()
+	}
+}
+
+// ApiKeyAuth validates the X-API-Key header and stores the key's scopes as the caller's roles,
+// so api keys are authorized by the same RequireRole checks as bearer tokens.
+// A key that can not be looked up is a server error, not an invalid key.
+func ApiKeyAuth(apiKeys *services.IApiKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, err := (*apiKeys).Authenticate(c.GetHeader(ApiKeyHeader))
+		if errors.Is(err, services.ErrInvalidApiKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.Error{Message: "Invalid api key"})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.Error{Message: "Unable to verify api key"})
+			return
+		}
+
+		c.Set(SubjectKey, fmt.Sprintf("apikey:%d", apiKey.Id))
+		c.Set(RolesKey, []string(apiKey.Scopes))
+		c.Next()
+	}
+}
+
+// Authenticate uses ApiKeyAuth when the request carries an X-API-Key header and JwtAuth otherwise
+func Authenticate(keySet *libs.JwtKeySet, apiKeys *services.IApiKeyService) gin.HandlerFunc {
+	jwtAuth := JwtAuth(keySet)
+	apiKeyAuth := ApiKeyAuth(apiKeys)
+	return func(c *gin.Context) {
+		if c.GetHeader(ApiKeyHeader) != "" {
+			apiKeyAuth(c)
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// RequireRole rejects callers that do not hold role or a role ranked above it
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.Error{Message: "Requires role " + role})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasRole reports whether the authenticated caller holds role or a role ranked above it
+func HasRole(c *gin.Context, role string) bool {
+	required, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	for _, granted := range c.GetStringSlice(RolesKey) {
+		if roleRanks[granted] >= required {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject returns the authenticated caller, empty for anonymous requests
+func Subject(c *gin.Context) string {
+	return c.GetString(SubjectKey)
+}
@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"acy.com/api/src/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type CorsConfig struct {
+	// AllowedOrigins holds exact origins, "*" for any origin or wildcard subdomains like "https://*.example.com".
+	// Any origin is answered with a literal "*", which browsers never send credentials to.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// CorsConfigProvider reads CORS_ALLOWED_ORIGINS (comma separated), CORS_ALLOW_CREDENTIALS and CORS_MAX_AGE (seconds),
+// the server does not start with a config that Validate refuses
+func CorsConfigProvider() CorsConfig {
+	utils.InitEnv()
+	maxAge, _ := strconv.Atoi(os.Getenv("CORS_MAX_AGE"))
+	allowCredentials, _ := strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS"))
+
+	config := CorsConfig{
+		AllowedOrigins:   splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", ApiKeyHeader, RequestIdHeader},
+		ExposedHeaders:   []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", RequestIdHeader, "X-Next-Cursor"},
+		AllowCredentials: allowCredentials,
+		MaxAge:           time.Duration(maxAge) * time.Second,
+	}
+	if err := config.Validate(); err != nil {
+		panic(err.Error())
+	}
+	return config
+}
+
+// Validate refuses credentials for any origin, every site could read responses made with the caller's credentials
+func (config CorsConfig) Validate() error {
+	if config.AllowCredentials && anyOrigin(config.AllowedOrigins) {
+		return errors.New("CORS_ALLOW_CREDENTIALS can not be set when CORS_ALLOWED_ORIGINS allows any origin, list the origins instead")
+	}
+	return nil
+}
+
+// Cors answers preflight requests and adds the CORS headers for allowed origins.
+// Requests from other origins are passed on without CORS headers, so the browser blocks the response.
+func Cors(config CorsConfig) gin.HandlerFunc {
+	allowedMethods := strings.Join(config.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+	allowsAny := anyOrigin(config.AllowedOrigins)
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !originAllowed(config.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowsAny {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", allowedMethods)
+			c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			if config.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposedHeaders)
+		}
+		c.Next()
+	}
+}
+
+func anyOrigin(allowedOrigins []string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func originAllowed(allowedOrigins []string, origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// "https://*.example.com" allows any subdomain of example.com over https
+		if i := strings.Index(allowed, "*."); i >= 0 {
+			scheme, domain := allowed[:i], allowed[i+1:]
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, domain) && len(origin) > len(scheme)+len(domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,26 +1,31 @@
 package middlewares
 
 import (
+	"bytes"
+	"io"
 	"net"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"runtime/debug"
 	"strings"
 	"time"
 
+	libs "acy.com/api/src/lib"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// recoveryBodyLimit caps how much of the request body GinRecovery keeps for its request dump
+const recoveryBodyLimit = 64 << 10
+
 // GinLogger  receive gin The default log of the framework
 
 
-func GinLogger(logger *zap.Logger) gin.HandlerFunc {
+func GinLogger(logger *zap.Logger, redactor *libs.Redactor) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactor.Query(c.Request.URL.RawQuery)
 		c.Next()
 		cost := time.Since(start)
 		logger.Info(path,
@@ -30,6 +35,7 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 			zap.String("query", query),
 			zap.String("ip", c.ClientIP()),
 			zap.String("user-agent", c.Request.UserAgent()),
+			zap.String("request_id", RequestIdOf(c)),
 			zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
 			zap.Duration("cost", cost),
 		)
@@ -37,8 +43,14 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 }
 
 // GinRecovery recover Drop the project that may appear panic
-func GinRecovery(logger *zap.Logger, stack bool) gin.HandlerFunc {
+func GinRecovery(logger *zap.Logger, stack bool, redactor *libs.Redactor) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// keep what the handlers read of the body, so it can be part of the request dump
+		body := &cappedBuffer{limit: recoveryBodyLimit}
+		if c.Request.Body != nil {
+			c.Request.Body = readCloser{Reader: io.TeeReader(c.Request.Body, body), Closer: c.Request.Body}
+		}
+
 		defer func() {
 			if err := recover(); err != nil {
 				// Check for a broken connection, as it is not really a
@@ -52,12 +64,12 @@ func GinRecovery(logger *zap.Logger, stack bool) gin.HandlerFunc {
 					}
 				}
 
-				httpRequest, _ := httputil.DumpRequest(c.Request, false)
+				httpRequest := redactor.DumpRequest(c.Request, body.Bytes())
 
 				if brokenPipe {
 					logger.Error(c.Request.URL.Path,
 						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
+						zap.String("request", httpRequest),
 					)
 
 					// If the connection is dead, we can't write a status to it.
@@ -69,13 +81,13 @@ func GinRecovery(logger *zap.Logger, stack bool) gin.HandlerFunc {
 				if stack {
 					logger.Error("[Recovery from panic]",
 						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
+						zap.String("request", httpRequest),
 						zap.String("stack", string(debug.Stack())),
 					)
 				} else {
 					logger.Error("[Recovery from panic]",
 						zap.Any("error", err),
-						zap.String("request", string(httpRequest)),
+						zap.String("request", httpRequest),
 					)
 				}
 				c.AbortWithStatus(http.StatusInternalServerError)
@@ -84,3 +96,25 @@ func GinRecovery(logger *zap.Logger, stack bool) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cappedBuffer keeps the first limit bytes written to it and silently drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (buffer *cappedBuffer) Write(p []byte) (int, error) {
+	if room := buffer.limit - buffer.Len(); room > 0 {
+		if len(p) > room {
+			buffer.Buffer.Write(p[:room])
+		} else {
+			buffer.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
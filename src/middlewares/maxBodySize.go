@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"acy.com/api/src/models"
+	"acy.com/api/src/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimits holds the maximum request body size in bytes, Routes is keyed by method and route like "POST /api/v1/albums/".
+// Streamed routes read their body as it arrives and answer 413 themselves when it goes past the limit.
+type BodyLimits struct {
+	Default  int64
+	Routes   map[string]int64
+	Streamed map[string]bool
+}
+
+// DefaultMaxBodyBytesProvider reads MAX_BODY_BYTES, 1MB when it is not set
+func DefaultMaxBodyBytesProvider() int64 {
+	utils.InitEnv()
+	if limit, err := strconv.ParseInt(os.Getenv("MAX_BODY_BYTES"), 10, 64); err == nil && limit > 0 {
+		return limit
+	}
+	return 1 << 20
+}
+
+// MaxBodySize answers 413 when the request body is larger than the limit of its route.
+// Bodies without Content-Length are read up to the limit before the handler runs, so handlers
+// never see a body that is cut off halfway, except on streamed routes.
+func MaxBodySize(limits BodyLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := limits.Routes[route]
+		if !ok {
+			limit = limits.Default
+		}
+
+		if c.Request.ContentLength > limit {
+			abortBodyTooLarge(c, limit)
+			return
+		}
+
+		if c.Request.ContentLength < 0 && !limits.Streamed[route] {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+				return
+			}
+			if int64(len(body)) > limit {
+				abortBodyTooLarge(c, limit)
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		} else {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge tells whether err comes from reading a body past its limit, net/http has no error type for it
+func IsBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.Error{Message: "Request body exceeds " + strconv.FormatInt(limit, 10) + " bytes"})
+}
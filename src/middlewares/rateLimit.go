@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RateLimitPolicies picks the policy of a request, Routes is keyed by method and route like "GET /api/v1/albums/"
+type RateLimitPolicies struct {
+	Default libs.RateLimitPolicy
+	Routes  map[string]libs.RateLimitPolicy
+}
+
+// RateLimit gives every client its own token bucket per policy. Clients are identified by their api key or
+// user once authenticated and by ip otherwise. When the store is unavailable the request is let through.
+func RateLimit(store libs.IRateLimitStore, policies RateLimitPolicies, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := policies.Routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			policy = policies.Default
+		}
+
+		client := "ip:" + c.ClientIP()
+		if subject := Subject(c); subject != "" {
+			client = "sub:" + subject
+		}
+		takeToken(c, store, policy, client, logger)
+	}
+}
+
+// IpRateLimit limits every ip with one bucket before the request is authenticated, so floods of
+// requests with invalid credentials are turned away before any token or api key lookup is done
+func IpRateLimit(store libs.IRateLimitStore, policy libs.RateLimitPolicy, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		takeToken(c, store, policy, "ip:"+c.ClientIP(), logger)
+	}
+}
+
+// takeToken takes a token from the bucket of client and aborts with 429 when it is empty
+func takeToken(c *gin.Context, store libs.IRateLimitStore, policy libs.RateLimitPolicy, client string, logger *zap.Logger) {
+	result, err := store.Take(fmt.Sprintf("ratelimit:%s:%s", policy.Name, client), policy)
+	if err != nil {
+		logger.Warn("rate limit store unavailable",
+			zap.String("policy", policy.Name),
+			zap.String("error", err.Error()),
+		)
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Period)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, models.Error{Message: "Too many requests"})
+		return
+	}
+	c.Next()
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIdHeader = "X-Request-ID"
+	// gin context key set by RequestId
+	RequestIdKey = "request_id"
+
+	maxRequestIdLength = 128
+)
+
+// RequestId keeps the X-Request-ID sent by the client, or generates one, and echoes it in the response
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+
+		c.Set(RequestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+		c.Next()
+	}
+}
+
+// RequestIdOf returns the request id set by RequestId
+func RequestIdOf(c *gin.Context) string {
+	return c.GetString(RequestIdKey)
+}
+
+// validRequestId only accepts short printable ascii ids, so a client can not inject anything into logs
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(requestId); i++ {
+		if requestId[i] < '!' || requestId[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"acy.com/api/src/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge in seconds, Strict-Transport-Security is left out when it is 0
+	HSTSMaxAge            int
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+// SecurityHeadersProvider returns the headers for api responses, HSTS_MAX_AGE sets the HSTS max age in seconds
+func SecurityHeadersProvider() SecurityHeadersConfig {
+	utils.InitEnv()
+	hstsMaxAge, _ := strconv.Atoi(os.Getenv("HSTS_MAX_AGE"))
+
+	return SecurityHeadersConfig{
+		HSTSMaxAge: hstsMaxAge,
+		// api responses are json and never need to load anything
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+	}
+}
+
+// SecurityHeaders sets the security headers of config on every response, a later SecurityHeaders on a
+// route overrides the ones set by the engine
+func SecurityHeaders(config SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := fmt.Sprintf("max-age=%d; includeSubDomains", config.HSTSMaxAge)
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if config.HSTSMaxAge > 0 {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+		}
+		if config.FrameOptions != "" {
+			header.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		c.Next()
+	}
+}
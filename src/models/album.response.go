@@ -1,10 +1,59 @@
 package models
 
+import (
+    "time"
+
+    "acy.com/api/src/entities"
+)
+
 type AlbumResponse struct {
     Id     uint  `json:"id"`
     Title  string  `json:"title"`
+    // Artist is the artist name
     Artist string  `json:"artist"`
-    Price  float64 `json:"price"`
+    ArtistId uint `json:"artist_id"`
+    // Price has the decimal places of its currency, prices saved before they were checked keep the places they have
+    Price  string `json:"price" example:"56.99"`
+    Currency string `json:"currency" example:"USD"`
+    // ConvertedPrice is the price in the currency asked for with ?currency= or Accept-Currency
+    ConvertedPrice *ConvertedPriceResponse `json:"converted_price,omitempty"`
+    // Genres are genre slugs
+    Genres []string `json:"genres"`
+    Tags []string `json:"tags"`
+    // TotalDurationSeconds is the sum of the track durations
+    TotalDurationSeconds uint `json:"total_duration_seconds"`
+    // AverageRating is the mean review rating rounded to two decimals, null without reviews
+    AverageRating *float64 `json:"average_rating"`
+    ReviewCount int64 `json:"review_count"`
+    // Content is markdown, or sanitized html or plain text rendered from it, as ContentFormat says. It is empty
+    // when the content is too large to embed, ContentUrl streams it then.
 	Content string `json:"content"`
+    ContentFormat string `json:"content_format" example:"markdown"`
+    // ContentSize is the length of the markdown in bytes
+    ContentSize int64 `json:"content_size" example:"2048"`
+    ContentUrl string `json:"content_url,omitempty" example:"/api/v1/albums/1/content?content_format=markdown"`
+    // CoverUrls are keyed by size: original, small, medium and large, albums without a cover have none
+    CoverUrls map[string]string `json:"cover_urls,omitempty" swaggertype:"object,string"`
     HasRead bool `json:"has_read"`
+    ReadAt *time.Time `json:"read_at,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    CreatedBy string `json:"created_by"`
+    UpdatedBy string `json:"updated_by"`
+    // Embedded holds the related resources requested with ?include=
+    Embedded *AlbumEmbedded `json:"embedded,omitempty"`
+}
+
+type ConvertedPriceResponse struct {
+    Price string `json:"price" example:"52.47"`
+    Currency string `json:"currency" example:"EUR"`
+    // Rate is how many units of Currency one unit of the album currency buys
+    Rate string `json:"rate" example:"0.9215"`
+    // RateAsOf is when the rate was quoted
+    RateAsOf time.Time `json:"rate_as_of"`
+}
+
+type AlbumEmbedded struct {
+    Artist *entities.Artist `json:"artist,omitempty"`
+    Tracks []entities.Track `json:"tracks,omitempty"`
 }
\ No newline at end of file
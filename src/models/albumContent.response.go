@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// AlbumContentResponse describes the content of an album as written, without the content itself
+type AlbumContentResponse struct {
+	AlbumId uint `json:"album_id"`
+	// ContentSize is the length of the markdown in bytes
+	ContentSize int64     `json:"content_size" example:"2048"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   string    `json:"updated_by"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AlbumCoverResponse describes the cover image of an album
+type AlbumCoverResponse struct {
+	AlbumId     uint   `json:"album_id"`
+	Version     string `json:"version" example:"9f86d081884c7d65"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	Width       int    `json:"width" example:"1200"`
+	Height      int    `json:"height" example:"1200"`
+	SizeBytes   int64  `json:"size_bytes" example:"245760"`
+	// Urls are keyed by size: original, small, medium and large
+	Urls      map[string]string `json:"urls" swaggertype:"object,string"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	UpdatedBy string            `json:"updated_by"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"acy.com/api/src/entities"
+)
+
+// album event types published on the event bus and streamed by GET /albums/events
+const (
+	AlbumEventCreated = "album.created"
+	AlbumEventUpdated = "album.updated"
+	AlbumEventDeleted = "album.deleted"
+	AlbumEventRead    = "album.read"
+	// AlbumEventResync tells a resuming client that events were lost and it should reload, e.g. with updated_since
+	AlbumEventResync = "album.resync"
+)
+
+type AlbumEventResponse struct {
+	AlbumId uint `json:"album_id"`
+	// Album is the album as written, for created and updated events
+	Album *entities.Album `json:"album,omitempty"`
+	// UserId and ReadAt are set for read events
+	UserId string     `json:"user_id,omitempty"`
+	ReadAt *time.Time `json:"read_at,omitempty"`
+}
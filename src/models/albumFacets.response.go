@@ -0,0 +1,27 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// AlbumFacetsResponse counts the albums matching the current filters by genre, tag, artist and price bucket
+type AlbumFacetsResponse struct {
+	Total   int64                `json:"total"`
+	Genres  []FacetCountResponse `json:"genres"`
+	Tags    []FacetCountResponse `json:"tags"`
+	Artists []FacetCountResponse `json:"artists"`
+	// Prices count the albums of every currency by price, the currencies with the most albums first
+	Prices []CurrencyPriceBucketsResponse `json:"prices"`
+}
+
+type FacetCountResponse struct {
+	// Value is what to pass to the matching filter: the genre slug, the tag or the artist id
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// PriceBucketResponse counts the albums priced from From (inclusive) to To (exclusive), the last bucket has no To
+type PriceBucketResponse struct {
+	From  decimal.Decimal  `json:"from" swaggertype:"string"`
+	To    *decimal.Decimal `json:"to,omitempty" swaggertype:"string"`
+	Count int64            `json:"count"`
+}
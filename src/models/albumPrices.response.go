@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// AlbumPricesResponse is the price of an album now, how it got there and what it is scheduled to become
+type AlbumPricesResponse struct {
+	AlbumId  uint   `json:"album_id"`
+	Price    string `json:"price" example:"56.99"`
+	Currency string `json:"currency" example:"USD"`
+	// History is newest first, its first change is the current price once a due scheduled price is applied
+	History []PriceChangeResponse `json:"history"`
+	// Scheduled are the pending prices in the order they take effect
+	Scheduled []ScheduledPriceResponse `json:"scheduled"`
+}
+
+type PriceChangeResponse struct {
+	Price            string    `json:"price" example:"56.99"`
+	Currency         string    `json:"currency" example:"USD"`
+	PreviousPrice    *string   `json:"previous_price,omitempty" example:"59.99"`
+	PreviousCurrency string    `json:"previous_currency,omitempty" example:"USD"`
+	ChangedAt        time.Time `json:"changed_at"`
+	ChangedBy        string    `json:"changed_by"`
+}
+
+type ScheduledPriceResponse struct {
+	Id            uint      `json:"id"`
+	Price         string    `json:"price" example:"49.99"`
+	Currency      string    `json:"currency" example:"USD"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     string    `json:"created_by"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type AlbumReadResponse struct {
+	AlbumId uint       `json:"album_id"`
+	HasRead bool       `json:"has_read"`
+	ReadAt  *time.Time `json:"read_at,omitempty"`
+}
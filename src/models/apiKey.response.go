@@ -0,0 +1,9 @@
+package models
+
+import "acy.com/api/src/entities"
+
+type ApiKeyResponse struct {
+	entities.ApiKey
+	// Key is the plain api key, it is only returned once when the key is created
+	Key string `json:"key,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+
+	// MaxBatchOperations is the upper bound of operations accepted by a single batch request
+	MaxBatchOperations = 100
+)
+
+type BatchAlbumDto struct {
+	Mode       string                   `json:"mode" binding:"omitempty,oneof=all_or_nothing best_effort" default:"all_or_nothing"`
+	Operations []BatchAlbumOperationDto `json:"operations" binding:"required,min=1,max=100"`
+}
+
+type BatchAlbumOperationDto struct {
+	Op    string          `json:"op" binding:"required,oneof=create update delete"`
+	Id    uint            `json:"id"`
+	Album *CreateAlbumDto `json:"album"`
+}
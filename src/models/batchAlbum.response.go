@@ -0,0 +1,16 @@
+package models
+
+type BatchAlbumResponse struct {
+	Mode      string                     `json:"mode"`
+	Succeeded int                        `json:"succeeded"`
+	Failed    int                        `json:"failed"`
+	Results   []BatchAlbumResultResponse `json:"results"`
+}
+
+type BatchAlbumResultResponse struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Id     uint   `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type ContentDiffResponse struct {
+	AlbumId uint `json:"album_id"`
+	From    int  `json:"from"`
+	To      int  `json:"to"`
+	// Additions and Deletions count changed lines, a changed line counts as both
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	// Diff is a unified diff, empty when the revisions have the same content
+	Diff string `json:"diff"`
+}
@@ -1,8 +1,21 @@
 package models
 
+import "github.com/shopspring/decimal"
+
 type CreateAlbumDto struct {
     Title  string  `json:"title" binding:"required"`
-    Artist string  `json:"artist" binding:"required"`
-    Price  float64 `json:"price" binding:"required,numeric,min=0"`
+    // Artist is the artist name, it is used when ArtistId is not given and creates the artist when it is unknown
+    Artist string  `json:"artist" binding:"required_without=ArtistId"`
+    ArtistId uint  `json:"artist_id"`
+    // Price is a decimal string, e.g. "56.99", with at most the decimal places of the currency
+    Price  *decimal.Decimal `json:"price" binding:"required" swaggertype:"string" example:"56.99"`
+    // Currency is an ISO 4217 code, DEFAULT_CURRENCY when left out
+    Currency string `json:"currency" binding:"omitempty,iso4217" example:"USD"`
+    // Content is markdown, raw html in it is not rendered
     Content string  `json:"content" binding:"required"`
+    // Genres are genre slugs, see /genres
+    Genres []string `json:"genres" binding:"omitempty,max=20"`
+    Tags []string `json:"tags" binding:"omitempty,max=50,dive,max=64"`
+    // Tracks is the track listing, numbered on each disc in the order of their number and then the order given
+    Tracks []CreateTrackDto `json:"tracks" binding:"omitempty,max=500,dive"`
 }
\ No newline at end of file
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type CreateApiKeyDto struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=reader editor admin"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type CreateGenreDto struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
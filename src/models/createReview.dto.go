@@ -0,0 +1,7 @@
+package models
+
+type CreateReviewDto struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=200"`
+	Body   string `json:"body" binding:"max=10000"`
+}
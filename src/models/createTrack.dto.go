@@ -0,0 +1,10 @@
+package models
+
+type CreateTrackDto struct {
+	Title           string `json:"title" binding:"required,max=255"`
+	DurationSeconds uint   `json:"duration_seconds" binding:"required"`
+	// Number is the position on the disc, the track is appended when it is left out or past the last track
+	Number uint `json:"number"`
+	// DiscNumber defaults to 1
+	DiscNumber uint `json:"disc_number"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type CreateWebhookDto struct {
+	Url        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=album.created album.updated album.deleted album.read *"`
+	// Secret signs the payloads, one is generated when it is left out
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRatesDto is a whole rate table, a json EXCHANGE_RATES_FILE has the same format
+type ExchangeRatesDto struct {
+	// Base is the currency the rates are quoted against, it gets rate 1
+	Base string `json:"base" binding:"required,iso4217" example:"USD"`
+	// AsOf is when the rates were quoted, the time of the upload when left out
+	AsOf *time.Time `json:"as_of"`
+	// Rates maps a currency to how many units of it one unit of Base buys
+	Rates map[string]decimal.Decimal `json:"rates" binding:"required,min=1" swaggertype:"object,string" example:"EUR:0.9215"`
+}
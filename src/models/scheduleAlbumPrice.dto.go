@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ScheduleAlbumPriceDto struct {
+	// Price is a decimal string, e.g. "49.99", with at most the decimal places of the currency
+	Price *decimal.Decimal `json:"price" binding:"required" swaggertype:"string" example:"49.99"`
+	// Currency is an ISO 4217 code, the current currency of the album when left out
+	Currency string `json:"currency" binding:"omitempty,iso4217" example:"USD"`
+	// EffectiveFrom is when the price takes effect, a time in the past takes effect right away
+	EffectiveFrom time.Time `json:"effective_from" binding:"required" example:"2030-01-01T00:00:00Z"`
+}
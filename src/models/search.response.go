@@ -0,0 +1,31 @@
+package models
+
+// SearchResponse is a page of search results
+type SearchResponse struct {
+	// Total counts the results of every page, at most the search window of each backend
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"page_size"`
+	Results  []SearchResultResponse `json:"results"`
+}
+
+type SearchResultResponse struct {
+	AlbumId  uint   `json:"album_id"`
+	Title    string `json:"title"`
+	ArtistId uint   `json:"artist_id"`
+	Artist   string `json:"artist"`
+	// Score is the relevance of the album, higher is better
+	Score float64 `json:"score" example:"0.42"`
+	// Highlights are html escaped snippets of the matching fields with the matches in <mark>, keyed by title, artist and content
+	Highlights map[string]string `json:"highlights" swaggertype:"object,string"`
+}
+
+// SuggestionResponse completes a search box with an album title or an artist name
+type SuggestionResponse struct {
+	// Type is album or artist, Id is the id of the album or of the artist
+	Type string `json:"type" example:"album"`
+	Id   uint   `json:"id"`
+	Text string `json:"text" example:"A Love Supreme"`
+	// Highlight is Text html escaped with what was typed in <mark>
+	Highlight string `json:"highlight" example:"A <mark>Love</mark> Supreme"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatsResponse are figures about the whole catalogue, Artists or PriceBuckets is set depending on group_by
+type StatsResponse struct {
+	Albums int64 `json:"albums"`
+	// ArtistCount counts the artists having albums
+	ArtistCount int64 `json:"artist_count"`
+	// Prices are figures per currency, prices of different currencies are not comparable
+	Prices  []CurrencyPriceStatsResponse `json:"prices"`
+	Reads   ReadStatsResponse            `json:"reads"`
+	Content ContentStatsResponse         `json:"content"`
+	GroupBy string                       `json:"group_by" example:"artist"`
+	// Artists count the albums of the artists with the most albums
+	Artists []FacetCountResponse `json:"artists,omitempty"`
+	// PriceBuckets count the albums of every currency by price, in the order of Prices
+	PriceBuckets []CurrencyPriceBucketsResponse `json:"price_buckets,omitempty"`
+	// ComputedAt is when the figures were computed, they are refreshed periodically; reads are always current
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+type CurrencyPriceStatsResponse struct {
+	Currency string          `json:"currency" example:"USD"`
+	Count    int64           `json:"count"`
+	Min      decimal.Decimal `json:"min" swaggertype:"string" example:"9.99"`
+	Max      decimal.Decimal `json:"max" swaggertype:"string" example:"120.00"`
+	Avg      decimal.Decimal `json:"avg" swaggertype:"string" example:"34.50"`
+	// Percentiles are album prices keyed by percentile, e.g. p50 is the median price
+	Percentiles map[string]decimal.Decimal `json:"percentiles" swaggertype:"object,string"`
+}
+
+type CurrencyPriceBucketsResponse struct {
+	Currency string                `json:"currency" example:"USD"`
+	Buckets  []PriceBucketResponse `json:"buckets"`
+}
+
+// ReadStatsResponse counts the albums the caller has read and has not read
+type ReadStatsResponse struct {
+	Read   int64 `json:"read"`
+	Unread int64 `json:"unread"`
+	// Ratio is the share of albums read, from 0 to 1
+	Ratio float64 `json:"ratio" example:"0.25"`
+}
+
+type ContentStatsResponse struct {
+	// Albums counts the albums having content
+	Albums     int64 `json:"albums"`
+	TotalBytes int64 `json:"total_bytes"`
+	AvgBytes   int64 `json:"avg_bytes"`
+	MaxBytes   int64 `json:"max_bytes"`
+	// Lengths count the albums by content length, albums without content are in the first bucket
+	Lengths []ContentLengthBucketResponse `json:"lengths"`
+}
+
+// ContentLengthBucketResponse counts the contents from From bytes (inclusive) to To (exclusive), the last bucket has no To
+type ContentLengthBucketResponse struct {
+	From  int64  `json:"from"`
+	To    *int64 `json:"to,omitempty"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// UpdateAlbumDto replaces the details of an album, its content and tracks have their own endpoints
+type UpdateAlbumDto struct {
+	Title string `json:"title" binding:"required"`
+	// Artist is the artist name, it is used when ArtistId is not given and creates the artist when it is unknown
+	Artist   string `json:"artist" binding:"required_without=ArtistId"`
+	ArtistId uint   `json:"artist_id"`
+	// Price is a decimal string, e.g. "56.99", with at most the decimal places of the currency
+	Price *decimal.Decimal `json:"price" binding:"required" swaggertype:"string" example:"56.99"`
+	// Currency is an ISO 4217 code, DEFAULT_CURRENCY when left out
+	Currency string `json:"currency" binding:"omitempty,iso4217" example:"USD"`
+	// Genres are genre slugs, see /genres, an empty list removes every genre
+	Genres []string `json:"genres" binding:"omitempty,max=20"`
+	Tags   []string `json:"tags" binding:"omitempty,max=50,dive,max=64"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "acy.com/api/src/entities"
+
+type WebhookResponse struct {
+	entities.WebhookSubscription
+	// Secret is the signing secret, it is only returned once when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
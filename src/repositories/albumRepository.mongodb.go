@@ -1,34 +1,88 @@
 package repositories
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
 
 	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type IAlbumMongoDBRepository interface {
 	FindAll() []entities.AlbumMongoDB
 	FindById(albumId uint) entities.AlbumMongoDB
+	FindByAlbumIds(albumIds []uint) []entities.AlbumMongoDB
 	Create(newAlbum *entities.AlbumMongoDB) string
 	Delete(albumId uint) bool
+	BulkWrite(writes []AlbumMongoDBWrite, ordered bool) (AlbumMongoDBBulkResult, error)
+	Release(result AlbumMongoDBBulkResult) error
+	Revert(result AlbumMongoDBBulkResult) error
+	WithActor(actor string) IAlbumMongoDBRepository
+	ReadContent(album entities.AlbumMongoDB) (io.ReadSeekCloser, error)
+	OpenContent(albumId uint, html bool) (io.ReadSeekCloser, entities.AlbumMongoDB, error)
+	WriteContent(album *entities.AlbumMongoDB, reader io.Reader) error
 }
 
+const (
+	AlbumMongoDBWriteCreate = "create"
+	AlbumMongoDBWriteUpdate = "update"
+	AlbumMongoDBWriteDelete = "delete"
+)
+
+// AlbumMongoDBWrite is a single content write of a bulk operation
+type AlbumMongoDBWrite struct {
+	Op    string
+	Album entities.AlbumMongoDB
+}
+
+// AlbumMongoDBBulkResult is what a bulk write did. The documents its updates and deletes replaced are kept with
+// their content files until the result is released, once the writes are there to stay, or reverted.
+type AlbumMongoDBBulkResult struct {
+	// Failed holds the error of every write that was not applied, keyed by its index in the writes
+	Failed map[int]error
+	// writes are the writes as they were sent, with the content files made for them
+	writes []AlbumMongoDBWrite
+	// replaced are the documents as they were before the writes, keyed by album id, an album without a document is missing
+	replaced map[uint]entities.AlbumMongoDB
+	// unknown is set when the bulk write failed without telling which writes were applied
+	unknown bool
+}
+
+// searchFields leaves out what documents keep for search only when they are found
+var searchFields = bson.M{"contentText": 0, "terms": 0}
+
+var (
+	ErrBulkWriteSkipped     = errors.New("skipped after an earlier write failed")
+	ErrAlbumContentNotFound = errors.New("album content not found")
+)
+
 type albumMongoDBRepository struct {
 	dbContext *mongo.Database
+	// files holds the content that is too large for the documents
+	files contentFiles
+	// actor is written to createdBy and updatedBy
+	actor string
 }
 
 // AlbumMongoDBRepository constructor
-func AlbumMongoDBRepository(db *mongo.Database) *albumMongoDBRepository {
-	albumMongoDBRepository := albumMongoDBRepository{dbContext: db}
+func AlbumMongoDBRepository(db *mongo.Database, storage libs.ContentStorage) *albumMongoDBRepository {
+	albumMongoDBRepository := albumMongoDBRepository{dbContext: db, files: contentFiles{dbContext: db, storage: storage}}
 	return &albumMongoDBRepository
 }
 
+// FindAll returns the documents without the content that is kept in GridFS, ReadContent reads it, and without
+// their search fields
 func (albumRepo *albumMongoDBRepository) FindAll() []entities.AlbumMongoDB {
 	var results []entities.AlbumMongoDB
-	cursor, err := albumRepo.dbContext.Collection("albums").Find(context.TODO(), bson.D{})
+	cursor, err := albumRepo.dbContext.Collection("albums").Find(context.TODO(), bson.D{}, options.Find().SetProjection(searchFields))
 	if err != nil {
 		panic(err)
 	}
@@ -49,18 +103,44 @@ func (albumRepo *albumMongoDBRepository) FindAll() []entities.AlbumMongoDB {
 
 func (albumRepo *albumMongoDBRepository) FindById(albumId uint) entities.AlbumMongoDB {
 	var album entities.AlbumMongoDB
-	if err := albumRepo.dbContext.Collection("albums").FindOne(context.TODO(), bson.M{"albumId": albumId}).Decode(&album); err != nil {
+	err := albumRepo.dbContext.Collection("albums").FindOne(context.TODO(), bson.M{"albumId": albumId}, options.FindOne().SetProjection(searchFields)).Decode(&album)
+	// an album without content is not exceptional, it is returned as an empty document
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
 		panic(err)
 	}
 	return album
 }
 
+func (albumRepo *albumMongoDBRepository) FindByAlbumIds(albumIds []uint) []entities.AlbumMongoDB {
+	results := []entities.AlbumMongoDB{}
+	if len(albumIds) == 0 {
+		return results
+	}
+
+	cursor, err := albumRepo.dbContext.Collection("albums").Find(context.TODO(), bson.M{"albumId": bson.M{"$in": albumIds}}, options.Find().SetProjection(searchFields))
+	if err != nil {
+		panic(err)
+	}
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	return results
+}
+
 func (albumRepo *albumMongoDBRepository) Create(newAlbum *entities.AlbumMongoDB) string {
-	result, err := albumRepo.dbContext.Collection("albums").InsertOne(context.TODO(), *newAlbum)
+	albumRepo.stamp(newAlbum)
+	render(newAlbum)
+	document := *newAlbum
+	if err := albumRepo.offload(&document); err != nil {
+		panic(err)
+	}
+	result, err := albumRepo.dbContext.Collection("albums").InsertOne(context.TODO(), document)
 
 	if err != nil {
+		albumRepo.files.delete(document.ContentFileId, document.ContentHtmlFileId)
 		panic(err)
 	}
+	newAlbum.ContentFileId, newAlbum.ContentHtmlFileId, newAlbum.ContentSize = document.ContentFileId, document.ContentHtmlFileId, document.ContentSize
 
 	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
 		return oid.Hex()
@@ -70,10 +150,384 @@ func (albumRepo *albumMongoDBRepository) Create(newAlbum *entities.AlbumMongoDB)
 }
 
 func (albumRepo *albumMongoDBRepository) Delete(albumId uint) bool {
-	result, err := albumRepo.dbContext.Collection("albums").DeleteOne(context.TODO(), bson.M{"albumId": albumId})
+	var deleted entities.AlbumMongoDB
+	err := albumRepo.dbContext.Collection("albums").FindOneAndDelete(context.TODO(), bson.M{"albumId": albumId}).Decode(&deleted)
 
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false
+	}
 	if err != nil {
 		panic(err)
 	}
-	return result.DeletedCount > 0
+	albumRepo.files.delete(deleted.ContentFileId, deleted.ContentHtmlFileId)
+	return true
+}
+
+// BulkWrite sends all writes to mongodb in a single round trip. The result is returned even with an error, an error
+// leaves what was applied unknown and the result can still be reverted.
+func (albumRepo *albumMongoDBRepository) BulkWrite(writes []AlbumMongoDBWrite, ordered bool) (AlbumMongoDBBulkResult, error) {
+	result := AlbumMongoDBBulkResult{Failed: map[int]error{}}
+	if len(writes) == 0 {
+		return result, nil
+	}
+
+	replaced, err := albumRepo.documents(writes)
+	if err != nil {
+		return result, err
+	}
+	removeUploaded := func(writes []AlbumMongoDBWrite) {
+		for _, write := range writes {
+			albumRepo.files.delete(write.Album.ContentFileId, write.Album.ContentHtmlFileId)
+		}
+	}
+
+	var models []mongo.WriteModel
+	sent := []AlbumMongoDBWrite{}
+	for _, write := range writes {
+		filter := bson.M{"albumId": write.Album.AlbumId}
+		switch write.Op {
+		case AlbumMongoDBWriteCreate:
+			albumRepo.stamp(&write.Album)
+			render(&write.Album)
+			if err := albumRepo.offload(&write.Album); err != nil {
+				removeUploaded(sent)
+				return result, err
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(write.Album))
+		case AlbumMongoDBWriteUpdate:
+			render(&write.Album)
+			if err := albumRepo.offload(&write.Album); err != nil {
+				removeUploaded(sent)
+				return result, err
+			}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(albumRepo.contentUpdate(write.Album)).SetUpsert(true))
+		case AlbumMongoDBWriteDelete:
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(filter))
+		}
+		sent = append(sent, write)
+	}
+	result.writes, result.replaced = sent, replaced
+
+	_, err = albumRepo.dbContext.Collection("albums").BulkWrite(context.TODO(), models, options.BulkWrite().SetOrdered(ordered))
+
+	var bulkErr mongo.BulkWriteException
+	if err != nil && (!errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0) {
+		// what was applied is unknown, files are left behind rather than taken from documents that use them
+		result.unknown = true
+		return result, err
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		result.Failed[writeErr.Index] = writeErr
+	}
+	// an ordered bulk write stops at the first failure
+	if ordered && len(bulkErr.WriteErrors) > 0 {
+		for i := bulkErr.WriteErrors[0].Index + 1; i < len(writes); i++ {
+			result.Failed[i] = ErrBulkWriteSkipped
+		}
+	}
+	for i := range result.Failed {
+		removeUploaded(sent[i : i+1])
+	}
+	return result, nil
+}
+
+// Release removes the content files of the documents that the applied writes of result replaced, the writes can
+// not be reverted any more
+func (albumRepo *albumMongoDBRepository) Release(result AlbumMongoDBBulkResult) error {
+	if result.unknown {
+		return errors.New("the writes of a failed bulk write can not be released, they have to be reverted")
+	}
+	var err error
+	remove := func(ids ...*primitive.ObjectID) {
+		if deleteErr := albumRepo.files.delete(ids...); deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}
+	// an album written twice keeps the files of its last write only
+	last := map[uint]int{}
+	for i, write := range result.writes {
+		if _, failed := result.Failed[i]; !failed {
+			last[write.Album.AlbumId] = i
+		}
+	}
+	for i, write := range result.writes {
+		if _, failed := result.Failed[i]; failed {
+			continue
+		}
+		if last[write.Album.AlbumId] != i || write.Op == AlbumMongoDBWriteDelete {
+			remove(write.Album.ContentFileId, write.Album.ContentHtmlFileId)
+		}
+	}
+	for albumId := range last {
+		if previous, ok := result.replaced[albumId]; ok {
+			remove(previous.ContentFileId, previous.ContentHtmlFileId)
+		}
+	}
+	return err
+}
+
+// Revert puts the documents that result replaced back as they were, with their content files, and removes the
+// documents and the files that its writes made. Writes that were not applied are left alone unless it is unknown
+// which were.
+func (albumRepo *albumMongoDBRepository) Revert(result AlbumMongoDBBulkResult) error {
+	var models []mongo.WriteModel
+	reverted := map[uint]bool{}
+	for i, write := range result.writes {
+		if _, failed := result.Failed[i]; failed && !result.unknown {
+			continue
+		}
+		if reverted[write.Album.AlbumId] {
+			continue
+		}
+		reverted[write.Album.AlbumId] = true
+		filter := bson.M{"albumId": write.Album.AlbumId}
+		if previous, ok := result.replaced[write.Album.AlbumId]; ok {
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(previous).SetUpsert(true))
+		} else {
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(filter))
+		}
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	if _, err := albumRepo.dbContext.Collection("albums").BulkWrite(context.TODO(), models, options.BulkWrite().SetOrdered(false)); err != nil {
+		// the files may still be used by documents that could not be put back
+		return err
+	}
+
+	var err error
+	for i, write := range result.writes {
+		if _, failed := result.Failed[i]; failed && !result.unknown {
+			continue
+		}
+		if deleteErr := albumRepo.files.delete(write.Album.ContentFileId, write.Album.ContentHtmlFileId); deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}
+	return err
+}
+
+// OpenContent opens the markdown, or the html when html is set, of an album for reading from any offset.
+// The document is returned without its content.
+func (albumRepo *albumMongoDBRepository) OpenContent(albumId uint, html bool) (io.ReadSeekCloser, entities.AlbumMongoDB, error) {
+	var album entities.AlbumMongoDB
+	err := albumRepo.dbContext.Collection("albums").FindOne(context.TODO(), bson.M{"albumId": albumId}, options.FindOne().SetProjection(searchFields)).Decode(&album)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, album, ErrAlbumContentNotFound
+	}
+	if err != nil {
+		return nil, album, err
+	}
+
+	if html && album.ContentHtml == "" && album.ContentHtmlFileId == nil {
+		// documents written before content was rendered are rendered now
+		if err := albumRepo.files.load(&album); err != nil {
+			return nil, album, err
+		}
+		album.ContentHtml, _ = libs.RenderMarkdown(album.Content)
+	}
+	content, fileId := album.Content, album.ContentFileId
+	if html {
+		content, fileId = album.ContentHtml, album.ContentHtmlFileId
+	}
+	album.Content, album.ContentHtml = "", ""
+
+	if fileId != nil {
+		file, err := albumRepo.files.open(*fileId)
+		return file, album, err
+	}
+	return libs.NopReadSeekCloser(strings.NewReader(content)), album, nil
+}
+
+// ReadContent opens the markdown of a document as it was found for reading from any offset, from GridFS when it is
+// kept there
+func (albumRepo *albumMongoDBRepository) ReadContent(album entities.AlbumMongoDB) (io.ReadSeekCloser, error) {
+	if album.ContentFileId != nil {
+		return albumRepo.files.open(*album.ContentFileId)
+	}
+	return libs.NopReadSeekCloser(strings.NewReader(album.Content)), nil
+}
+
+// WriteContent replaces the content of an album, or creates it, with what reader has. Content larger than the
+// inline limit is streamed to GridFS as it is read and its html is rendered from there, album is left without it
+// like the documents that are found. album holds the album id and name and is filled with what was written.
+func (albumRepo *albumMongoDBRepository) WriteContent(album *entities.AlbumMongoDB, reader io.Reader) error {
+	replaced, err := albumRepo.documents([]AlbumMongoDBWrite{{Op: AlbumMongoDBWriteUpdate, Album: *album}})
+	if err != nil {
+		return err
+	}
+
+	inlineMaxBytes := albumRepo.files.storage.InlineMaxBytes
+	head, err := io.ReadAll(io.LimitReader(reader, int64(inlineMaxBytes)+1))
+	if err != nil {
+		return err
+	}
+	document := *album
+	if len(head) > inlineMaxBytes {
+		counted := &countingReader{reader: io.MultiReader(bytes.NewReader(head), reader)}
+		markdownId, err := albumRepo.files.upload(album.AlbumId, contentFileOwnerAlbum, contentFileMarkdown, counted)
+		if err != nil {
+			return err
+		}
+		htmlId, text, err := albumRepo.files.render(album.AlbumId, contentFileOwnerAlbum, markdownId)
+		if err != nil {
+			albumRepo.files.delete(&markdownId)
+			return err
+		}
+		document.Content, document.ContentHtml = "", ""
+		document.ContentFileId, document.ContentHtmlFileId, document.ContentSize = &markdownId, &htmlId, counted.read
+		index(&document, text, inlineMaxBytes)
+	} else {
+		document.Content = string(head)
+		render(&document)
+		if err := albumRepo.offload(&document); err != nil {
+			return err
+		}
+	}
+	album.Content, album.ContentHtml = document.Content, document.ContentHtml
+	album.ContentText, album.Terms = "", nil
+
+	after := options.After
+	err = albumRepo.dbContext.Collection("albums").FindOneAndUpdate(context.TODO(), bson.M{"albumId": album.AlbumId}, albumRepo.contentUpdate(document),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(after)).Decode(&document)
+	if err != nil {
+		albumRepo.files.delete(document.ContentFileId, document.ContentHtmlFileId)
+		return err
+	}
+	if previous, ok := replaced[album.AlbumId]; ok {
+		albumRepo.files.delete(previous.ContentFileId, previous.ContentHtmlFileId)
+	}
+
+	album.ID, album.ContentFileId, album.ContentHtmlFileId, album.ContentSize = document.ID, document.ContentFileId, document.ContentHtmlFileId, document.ContentSize
+	album.CreatedAt, album.UpdatedAt, album.CreatedBy, album.UpdatedBy = document.CreatedAt, document.UpdatedAt, document.CreatedBy, document.UpdatedBy
+	return nil
+}
+
+// WithActor returns a repository that records actor as the author of its writes
+func (albumRepo *albumMongoDBRepository) WithActor(actor string) IAlbumMongoDBRepository {
+	return &albumMongoDBRepository{dbContext: albumRepo.dbContext, files: albumRepo.files, actor: actor}
+}
+
+// stamp fills the timestamps and authors of a new document, values that are set already are kept
+func (albumRepo *albumMongoDBRepository) stamp(album *entities.AlbumMongoDB) {
+	now := time.Now()
+	if album.CreatedAt.IsZero() {
+		album.CreatedAt = now
+	}
+	if album.UpdatedAt.IsZero() {
+		album.UpdatedAt = now
+	}
+	if album.CreatedBy == "" {
+		album.CreatedBy = albumRepo.actor
+	}
+	if album.UpdatedBy == "" {
+		album.UpdatedBy = albumRepo.actor
+	}
+}
+
+// contentUpdate writes the content of album over the document of its album, or inserts it
+func (albumRepo *albumMongoDBRepository) contentUpdate(album entities.AlbumMongoDB) bson.M {
+	now := time.Now()
+	set := bson.M{"name": album.Name, "content": album.Content, "contentHtml": album.ContentHtml, "contentSize": album.ContentSize, "updatedAt": now, "updatedBy": albumRepo.actor}
+	unset := bson.M{}
+	if album.ContentText != "" {
+		set["contentText"] = album.ContentText
+	} else {
+		unset["contentText"] = ""
+	}
+	if len(album.Terms) > 0 {
+		set["terms"] = album.Terms
+	} else {
+		unset["terms"] = ""
+	}
+	if album.ContentFileId != nil {
+		set["contentFileId"] = *album.ContentFileId
+	} else {
+		unset["contentFileId"] = ""
+	}
+	if album.ContentHtmlFileId != nil {
+		set["contentHtmlFileId"] = *album.ContentHtmlFileId
+	} else {
+		unset["contentHtmlFileId"] = ""
+	}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"createdAt": now, "createdBy": albumRepo.actor},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// documents returns the documents that the updates and deletes of writes replace as they are stored, without
+// loading their content files, keyed by album id
+func (albumRepo *albumMongoDBRepository) documents(writes []AlbumMongoDBWrite) (map[uint]entities.AlbumMongoDB, error) {
+	lookup := map[uint]entities.AlbumMongoDB{}
+	albumIds := []uint{}
+	for _, write := range writes {
+		if write.Op != AlbumMongoDBWriteCreate {
+			albumIds = append(albumIds, write.Album.AlbumId)
+		}
+	}
+	if len(albumIds) == 0 {
+		return lookup, nil
+	}
+
+	var documents []entities.AlbumMongoDB
+	cursor, err := albumRepo.dbContext.Collection("albums").Find(context.TODO(), bson.M{"albumId": bson.M{"$in": albumIds}})
+	if err != nil {
+		return lookup, err
+	}
+	if err := cursor.All(context.TODO(), &documents); err != nil {
+		return lookup, err
+	}
+	for _, document := range documents {
+		lookup[document.AlbumId] = document
+	}
+	return lookup, nil
+}
+
+// offload moves the markdown and the html of a rendered document that are too large for it to content files and
+// indexes the document for search
+func (albumRepo *albumMongoDBRepository) offload(document *entities.AlbumMongoDB) error {
+	text := libs.ContentText(document.ContentHtml)
+	if err := albumRepo.files.offload(document, contentFileOwnerAlbum); err != nil {
+		return err
+	}
+	index(document, text, albumRepo.files.storage.InlineMaxBytes)
+	return nil
+}
+
+// index fills what search looks through besides the text index of names and inline content: the words that
+// prefixes are looked up in and the text of content kept in GridFS. Text past maxBytes is not searched.
+func index(document *entities.AlbumMongoDB, text string, maxBytes int) {
+	capped := &cappedText{max: maxBytes}
+	io.WriteString(capped, text)
+	text = capped.String()
+	document.Terms = libs.SearchTerms(document.Name, text)
+	document.ContentText = ""
+	if document.ContentFileId != nil {
+		document.ContentText = text
+	}
+}
+
+// render stores the sanitized html of the markdown content next to it, rendering into memory can not fail.
+// Content files of the content it replaces no longer apply, offload makes new ones when they are needed.
+func render(album *entities.AlbumMongoDB) {
+	album.ContentHtml, _ = libs.RenderMarkdown(album.Content)
+	album.ContentFileId, album.ContentHtmlFileId = nil, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (counting *countingReader) Read(p []byte) (int, error) {
+	n, err := counting.reader.Read(p)
+	counting.read += int64(n)
+	return n, err
 }
@@ -2,25 +2,145 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"acy.com/api/src/entities"
 	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 type IAlbumRepository interface {
-	FindAll(page, pageSize int) ([]entities.Album, error)
+	FindAll(filter AlbumFilter, page, pageSize int) ([]entities.Album, error)
 	FindById(id uint) (entities.Album, error)
+	Facets(filter AlbumFilter) (AlbumFacets, error)
 	Create(newAlbum *entities.Album) (entities.Album, error)
 	Update(id uint, column string, value interface{})
+	Save(album *entities.Album) (entities.Album, error)
+	// Touch marks the album as updated now by the actor, e.g. when its content was written
+	Touch(id uint) (entities.Album, error)
 	Delete(id uint) error
+	Transaction(fn func(txRepo IAlbumRepository) error) error
+	WithActor(actor string) IAlbumRepository
+	// Audits returns an audit repository writing through the same connection, inside Transaction its entries
+	// commit or roll back with the albums
+	Audits() IAuditRepository
+	// Tracks returns a track repository writing through the same connection, like Audits
+	Tracks() ITrackRepository
 }
 
+// AlbumFilter narrows FindAll, zero fields match everything
+type AlbumFilter struct {
+	// Ids only returns these albums, an empty list returns every album
+	Ids []uint
+	// UpdatedSince only returns albums changed after it, ordered by updated_at so a client can sync incrementally
+	UpdatedSince *time.Time
+	// After continues a listing ordered by updated_at after the last album of the previous page, in place of an offset
+	After *AlbumCursor
+	ArtistId     uint
+	// Genres are genre slugs and Tags are tags, an album has to carry all of them
+	Genres []string
+	Tags   []string
+	// Currency only returns albums priced in it. MinPrice is inclusive and MaxPrice exclusive, like the price
+	// buckets of the facets, they are amounts of Currency and need it: prices of different currencies are not
+	// comparable.
+	Currency string
+	MinPrice *decimal.Decimal
+	MaxPrice *decimal.Decimal
+}
+
+// AlbumCursor is the position of an album in the listing ordered by updated_at and id
+type AlbumCursor struct {
+	UpdatedAt time.Time
+	Id        uint
+}
+
+var ErrInvalidAlbumCursor = errors.New("invalid cursor")
+
+var ErrPriceRangeWithoutCurrency = errors.New("a price range needs the currency of its prices")
+
+// NewAlbumCursor returns the cursor continuing a listing after album
+func NewAlbumCursor(album entities.Album) AlbumCursor {
+	return AlbumCursor{UpdatedAt: album.UpdatedAt, Id: album.Id}
+}
+
+// String encodes the cursor as an opaque token for clients
+func (cursor AlbumCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", cursor.UpdatedAt.UnixNano(), cursor.Id)))
+}
+
+// ParseAlbumCursor decodes a token made by AlbumCursor.String
+func ParseAlbumCursor(token string) (AlbumCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return AlbumCursor{}, ErrInvalidAlbumCursor
+	}
+	parts := strings.SplitN(string(decoded), ".", 2)
+	if len(parts) != 2 {
+		return AlbumCursor{}, ErrInvalidAlbumCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return AlbumCursor{}, ErrInvalidAlbumCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 0)
+	if err != nil {
+		return AlbumCursor{}, ErrInvalidAlbumCursor
+	}
+	return AlbumCursor{UpdatedAt: time.Unix(0, nanos).UTC(), Id: uint(id)}, nil
+}
+
+// AlbumFacets counts the albums matching a filter
+type AlbumFacets struct {
+	Total   int64
+	Genres  []FacetCount
+	Tags    []FacetCount
+	Artists []FacetCount
+	Prices  []CurrencyPriceBuckets
+}
+
+type FacetCount struct {
+	Value string
+	Name  string
+	Count int64
+}
+
+// PriceBucketCount counts the prices from From up to To, To is nil for the last bucket
+type PriceBucketCount struct {
+	From  decimal.Decimal
+	To    *decimal.Decimal
+	Count int64
+}
+
+// CurrencyPriceBuckets count the albums priced in a currency in every bucket of AlbumPriceBuckets
+type CurrencyPriceBuckets struct {
+	Currency string
+	Buckets  []PriceBucketCount
+}
+
+// AlbumPriceBuckets are the upper bounds of the price buckets of the facets and the stats, the last bucket is open
+// ended. Every currency is bucketed on its own.
+var AlbumPriceBuckets = []decimal.Decimal{decimal.NewFromInt(10), decimal.NewFromInt(20), decimal.NewFromInt(50), decimal.NewFromInt(100)}
+
+// FacetLimit bounds the genre, tag and artist facets to their most used values
+const FacetLimit = 50
+
+var ErrAlbumNotFound = errors.New("album not found")
+
 type AlbumRepository struct {
 	dbContext *gorm.DB
 	logger    *zap.Logger
+	// actor is written to created_by and updated_by
+	actor string
 }
 
 // AlbumRepository constructor
@@ -38,15 +158,23 @@ func NewAlbumRepository(conn *sql.DB) *AlbumRepository {
 
 // FindAll /* interface implementations */
 
-func (repo *AlbumRepository) FindAll(page, pageSize int) ([]entities.Album, error) {
+func (repo *AlbumRepository) FindAll(filter AlbumFilter, page, pageSize int) ([]entities.Album, error) {
 	var albums []entities.Album
 	var result *gorm.DB
 
+	query := repo.filtered(filter).Preload("Artist").Preload("Genres")
+	if filter.UpdatedSince != nil || filter.After != nil {
+		query = query.Order("updated_at").Order("id")
+	}
+
 	if pageSize <= 0 {
-		result = repo.dbContext.Debug().Find(&albums)
+		result = query.Find(&albums)
+	} else if filter.After != nil {
+		// the cursor replaces the offset, pages stay stable while albums are updated
+		result = query.Limit(pageSize).Find(&albums)
 	} else {
 		offset := (page - 1) * pageSize
-		result = repo.dbContext.Debug().Offset(offset).Limit(pageSize).Find(&albums)
+		result = query.Offset(offset).Limit(pageSize).Find(&albums)
 	}
 
 	return albums, result.Error
@@ -54,18 +182,186 @@ func (repo *AlbumRepository) FindAll(page, pageSize int) ([]entities.Album, erro
 
 func (repo *AlbumRepository) FindById(id uint) (entities.Album, error) {
 	album := entities.Album{}
-	result := repo.dbContext.Debug().Find(&album, "id", id)
+	result := repo.dbContext.Debug().Preload("Artist").Preload("Genres").Find(&album, "id", id)
 	return album, result.Error
 }
 
+// Facets counts the albums matching filter per genre, tag, artist and price bucket, Total counts every match
+func (repo *AlbumRepository) Facets(filter AlbumFilter) (AlbumFacets, error) {
+	facets := AlbumFacets{Genres: []FacetCount{}, Tags: []FacetCount{}, Artists: []FacetCount{}}
+	// every query gets its own subquery, a gorm statement cannot be reused once executed.
+	// A facet counts the albums matching every filter but its own, so the other values of a facet stay selectable.
+	matching := func(own func(filter *AlbumFilter)) *gorm.DB {
+		others := filter
+		own(&others)
+		return repo.filtered(others).Select("albums.id")
+	}
+
+	if err := repo.filtered(filter).Count(&facets.Total).Error; err != nil {
+		return facets, err
+	}
+
+	err := repo.dbContext.Debug().Table("album_genres").
+		Select("genres.slug AS value, genres.name AS name, count(*) AS count").
+		Joins("JOIN genres ON genres.id = album_genres.genre_id").
+		Where("album_genres.album_id IN (?)", matching(func(filter *AlbumFilter) { filter.Genres = nil })).
+		Group("genres.slug, genres.name").
+		Order("count DESC, value").
+		Limit(FacetLimit).
+		Scan(&facets.Genres).Error
+	if err != nil {
+		return facets, err
+	}
+
+	err = repo.dbContext.Debug().Table("albums, unnest(albums.tags) AS tag").
+		Select("tag AS value, count(*) AS count").
+		Where("albums.id IN (?)", matching(func(filter *AlbumFilter) { filter.Tags = nil })).
+		Group("tag").
+		Order("count DESC, value").
+		Limit(FacetLimit).
+		Scan(&facets.Tags).Error
+	if err != nil {
+		return facets, err
+	}
+
+	err = repo.dbContext.Debug().Table("albums").
+		Select("CAST(artists.id AS TEXT) AS value, artists.name AS name, count(*) AS count").
+		Joins("JOIN artists ON artists.id = albums.artist_id").
+		Where("albums.id IN (?)", matching(func(filter *AlbumFilter) { filter.ArtistId = 0 })).
+		Group("artists.id, artists.name").
+		Order("count DESC, artists.name").
+		Limit(FacetLimit).
+		Scan(&facets.Artists).Error
+	if err != nil {
+		return facets, err
+	}
+
+	// the price facet keeps the currency filter, the range is a range of its prices
+	prices := matching(func(filter *AlbumFilter) { filter.MinPrice, filter.MaxPrice = nil, nil })
+	facets.Prices, err = countPriceBuckets(repo.dbContext.Debug().Table("albums").Where("albums.id IN (?)", prices))
+	if err != nil {
+		return facets, err
+	}
+	return facets, nil
+}
+
+// countPriceBuckets counts the albums of query, a query on the albums table, in every bucket of AlbumPriceBuckets for
+// every currency, prices of different currencies are never in the same bucket. The currencies with the most albums
+// come first.
+func countPriceBuckets(query *gorm.DB) ([]CurrencyPriceBuckets, error) {
+	// width_bucket numbers the buckets from 0 (below the first bound) to len(AlbumPriceBuckets)
+	var buckets []struct {
+		Currency string
+		Bucket   int
+		Count    int64
+	}
+	err := query.
+		Select("currency, width_bucket(albums.price, ?::numeric[]) AS bucket, count(*) AS count", priceBucketBounds()).
+		Group("currency, bucket").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]map[int]int64{}
+	totals := map[string]int64{}
+	currencies := []string{}
+	for _, bucket := range buckets {
+		if counts[bucket.Currency] == nil {
+			counts[bucket.Currency] = map[int]int64{}
+			currencies = append(currencies, bucket.Currency)
+		}
+		counts[bucket.Currency][bucket.Bucket] = bucket.Count
+		totals[bucket.Currency] += bucket.Count
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		if totals[currencies[i]] != totals[currencies[j]] {
+			return totals[currencies[i]] > totals[currencies[j]]
+		}
+		return currencies[i] < currencies[j]
+	})
+	prices := []CurrencyPriceBuckets{}
+	for _, currency := range currencies {
+		prices = append(prices, CurrencyPriceBuckets{Currency: currency, Buckets: priceBuckets(counts[currency])})
+	}
+	return prices, nil
+}
+
+// priceBucketBounds are AlbumPriceBuckets for width_bucket
+func priceBucketBounds() pq.StringArray {
+	bounds := pq.StringArray{}
+	for _, bound := range AlbumPriceBuckets {
+		bounds = append(bounds, bound.String())
+	}
+	return bounds
+}
+
+// priceBuckets are the buckets of AlbumPriceBuckets with the counts of their width_bucket numbers
+func priceBuckets(counts map[int]int64) []PriceBucketCount {
+	prices := []PriceBucketCount{}
+	from := decimal.Zero
+	for i := 0; i <= len(AlbumPriceBuckets); i++ {
+		price := PriceBucketCount{From: from, Count: counts[i]}
+		if i < len(AlbumPriceBuckets) {
+			to := AlbumPriceBuckets[i]
+			price.To, from = &to, to
+		}
+		prices = append(prices, price)
+	}
+	return prices
+}
+
+// Create sets created_by and updated_by to the actor unless they are set already, e.g. when a deleted row is put back
 func (repo *AlbumRepository) Create(newAlbum *entities.Album) (entities.Album, error) {
-	result := repo.dbContext.Debug().Create(&newAlbum)
-	return *newAlbum, result.Error
+	if newAlbum.CreatedBy == "" {
+		newAlbum.CreatedBy = repo.actor
+	}
+	if newAlbum.UpdatedBy == "" {
+		newAlbum.UpdatedBy = repo.actor
+	}
+	newAlbum.Tags = NormalizeTags(newAlbum.Tags)
+	// the artist is referenced by id and tracks are numbered by the track repository, neither is written through the album
+	err := repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Omit("Artist", "Tracks", "Genres").Create(&newAlbum).Error; err != nil {
+			return err
+		}
+		return insertAlbumGenres(tx, newAlbum.Id, newAlbum.Genres)
+	})
+	return *newAlbum, err
 }
 
 func (repo *AlbumRepository) Update(id uint, column string, value interface{}) {
 	album := entities.Album{}
-	repo.dbContext.Debug().Model(&album).Where("id = ?", id).Update(column, value)
+	repo.dbContext.Debug().Model(&album).Where("id = ?", id).Updates(map[string]interface{}{column: value, "updated_by": repo.actor})
+}
+
+func (repo *AlbumRepository) Save(album *entities.Album) (entities.Album, error) {
+	album.UpdatedBy = repo.actor
+	album.Tags = NormalizeTags(album.Tags)
+	err := repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		result := tx.Debug().Model(album).Select("title", "artist_id", "price", "currency", "tags", "updated_at", "updated_by").Updates(album)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrAlbumNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Debug().Exec(`DELETE FROM album_genres WHERE album_id = ?`, album.Id).Error; err != nil {
+			return err
+		}
+		return insertAlbumGenres(tx, album.Id, album.Genres)
+	})
+	return *album, err
+}
+
+func (repo *AlbumRepository) Touch(id uint) (entities.Album, error) {
+	result := repo.dbContext.Debug().Model(&entities.Album{}).Where("id = ?", id).Updates(map[string]interface{}{"updated_at": time.Now(), "updated_by": repo.actor})
+	if result.Error != nil {
+		return entities.Album{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.Album{}, ErrAlbumNotFound
+	}
+	return repo.FindById(id)
 }
 
 func (repo *AlbumRepository) Delete(id uint) error {
@@ -77,3 +373,85 @@ func (repo *AlbumRepository) Delete(id uint) error {
 	result = repo.dbContext.Debug().Delete(&targetAlbum, id)
 	return result.Error
 }
+
+// Transaction runs fn against a repository bound to a single database transaction.
+// Calling Transaction again on txRepo opens a savepoint instead of a new transaction.
+func (repo *AlbumRepository) Transaction(fn func(txRepo IAlbumRepository) error) error {
+	return repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		return fn(&AlbumRepository{dbContext: tx, logger: repo.logger, actor: repo.actor})
+	})
+}
+
+// WithActor returns a repository that records actor as the author of its writes
+func (repo *AlbumRepository) WithActor(actor string) IAlbumRepository {
+	return &AlbumRepository{dbContext: repo.dbContext, logger: repo.logger, actor: actor}
+}
+
+func (repo *AlbumRepository) Audits() IAuditRepository {
+	return &AuditRepository{dbContext: repo.dbContext, logger: repo.logger}
+}
+
+func (repo *AlbumRepository) Tracks() ITrackRepository {
+	return &TrackRepository{dbContext: repo.dbContext, logger: repo.logger}
+}
+
+// filtered selects the albums matching filter
+func (repo *AlbumRepository) filtered(filter AlbumFilter) *gorm.DB {
+	query := repo.dbContext.Debug().Model(&entities.Album{})
+	if len(filter.Ids) > 0 {
+		query = query.Where("albums.id IN ?", filter.Ids)
+	}
+	if filter.ArtistId != 0 {
+		query = query.Where("artist_id = ?", filter.ArtistId)
+	}
+	for _, genre := range filter.Genres {
+		query = query.Where(`EXISTS (SELECT 1 FROM album_genres JOIN genres ON genres.id = album_genres.genre_id WHERE album_genres.album_id = albums.id AND genres.slug = ?)`, strings.ToLower(genre))
+	}
+	if tags := NormalizeTags(filter.Tags); len(tags) > 0 {
+		query = query.Where("tags @> ?", tags)
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", filter.Currency)
+	} else if filter.MinPrice != nil || filter.MaxPrice != nil {
+		query.AddError(ErrPriceRangeWithoutCurrency)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price < ?", *filter.MaxPrice)
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at > ?", *filter.UpdatedSince)
+	}
+	if filter.After != nil {
+		query = query.Where("(updated_at, id) > (?, ?)", filter.After.UpdatedAt, filter.After.Id)
+	}
+	return query
+}
+
+// NormalizeTags trims, lower cases and deduplicates tags, collapsing inner whitespace, and sorts them
+func NormalizeTags(tags []string) pq.StringArray {
+	normalized := pq.StringArray{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func insertAlbumGenres(tx *gorm.DB, albumId uint, genres []entities.Genre) error {
+	if len(genres) == 0 {
+		return nil
+	}
+	genreIds := make(pq.Int64Array, len(genres))
+	for i, genre := range genres {
+		genreIds[i] = int64(genre.Id)
+	}
+	return tx.Debug().Exec(`INSERT INTO album_genres (album_id, genre_id) SELECT ?, unnest(?::int[]) ON CONFLICT DO NOTHING`, albumId, genreIds).Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IApiKeyRepository interface {
+	FindAll() ([]entities.ApiKey, error)
+	FindByHash(keyHash string) (entities.ApiKey, error)
+	Create(newKey *entities.ApiKey) (entities.ApiKey, error)
+	Revoke(id uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+var ErrApiKeyNotFound = errors.New("api key not found")
+
+type ApiKeyRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// ApiKeyRepository constructor
+func NewApiKeyRepository(conn *sql.DB) *ApiKeyRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &ApiKeyRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *ApiKeyRepository) FindAll() ([]entities.ApiKey, error) {
+	var keys []entities.ApiKey
+	result := repo.dbContext.Debug().Order("id").Find(&keys)
+	return keys, result.Error
+}
+
+func (repo *ApiKeyRepository) FindByHash(keyHash string) (entities.ApiKey, error) {
+	key := entities.ApiKey{}
+	result := repo.dbContext.Debug().Find(&key, "key_hash = ?", keyHash)
+	return key, result.Error
+}
+
+func (repo *ApiKeyRepository) Create(newKey *entities.ApiKey) (entities.ApiKey, error) {
+	result := repo.dbContext.Debug().Create(newKey)
+	return *newKey, result.Error
+}
+
+func (repo *ApiKeyRepository) Revoke(id uint, at time.Time) error {
+	result := repo.dbContext.Debug().Model(&entities.ApiKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrApiKeyNotFound
+	}
+	return result.Error
+}
+
+func (repo *ApiKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	result := repo.dbContext.Model(&entities.ApiKey{}).Where("id = ?", id).Update("last_used_at", at)
+	return result.Error
+}
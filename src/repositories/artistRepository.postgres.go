@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IArtistRepository interface {
+	FindAll(name string, page, pageSize int) ([]entities.Artist, error)
+	FindById(id uint) (entities.Artist, error)
+	FindOrCreate(name string, actor string) (entities.Artist, error)
+	Create(newArtist *entities.Artist) (entities.Artist, error)
+	Save(artist *entities.Artist) (entities.Artist, error)
+	Delete(id uint) error
+}
+
+var (
+	ErrArtistNotFound = errors.New("artist not found")
+	ErrArtistExists   = errors.New("an artist with this name exists already")
+	ErrArtistInUse    = errors.New("artist still has albums")
+)
+
+// postgres error codes
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+type ArtistRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// ArtistRepository constructor
+func NewArtistRepository(conn *sql.DB) *ArtistRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &ArtistRepository{dbContext: gormDB, logger: logger}
+}
+
+// ArtistNameKey normalizes an artist name the way artists are deduplicated:
+// surrounding whitespace trimmed, inner whitespace collapsed and lower case
+func ArtistNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+/* interface implementations */
+
+// FindAll returns artists ordered by name, name filters on a case insensitive part of the name
+func (repo *ArtistRepository) FindAll(name string, page, pageSize int) ([]entities.Artist, error) {
+	artists := []entities.Artist{}
+	query := repo.dbContext.Debug().Order("name_key")
+	if name != "" {
+		query = query.Where("name_key LIKE ?", "%"+escapeLike(ArtistNameKey(name))+"%")
+	}
+	if pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	result := query.Find(&artists)
+	return artists, result.Error
+}
+
+func (repo *ArtistRepository) FindById(id uint) (entities.Artist, error) {
+	artist := entities.Artist{}
+	result := repo.dbContext.Debug().Find(&artist, "id = ?", id)
+	if result.Error == nil && artist.Id == 0 {
+		return artist, ErrArtistNotFound
+	}
+	return artist, result.Error
+}
+
+// FindOrCreate returns the artist matching name once normalized, creating it when there is none.
+// It returns ErrArtistNotFound when the matching artist is deleted or renamed before it is read.
+func (repo *ArtistRepository) FindOrCreate(name string, actor string) (entities.Artist, error) {
+	artist := entities.Artist{
+		Name:      strings.Join(strings.Fields(name), " "),
+		NameKey:   ArtistNameKey(name),
+		CreatedBy: actor,
+		UpdatedBy: actor,
+	}
+	result := repo.dbContext.Debug().Clauses(clause.OnConflict{DoNothing: true}).Create(&artist)
+	if result.Error != nil {
+		return artist, result.Error
+	}
+
+	existing := entities.Artist{}
+	result = repo.dbContext.Debug().Find(&existing, "name_key = ?", artist.NameKey)
+	if result.Error == nil && existing.Id == 0 {
+		return existing, ErrArtistNotFound
+	}
+	return existing, result.Error
+}
+
+func (repo *ArtistRepository) Create(newArtist *entities.Artist) (entities.Artist, error) {
+	newArtist.NameKey = ArtistNameKey(newArtist.Name)
+	result := repo.dbContext.Debug().Create(newArtist)
+	return *newArtist, translateArtistError(result.Error)
+}
+
+func (repo *ArtistRepository) Save(artist *entities.Artist) (entities.Artist, error) {
+	artist.NameKey = ArtistNameKey(artist.Name)
+	result := repo.dbContext.Debug().Model(artist).Select("name", "name_key", "updated_at", "updated_by").Updates(artist)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return *artist, ErrArtistNotFound
+	}
+	return *artist, translateArtistError(result.Error)
+}
+
+// Delete refuses to remove an artist that albums still reference
+func (repo *ArtistRepository) Delete(id uint) error {
+	result := repo.dbContext.Debug().Delete(&entities.Artist{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrArtistNotFound
+	}
+	return translateArtistError(result.Error)
+}
+
+func translateArtistError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		return ErrArtistExists
+	case pqForeignKeyViolation:
+		return ErrArtistInUse
+	default:
+		return err
+	}
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
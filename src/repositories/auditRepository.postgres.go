@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IAuditRepository interface {
+	Create(entry *entities.AuditEntry) error
+	Find(filter AuditFilter, page, pageSize int) ([]entities.AuditEntry, error)
+	Each(filter AuditFilter, fn func(entry entities.AuditEntry) error) error
+}
+
+// AuditFilter narrows audit queries, empty fields match everything
+type AuditFilter struct {
+	Entity   string
+	EntityId string
+	Action   string
+	Actor    string
+}
+
+type AuditRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// AuditRepository constructor
+func NewAuditRepository(conn *sql.DB) *AuditRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &AuditRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *AuditRepository) Create(entry *entities.AuditEntry) error {
+	result := repo.dbContext.Debug().Create(entry)
+	if result.Error != nil {
+		// a lost audit entry must at least leave a trace
+		repo.logger.Error("unable to write audit entry",
+			zap.String("entity", entry.Entity),
+			zap.String("entity_id", entry.EntityId),
+			zap.String("action", entry.Action),
+			zap.String("request_id", entry.RequestId),
+			zap.String("error", result.Error.Error()),
+		)
+	}
+	return result.Error
+}
+
+// Find returns the matching entries oldest first, a pageSize <= 0 returns all of them
+func (repo *AuditRepository) Find(filter AuditFilter, page, pageSize int) ([]entities.AuditEntry, error) {
+	entries := []entities.AuditEntry{}
+	query := repo.where(filter).Order("id")
+	if pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	result := query.Find(&entries)
+	return entries, result.Error
+}
+
+// Each streams the matching entries oldest first without loading them all in memory
+func (repo *AuditRepository) Each(filter AuditFilter, fn func(entry entities.AuditEntry) error) error {
+	rows, err := repo.where(filter).Model(&entities.AuditEntry{}).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry entities.AuditEntry
+		if err := repo.dbContext.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (repo *AuditRepository) where(filter AuditFilter) *gorm.DB {
+	query := repo.dbContext.Debug()
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityId != "" {
+		query = query.Where("entity_id = ?", filter.EntityId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	return query
+}
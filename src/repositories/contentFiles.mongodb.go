@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// content files are album content too large for a document, kept in GridFS with their owner in the metadata
+const (
+	contentFilesBucket = "album_content"
+
+	contentFileOwnerAlbum    = "album"
+	contentFileOwnerRevision = "revision"
+
+	contentFileMarkdown = "md"
+	contentFileHtml     = "html"
+)
+
+type contentFiles struct {
+	dbContext *mongo.Database
+	storage   libs.ContentStorage
+}
+
+// bucket is made for every operation, a bucket keeps buffers and deadlines that can not be shared between requests
+func (files contentFiles) bucket() *gridfs.Bucket {
+	// NewBucket only fails on invalid options
+	bucket, _ := gridfs.NewBucket(files.dbContext, options.GridFSBucket().SetName(contentFilesBucket))
+	return bucket
+}
+
+// upload stores what reader has as a content file, format is contentFileMarkdown or contentFileHtml
+func (files contentFiles) upload(albumId uint, owner, format string, reader io.Reader) (primitive.ObjectID, error) {
+	name := fmt.Sprintf("albums/%d/%s.%s", albumId, owner, format)
+	metadata := bson.M{"albumId": albumId, "owner": owner, "format": format}
+	return files.bucket().UploadFromStream(name, reader, options.GridFSUpload().SetMetadata(metadata))
+}
+
+func (files contentFiles) read(id primitive.ObjectID) (string, error) {
+	var content strings.Builder
+	_, err := files.bucket().DownloadToStream(id, &content)
+	return content.String(), err
+}
+
+// open returns a content file for reading from any offset, e.g. to serve ranges of it
+func (files contentFiles) open(id primitive.ObjectID) (io.ReadSeekCloser, error) {
+	bucket := files.bucket()
+	stream, err := bucket.OpenDownloadStream(id)
+	if err != nil {
+		return nil, err
+	}
+	// GridFS streams only read forward, a read after a seek opens the file again and skips to the offset
+	return libs.NewSeekableStream(stream, stream.GetFile().Length, func(offset int64) (io.ReadCloser, error) {
+		stream, err := bucket.OpenDownloadStream(id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stream.Skip(offset); err != nil {
+			stream.Close()
+			return nil, err
+		}
+		return stream, nil
+	}), nil
+}
+
+// render renders the markdown of a content file and stores the html as another content file, the plain text of
+// the html is returned up to the inline limit. The renderer needs all of the markdown at once, the html is
+// uploaded and its text taken while it is rendered.
+func (files contentFiles) render(albumId uint, owner string, markdownId primitive.ObjectID) (primitive.ObjectID, string, error) {
+	var source bytes.Buffer
+	if _, err := files.bucket().DownloadToStream(markdownId, &source); err != nil {
+		return primitive.NilObjectID, "", err
+	}
+	htmlReader, htmlWriter := io.Pipe()
+	textReader, textWriter := io.Pipe()
+	go func() {
+		err := libs.RenderMarkdownTo(io.MultiWriter(htmlWriter, textWriter), source.Bytes())
+		htmlWriter.CloseWithError(err)
+		textWriter.CloseWithError(err)
+	}()
+	text := &cappedText{max: files.storage.InlineMaxBytes}
+	extracted := make(chan error, 1)
+	go func() {
+		err := libs.WriteContentText(text, textReader)
+		// the renderer is stopped when the text can not be taken
+		textReader.CloseWithError(err)
+		extracted <- err
+	}()
+
+	id, err := files.upload(albumId, owner, contentFileHtml, htmlReader)
+	// the renderer is stopped when the upload fails
+	htmlReader.CloseWithError(err)
+	if textErr := <-extracted; err == nil && textErr != nil {
+		files.delete(&id)
+		err = textErr
+	}
+	return id, text.String(), err
+}
+
+// delete removes the files that are set, files that are gone already are not an error
+func (files contentFiles) delete(ids ...*primitive.ObjectID) error {
+	var err error
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		if deleteErr := files.bucket().Delete(*id); deleteErr != nil && !errors.Is(deleteErr, gridfs.ErrFileNotFound) && err == nil {
+			err = deleteErr
+		}
+	}
+	return err
+}
+
+// offload moves the markdown and the html of a document that are larger than the inline limit to content files,
+// the document is left with their ids. Content that fits stays in the document.
+func (files contentFiles) offload(document *entities.AlbumMongoDB, owner string) error {
+	inlineMaxBytes := files.storage.InlineMaxBytes
+	document.ContentSize = int64(len(document.Content))
+	if len(document.Content) > inlineMaxBytes && document.ContentFileId == nil {
+		id, err := files.upload(document.AlbumId, owner, contentFileMarkdown, strings.NewReader(document.Content))
+		if err != nil {
+			return err
+		}
+		document.ContentFileId = &id
+	}
+	if len(document.ContentHtml) > inlineMaxBytes && document.ContentHtmlFileId == nil {
+		id, err := files.upload(document.AlbumId, owner, contentFileHtml, strings.NewReader(document.ContentHtml))
+		if err != nil {
+			files.delete(document.ContentFileId)
+			return err
+		}
+		document.ContentHtmlFileId = &id
+	}
+	if document.ContentFileId != nil {
+		document.Content = ""
+	}
+	if document.ContentHtmlFileId != nil {
+		document.ContentHtml = ""
+	}
+	return nil
+}
+
+// load fills the markdown and the html of a document that are in content files
+func (files contentFiles) load(document *entities.AlbumMongoDB) error {
+	var err error
+	if document.ContentFileId != nil {
+		if document.Content, err = files.read(*document.ContentFileId); err != nil {
+			return err
+		}
+	}
+	if document.ContentHtmlFileId != nil {
+		if document.ContentHtml, err = files.read(*document.ContentHtmlFileId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cappedText keeps the text written to it up to max bytes and takes the rest without keeping it
+type cappedText struct {
+	text strings.Builder
+	max  int
+}
+
+func (capped *cappedText) Write(p []byte) (int, error) {
+	kept := p
+	if room := capped.max - capped.text.Len(); len(kept) > room {
+		kept = kept[:room]
+	}
+	capped.text.Write(kept)
+	return len(p), nil
+}
+
+// String is the text kept, a character cut in half at the end is dropped
+func (capped *cappedText) String() string {
+	return strings.ToValidUTF8(capped.text.String(), "")
+}
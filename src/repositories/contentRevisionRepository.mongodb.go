@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+type IContentRevisionMongoDBRepository interface {
+	FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error)
+	FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error)
+	OpenContent(albumId uint, revision int) (io.ReadSeekCloser, entities.AlbumContentRevisionMongoDB, error)
+	Latest(albumId uint) (entities.AlbumContentRevisionMongoDB, error)
+	Create(revision *entities.AlbumContentRevisionMongoDB, content io.Reader) (entities.AlbumContentRevisionMongoDB, error)
+}
+
+var ErrContentRevisionNotFound = errors.New("content revision not found")
+
+// how often Create takes the next number again when a concurrent write took it first
+const contentRevisionAttempts = 5
+
+type contentRevisionMongoDBRepository struct {
+	dbContext *mongo.Database
+	files     contentFiles
+	logger    *zap.Logger
+}
+
+// ContentRevisionMongoDBRepository constructor, it makes sure the revision numbers of an album are unique
+func ContentRevisionMongoDBRepository(db *mongo.Database, storage libs.ContentStorage) *contentRevisionMongoDBRepository {
+	repo := contentRevisionMongoDBRepository{dbContext: db, files: contentFiles{dbContext: db, storage: storage}, logger: libs.NewZapLogger()}
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "albumId", Value: 1}, {Key: "revision", Value: -1}}, Options: options.Index().SetUnique(true)}
+	if _, err := db.Collection("album_content_revisions").Indexes().CreateOne(context.TODO(), index); err != nil {
+		repo.logger.Error("mongodb index error",
+			zap.String("collection", "album_content_revisions"),
+			zap.String("error", err.Error()),
+		)
+	}
+	return &repo
+}
+
+/* interface implementations */
+
+// FindByAlbum returns the revisions of an album without their content, newest first
+func (repo *contentRevisionMongoDBRepository) FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error) {
+	revisions := []entities.AlbumContentRevisionMongoDB{}
+	findOptions := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}}).SetProjection(bson.M{"content": 0})
+	if pageSize > 0 {
+		findOptions.SetSkip(int64((page - 1) * pageSize)).SetLimit(int64(pageSize))
+	}
+
+	cursor, err := repo.dbContext.Collection("album_content_revisions").Find(context.TODO(), bson.M{"albumId": albumId}, findOptions)
+	if err != nil {
+		return revisions, err
+	}
+	err = cursor.All(context.TODO(), &revisions)
+	return revisions, err
+}
+
+func (repo *contentRevisionMongoDBRepository) FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error) {
+	var found entities.AlbumContentRevisionMongoDB
+	err := repo.dbContext.Collection("album_content_revisions").FindOne(context.TODO(), bson.M{"albumId": albumId, "revision": revision}).Decode(&found)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return found, ErrContentRevisionNotFound
+	}
+	if err == nil && found.ContentFileId != nil {
+		found.Content, err = repo.files.read(*found.ContentFileId)
+	}
+	return found, err
+}
+
+// OpenContent opens the content of a revision for reading from any offset, the revision is returned without it
+func (repo *contentRevisionMongoDBRepository) OpenContent(albumId uint, revision int) (io.ReadSeekCloser, entities.AlbumContentRevisionMongoDB, error) {
+	var found entities.AlbumContentRevisionMongoDB
+	err := repo.dbContext.Collection("album_content_revisions").FindOne(context.TODO(), bson.M{"albumId": albumId, "revision": revision}).Decode(&found)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, found, ErrContentRevisionNotFound
+	}
+	if err != nil {
+		return nil, found, err
+	}
+	if found.ContentFileId != nil {
+		file, err := repo.files.open(*found.ContentFileId)
+		return file, found, err
+	}
+	content := found.Content
+	found.Content = ""
+	return libs.NopReadSeekCloser(strings.NewReader(content)), found, nil
+}
+
+// Latest returns the newest revision of an album without its content, ErrContentRevisionNotFound when it has none
+func (repo *contentRevisionMongoDBRepository) Latest(albumId uint) (entities.AlbumContentRevisionMongoDB, error) {
+	var latest entities.AlbumContentRevisionMongoDB
+	err := repo.dbContext.Collection("album_content_revisions").
+		FindOne(context.TODO(), bson.M{"albumId": albumId}, options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}}).SetProjection(bson.M{"content": 0})).
+		Decode(&latest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return latest, ErrContentRevisionNotFound
+	}
+	return latest, err
+}
+
+// Create stores the revision with what content has under the next number of its album. Content larger than the
+// inline limit is streamed to GridFS as it is read and is left out of the revision returned.
+func (repo *contentRevisionMongoDBRepository) Create(revision *entities.AlbumContentRevisionMongoDB, content io.Reader) (entities.AlbumContentRevisionMongoDB, error) {
+	revision.ID, revision.Content, revision.ContentFileId = primitive.NewObjectID(), "", nil
+	inlineMaxBytes := repo.files.storage.InlineMaxBytes
+	head, err := io.ReadAll(io.LimitReader(content, int64(inlineMaxBytes)+1))
+	if err != nil {
+		return *revision, err
+	}
+	if len(head) > inlineMaxBytes {
+		id, err := repo.files.upload(revision.AlbumId, contentFileOwnerRevision, contentFileMarkdown, io.MultiReader(bytes.NewReader(head), content))
+		if err != nil {
+			return *revision, err
+		}
+		revision.ContentFileId = &id
+	} else {
+		revision.Content = string(head)
+	}
+	document := *revision
+
+	for attempt := 0; attempt < contentRevisionAttempts; attempt++ {
+		latest, latestErr := repo.Latest(revision.AlbumId)
+		if latestErr != nil && !errors.Is(latestErr, ErrContentRevisionNotFound) {
+			err = latestErr
+			break
+		}
+		revision.Revision = latest.Revision + 1
+		document.Revision = revision.Revision
+
+		if _, err = repo.dbContext.Collection("album_content_revisions").InsertOne(context.TODO(), document); !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		repo.files.delete(document.ContentFileId)
+	}
+	return *revision, err
+}
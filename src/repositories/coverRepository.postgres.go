@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ICoverRepository interface {
+	FindByAlbumIds(albumIds []uint) ([]entities.AlbumCover, error)
+	FindByAlbumId(albumId uint) (entities.AlbumCover, error)
+	Save(cover *entities.AlbumCover) (entities.AlbumCover, error)
+	Delete(albumId uint) error
+}
+
+var ErrCoverNotFound = errors.New("album has no cover")
+
+type CoverRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// CoverRepository constructor
+func NewCoverRepository(conn *sql.DB) *CoverRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &CoverRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *CoverRepository) FindByAlbumIds(albumIds []uint) ([]entities.AlbumCover, error) {
+	covers := []entities.AlbumCover{}
+	if len(albumIds) == 0 {
+		return covers, nil
+	}
+	result := repo.dbContext.Debug().Where("album_id IN ?", albumIds).Find(&covers)
+	return covers, result.Error
+}
+
+func (repo *CoverRepository) FindByAlbumId(albumId uint) (entities.AlbumCover, error) {
+	cover := entities.AlbumCover{}
+	result := repo.dbContext.Debug().Find(&cover, "album_id = ?", albumId)
+	if result.Error == nil && cover.AlbumId == 0 {
+		return cover, ErrCoverNotFound
+	}
+	return cover, result.Error
+}
+
+// Save inserts the cover of an album or replaces the one it has
+func (repo *CoverRepository) Save(cover *entities.AlbumCover) (entities.AlbumCover, error) {
+	result := repo.dbContext.Debug().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "album_id"}},
+		UpdateAll: true,
+	}).Create(cover)
+
+	var pqErr *pq.Error
+	if errors.As(result.Error, &pqErr) && pqErr.Code == pqForeignKeyViolation {
+		return *cover, ErrAlbumNotFound
+	}
+	return *cover, result.Error
+}
+
+func (repo *CoverRepository) Delete(albumId uint) error {
+	result := repo.dbContext.Debug().Where("album_id = ?", albumId).Delete(&entities.AlbumCover{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrCoverNotFound
+	}
+	return result.Error
+}
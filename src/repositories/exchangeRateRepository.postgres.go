@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IExchangeRateRepository interface {
+	FindAll() ([]entities.ExchangeRate, error)
+	Replace(rates []entities.ExchangeRate) error
+}
+
+type ExchangeRateRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// ExchangeRateRepository constructor
+func NewExchangeRateRepository(conn *sql.DB) *ExchangeRateRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &ExchangeRateRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *ExchangeRateRepository) FindAll() ([]entities.ExchangeRate, error) {
+	rates := []entities.ExchangeRate{}
+	result := repo.dbContext.Debug().Order("currency").Find(&rates)
+	return rates, result.Error
+}
+
+// Replace swaps the whole rate table in one transaction, rates of different tables must never be mixed
+func (repo *ExchangeRateRepository) Replace(rates []entities.ExchangeRate) error {
+	return repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Exec(`DELETE FROM exchange_rates`).Error; err != nil {
+			return err
+		}
+		if len(rates) == 0 {
+			return nil
+		}
+		return tx.Debug().Create(&rates).Error
+	})
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IGenreRepository interface {
+	FindAll() ([]entities.Genre, error)
+	FindBySlugs(slugs []string) ([]entities.Genre, error)
+	Create(newGenre *entities.Genre) (entities.Genre, error)
+	Delete(slug string) error
+}
+
+var (
+	ErrGenreNotFound = errors.New("genre not found")
+	ErrGenreExists   = errors.New("a genre with this name exists already")
+	ErrGenreInUse    = errors.New("genre still has albums")
+)
+
+var genreSlugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+type GenreRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// GenreRepository constructor
+func NewGenreRepository(conn *sql.DB) *GenreRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &GenreRepository{dbContext: gormDB, logger: logger}
+}
+
+// GenreSlug derives the slug of a genre name: lower case words joined by "-", e.g. "Hip Hop" is "hip-hop"
+func GenreSlug(name string) string {
+	return strings.Trim(genreSlugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+/* interface implementations */
+
+func (repo *GenreRepository) FindAll() ([]entities.Genre, error) {
+	genres := []entities.Genre{}
+	result := repo.dbContext.Debug().Order("slug").Find(&genres)
+	return genres, result.Error
+}
+
+// FindBySlugs returns the genres in the order of slugs, it fails with ErrGenreNotFound when one of them is unknown
+func (repo *GenreRepository) FindBySlugs(slugs []string) ([]entities.Genre, error) {
+	genres := []entities.Genre{}
+	if len(slugs) == 0 {
+		return genres, nil
+	}
+
+	found := []entities.Genre{}
+	if result := repo.dbContext.Debug().Where("slug IN ?", slugs).Find(&found); result.Error != nil {
+		return genres, result.Error
+	}
+	lookup := map[string]entities.Genre{}
+	for _, genre := range found {
+		lookup[genre.Slug] = genre
+	}
+
+	seen := map[string]bool{}
+	for _, slug := range slugs {
+		genre, ok := lookup[slug]
+		if !ok {
+			return genres, fmt.Errorf("%w: %s", ErrGenreNotFound, slug)
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			genres = append(genres, genre)
+		}
+	}
+	return genres, nil
+}
+
+func (repo *GenreRepository) Create(newGenre *entities.Genre) (entities.Genre, error) {
+	newGenre.Slug = GenreSlug(newGenre.Name)
+	result := repo.dbContext.Debug().Create(newGenre)
+	return *newGenre, translateGenreError(result.Error)
+}
+
+// Delete refuses to remove a genre that albums still use
+func (repo *GenreRepository) Delete(slug string) error {
+	result := repo.dbContext.Debug().Where("slug = ?", slug).Delete(&entities.Genre{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrGenreNotFound
+	}
+	return translateGenreError(result.Error)
+}
+
+func translateGenreError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		return ErrGenreExists
+	case pqForeignKeyViolation:
+		return ErrGenreInUse
+	default:
+		return err
+	}
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IPriceRepository interface {
+	FindHistory(albumId uint) ([]entities.PriceChange, error)
+	FindScheduled(albumId uint) ([]entities.ScheduledPrice, error)
+	Schedule(price *entities.ScheduledPrice) (entities.ScheduledPrice, error)
+	Cancel(albumId, id uint) error
+	DuePrices(albumIds []uint, now time.Time) (map[uint]entities.ScheduledPrice, error)
+	ApplyDue(now time.Time, limit int) ([]entities.ScheduledPrice, error)
+}
+
+var ErrScheduledPriceNotFound = errors.New("pending scheduled price not found")
+
+type PriceRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// PriceRepository constructor
+func NewPriceRepository(conn *sql.DB) *PriceRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &PriceRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+// FindHistory returns the price changes of an album, newest first
+func (repo *PriceRepository) FindHistory(albumId uint) ([]entities.PriceChange, error) {
+	changes := []entities.PriceChange{}
+	result := repo.dbContext.Debug().Where("album_id = ?", albumId).Order("changed_at DESC").Order("id DESC").Find(&changes)
+	return changes, result.Error
+}
+
+// FindScheduled returns the pending prices of an album in the order they take effect
+func (repo *PriceRepository) FindScheduled(albumId uint) ([]entities.ScheduledPrice, error) {
+	prices := []entities.ScheduledPrice{}
+	result := repo.dbContext.Debug().Where("album_id = ? AND status = ?", albumId, entities.ScheduledPricePending).
+		Order("effective_from").Order("id").Find(&prices)
+	return prices, result.Error
+}
+
+func (repo *PriceRepository) Schedule(price *entities.ScheduledPrice) (entities.ScheduledPrice, error) {
+	price.Status, price.CreatedAt = entities.ScheduledPricePending, time.Now()
+	result := repo.dbContext.Debug().Omit("AppliedAt").Create(price)
+
+	var pqErr *pq.Error
+	if errors.As(result.Error, &pqErr) && pqErr.Code == pqForeignKeyViolation {
+		return *price, ErrAlbumNotFound
+	}
+	return *price, result.Error
+}
+
+// Cancel withdraws a pending price, applied and superseded prices are history and can not be cancelled
+func (repo *PriceRepository) Cancel(albumId, id uint) error {
+	result := repo.dbContext.Debug().Model(&entities.ScheduledPrice{}).
+		Where("id = ? AND album_id = ? AND status = ?", id, albumId, entities.ScheduledPricePending).
+		Update("status", entities.ScheduledPriceCancelled)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrScheduledPriceNotFound
+	}
+	return result.Error
+}
+
+// DuePrices returns, for each of the albums that has one, the latest pending price that is due at now
+func (repo *PriceRepository) DuePrices(albumIds []uint, now time.Time) (map[uint]entities.ScheduledPrice, error) {
+	lookup := map[uint]entities.ScheduledPrice{}
+	if len(albumIds) == 0 {
+		return lookup, nil
+	}
+
+	var prices []entities.ScheduledPrice
+	result := repo.dbContext.Debug().Raw(`SELECT DISTINCT ON (album_id) * FROM scheduled_prices
+		WHERE album_id IN ? AND status = ? AND effective_from <= ?
+		ORDER BY album_id, effective_from DESC, id DESC`, albumIds, entities.ScheduledPricePending, now).Scan(&prices)
+	for _, price := range prices {
+		lookup[price.AlbumId] = price
+	}
+	return lookup, result.Error
+}
+
+// ApplyDue claims up to limit pending prices that are due at now and writes them to their albums, in one transaction.
+// When several prices of an album are claimed together only the latest is applied, the others are superseded.
+// The claimed prices are returned with their new status. Albums are written as the user that scheduled the price,
+// so the price history names them. Any other price write supersedes the prices that are due, see migration 0015.
+func (repo *PriceRepository) ApplyDue(now time.Time, limit int) ([]entities.ScheduledPrice, error) {
+	var claimed []entities.ScheduledPrice
+	err := repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		// the prices written here are the scheduled ones, they must not supersede the prices claimed with them
+		if err := tx.Debug().Exec(`SELECT set_config('app.applying_scheduled_prices', 'on', true)`).Error; err != nil {
+			return err
+		}
+
+		result := tx.Debug().Raw(`SELECT * FROM scheduled_prices
+			WHERE status = ? AND effective_from <= ?
+			ORDER BY effective_from, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED`, entities.ScheduledPricePending, now, limit).Scan(&claimed)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// claimed is in effective order, so the last price of an album wins
+		latest := map[uint]int{}
+		for i, price := range claimed {
+			latest[price.AlbumId] = i
+		}
+
+		for i := range claimed {
+			price := &claimed[i]
+			price.Status = entities.ScheduledPriceSuperseded
+			if latest[price.AlbumId] == i {
+				price.Status, price.AppliedAt = entities.ScheduledPriceApplied, &now
+				result := tx.Debug().Model(&entities.Album{}).Where("id = ?", price.AlbumId).Updates(map[string]interface{}{
+					"price":      price.Price,
+					"currency":   price.Currency,
+					"updated_at": now,
+					"updated_by": price.CreatedBy,
+				})
+				if result.Error != nil {
+					return result.Error
+				}
+			}
+			result := tx.Debug().Model(&entities.ScheduledPrice{}).Where("id = ?", price.Id).
+				Updates(map[string]interface{}{"status": price.Status, "applied_at": price.AppliedAt})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
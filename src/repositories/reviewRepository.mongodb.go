@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+type IReviewMongoDBRepository interface {
+	FindByAlbum(albumId uint, page, pageSize int) ([]entities.ReviewMongoDB, error)
+	FindById(albumId uint, id primitive.ObjectID) (entities.ReviewMongoDB, error)
+	Create(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error)
+	Update(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error)
+	Delete(albumId uint, id primitive.ObjectID) error
+	Ratings(albumIds []uint) (map[uint]entities.AlbumRatingMongoDB, error)
+	DeleteByAlbum(albumId uint) error
+}
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrReviewExists   = errors.New("the album has a review by this user already")
+)
+
+type reviewMongoDBRepository struct {
+	dbContext *mongo.Database
+	logger    *zap.Logger
+}
+
+// ReviewMongoDBRepository constructor, it makes sure the indexes the reviews rely on exist
+func ReviewMongoDBRepository(db *mongo.Database) *reviewMongoDBRepository {
+	repo := reviewMongoDBRepository{dbContext: db, logger: libs.NewZapLogger()}
+
+	indexes := []struct {
+		collection string
+		model      mongo.IndexModel
+	}{
+		// one review per user and album, it also serves listing the reviews of an album
+		{"reviews", mongo.IndexModel{Keys: bson.D{{Key: "albumId", Value: 1}, {Key: "author", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{"reviews", mongo.IndexModel{Keys: bson.D{{Key: "albumId", Value: 1}, {Key: "createdAt", Value: -1}}}},
+		{"album_ratings", mongo.IndexModel{Keys: bson.D{{Key: "albumId", Value: 1}}, Options: options.Index().SetUnique(true)}},
+	}
+	for _, index := range indexes {
+		if _, err := db.Collection(index.collection).Indexes().CreateOne(context.TODO(), index.model); err != nil {
+			repo.logger.Error("mongodb index error",
+				zap.String("collection", index.collection),
+				zap.String("error", err.Error()),
+			)
+		}
+	}
+	return &repo
+}
+
+/* interface implementations */
+
+// FindByAlbum returns the reviews of an album, newest first
+func (repo *reviewMongoDBRepository) FindByAlbum(albumId uint, page, pageSize int) ([]entities.ReviewMongoDB, error) {
+	reviews := []entities.ReviewMongoDB{}
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	if pageSize > 0 {
+		findOptions.SetSkip(int64((page - 1) * pageSize)).SetLimit(int64(pageSize))
+	}
+
+	cursor, err := repo.dbContext.Collection("reviews").Find(context.TODO(), bson.M{"albumId": albumId}, findOptions)
+	if err != nil {
+		return reviews, err
+	}
+	err = cursor.All(context.TODO(), &reviews)
+	return reviews, err
+}
+
+func (repo *reviewMongoDBRepository) FindById(albumId uint, id primitive.ObjectID) (entities.ReviewMongoDB, error) {
+	var review entities.ReviewMongoDB
+	err := repo.dbContext.Collection("reviews").FindOne(context.TODO(), bson.M{"_id": id, "albumId": albumId}).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return review, ErrReviewNotFound
+	}
+	return review, err
+}
+
+// Create adds the review and recomputes the album rating
+func (repo *reviewMongoDBRepository) Create(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	now := time.Now()
+	review.ID, review.CreatedAt, review.UpdatedAt = primitive.NewObjectID(), now, now
+
+	if _, err := repo.dbContext.Collection("reviews").InsertOne(context.TODO(), *review); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return *review, ErrReviewExists
+		}
+		return *review, err
+	}
+	return *review, repo.refreshRating(review.AlbumId)
+}
+
+// Update saves the rating, title and body of the review and recomputes the album rating when the rating changed
+func (repo *reviewMongoDBRepository) Update(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	review.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{"rating": review.Rating, "title": review.Title, "body": review.Body, "updatedAt": review.UpdatedAt}}
+
+	var before entities.ReviewMongoDB
+	err := repo.dbContext.Collection("reviews").
+		FindOneAndUpdate(context.TODO(), bson.M{"_id": review.ID, "albumId": review.AlbumId}, update, options.FindOneAndUpdate().SetReturnDocument(options.Before)).
+		Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return *review, ErrReviewNotFound
+	}
+	if err != nil {
+		return *review, err
+	}
+
+	review.Author, review.CreatedAt = before.Author, before.CreatedAt
+	if review.Rating != before.Rating {
+		err = repo.refreshRating(review.AlbumId)
+	}
+	return *review, err
+}
+
+// Delete removes the review and recomputes the album rating
+func (repo *reviewMongoDBRepository) Delete(albumId uint, id primitive.ObjectID) error {
+	var deleted entities.ReviewMongoDB
+	err := repo.dbContext.Collection("reviews").FindOneAndDelete(context.TODO(), bson.M{"_id": id, "albumId": albumId}).Decode(&deleted)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrReviewNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return repo.refreshRating(albumId)
+}
+
+// Ratings returns the rating of each album that has one
+func (repo *reviewMongoDBRepository) Ratings(albumIds []uint) (map[uint]entities.AlbumRatingMongoDB, error) {
+	ratings := map[uint]entities.AlbumRatingMongoDB{}
+	if len(albumIds) == 0 {
+		return ratings, nil
+	}
+
+	// the rating of an album whose reviews are all deleted stays with a count of 0
+	filter := bson.M{"albumId": bson.M{"$in": albumIds}, "count": bson.M{"$gt": 0}}
+	cursor, err := repo.dbContext.Collection("album_ratings").Find(context.TODO(), filter)
+	if err != nil {
+		return ratings, err
+	}
+	var results []entities.AlbumRatingMongoDB
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return ratings, err
+	}
+	for _, rating := range results {
+		ratings[rating.AlbumId] = rating
+	}
+	return ratings, nil
+}
+
+// DeleteByAlbum removes the reviews and the rating of a deleted album
+func (repo *reviewMongoDBRepository) DeleteByAlbum(albumId uint) error {
+	if _, err := repo.dbContext.Collection("reviews").DeleteMany(context.TODO(), bson.M{"albumId": albumId}); err != nil {
+		return err
+	}
+	_, err := repo.dbContext.Collection("album_ratings").DeleteOne(context.TODO(), bson.M{"albumId": albumId})
+	return err
+}
+
+// AlbumRatingPipeline sums up the reviews of an album in one document, there is none when the album has no reviews
+func AlbumRatingPipeline(albumId uint) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"albumId": albumId}}},
+		{{Key: "$group", Value: bson.M{"_id": "$albumId", "count": bson.M{"$sum": 1}, "sum": bson.M{"$sum": "$rating"}}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "albumId": "$_id", "count": 1, "sum": 1}}},
+	}
+}
+
+// AlbumRatingUpdate writes the count and sum of the reviews of an album counted at version, the filter leaves a
+// rating that was counted at a later version alone
+func AlbumRatingUpdate(albumId uint, version int64, counted entities.AlbumRatingMongoDB) (bson.M, bson.M) {
+	filter := bson.M{"albumId": albumId, "countedVersion": bson.M{"$not": bson.M{"$gte": version}}}
+	update := bson.M{"$set": bson.M{"count": counted.Count, "sum": counted.Sum, "countedVersion": version}}
+	return filter, update
+}
+
+// refreshRating recomputes the rating of an album from its reviews. The review is written already, so the rating
+// is never counted from a separate increment that could be lost or applied twice, a failed refresh is repaired
+// by the next review of the album. Refreshes of the same album can overlap: each takes the next version of the
+// rating before it counts and only writes over a count of an earlier version, so a count that started earlier
+// but finishes later never replaces a newer one.
+func (repo *reviewMongoDBRepository) refreshRating(albumId uint) error {
+	var rating entities.AlbumRatingMongoDB
+	err := repo.dbContext.Collection("album_ratings").
+		FindOneAndUpdate(context.TODO(), bson.M{"albumId": albumId}, bson.M{"$inc": bson.M{"version": 1}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).
+		Decode(&rating)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := repo.dbContext.Collection("reviews").Aggregate(context.TODO(), AlbumRatingPipeline(albumId))
+	if err != nil {
+		return err
+	}
+	var counts []entities.AlbumRatingMongoDB
+	if err := cursor.All(context.TODO(), &counts); err != nil {
+		return err
+	}
+	// the last review is gone when nothing is counted
+	counted := entities.AlbumRatingMongoDB{}
+	if len(counts) > 0 {
+		counted = counts[0]
+	}
+
+	filter, update := AlbumRatingUpdate(albumId, rating.Version, counted)
+	_, err = repo.dbContext.Collection("album_ratings").UpdateOne(context.TODO(), filter, update)
+	return err
+}
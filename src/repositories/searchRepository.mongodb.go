@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IAlbumSearchMongoDBRepository interface {
+	Search(query libs.SearchQuery, limit int) ([]SearchHit, error)
+	Sources(albumIds []uint) ([]entities.AlbumMongoDB, error)
+}
+
+// contentPrefixScore is the score of a match that only prefix terms found, mongodb does not score those
+const contentPrefixScore = 1
+
+type albumSearchMongoDBRepository struct {
+	dbContext *mongo.Database
+}
+
+// AlbumSearchMongoDBRepository constructor, the text and the terms indexes are made by db.MigrateMongo
+func AlbumSearchMongoDBRepository(db *mongo.Database) *albumSearchMongoDBRepository {
+	return &albumSearchMongoDBRepository{dbContext: db}
+}
+
+/* interface implementations */
+
+// Search returns up to limit albums whose name or content has every term of query, best first, scored by the text
+// index. Words and phrases go through the text index, which stems english words. The index has no prefixes, so
+// prefix terms are looked up in the words of the documents kept in the terms index, and then matched with a
+// regular expression on what it found. Content kept in GridFS is searched in its text.
+func (repo *albumSearchMongoDBRepository) Search(query libs.SearchQuery, limit int) ([]SearchHit, error) {
+	hits := []SearchHit{}
+	filter := bson.D{}
+	phrases := []string{}
+	for _, term := range query.Terms {
+		if !term.Prefix {
+			// quoted, every phrase has to match, bare words would match any of them
+			phrases = append(phrases, `"`+strings.Join(term.Words, " ")+`"`)
+			continue
+		}
+		filter = append(filter, prefixConditions(term)...)
+	}
+
+	findOptions := options.Find().SetLimit(int64(limit))
+	if len(phrases) > 0 {
+		filter = append(bson.D{{Key: "$text", Value: bson.M{"$search": strings.Join(phrases, " ")}}}, filter...)
+		score := bson.M{"$meta": "textScore"}
+		findOptions.SetProjection(bson.M{"albumId": 1, "score": score}).SetSort(bson.D{{Key: "score", Value: score}, {Key: "albumId", Value: 1}})
+	} else {
+		// prefix matches all score the same, the first limit the terms index finds are taken rather than sorting
+		// every match of a short prefix
+		findOptions.SetProjection(bson.M{"albumId": 1})
+	}
+	// conditions on the same field can not be keys of the same document
+	if len(filter) > 1 {
+		filter = bson.D{{Key: "$and", Value: andConditions(filter)}}
+	}
+
+	cursor, err := repo.dbContext.Collection("albums").Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return hits, err
+	}
+	var found []struct {
+		AlbumId uint    `bson:"albumId"`
+		Score   float64 `bson:"score"`
+	}
+	if err := cursor.All(context.TODO(), &found); err != nil {
+		return hits, err
+	}
+	for _, document := range found {
+		score := document.Score
+		if len(phrases) == 0 {
+			score = contentPrefixScore
+		}
+		hits = append(hits, SearchHit{AlbumId: document.AlbumId, Score: score})
+	}
+	return hits, nil
+}
+
+// Sources returns the names and the content of albums to highlight search matches in, the text of content kept in
+// GridFS stands in for it
+func (repo *albumSearchMongoDBRepository) Sources(albumIds []uint) ([]entities.AlbumMongoDB, error) {
+	sources := []entities.AlbumMongoDB{}
+	if len(albumIds) == 0 {
+		return sources, nil
+	}
+	cursor, err := repo.dbContext.Collection("albums").Find(context.TODO(), bson.M{"albumId": bson.M{"$in": albumIds}},
+		options.Find().SetProjection(bson.M{"albumId": 1, "name": 1, "content": 1, "contentHtml": 1, "contentText": 1}))
+	if err != nil {
+		return sources, err
+	}
+	err = cursor.All(context.TODO(), &sources)
+	return sources, err
+}
+
+// prefixConditions match a prefix term. Its words have to be words of the document, the last one only the start
+// of one, which the terms index answers with a range as the words are lower case and the expressions anchored.
+// The words are then matched in a row in the name or the content.
+func prefixConditions(term libs.SearchTerm) bson.D {
+	conditions := bson.D{}
+	last := len(term.Words) - 1
+	for _, word := range term.Words[:last] {
+		conditions = append(conditions, bson.E{Key: "terms", Value: word})
+	}
+	// words are letters and digits only, so they need no escaping
+	conditions = append(conditions, bson.E{Key: "terms", Value: primitive.Regex{Pattern: "^" + term.Words[last]}})
+
+	pattern := primitive.Regex{Pattern: `\b` + strings.Join(term.Words, `\W+`), Options: "i"}
+	return append(conditions, bson.E{Key: "$or", Value: bson.A{bson.M{"name": pattern}, bson.M{"content": pattern}, bson.M{"contentText": pattern}}})
+}
+
+func andConditions(filter bson.D) bson.A {
+	conditions := bson.A{}
+	for _, condition := range filter {
+		conditions = append(conditions, bson.D{condition})
+	}
+	return conditions
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IAlbumSearchRepository interface {
+	Search(query libs.SearchQuery, limit int) ([]SearchHit, error)
+	Fuzzy(text string, threshold float64, limit int) ([]SearchHit, error)
+	Catalogue() ([]CatalogueEntry, error)
+	Suggest(query libs.SearchQuery, limit int) ([]Suggestion, error)
+}
+
+// suggestCandidates bounds the titles and the names a suggestion is picked from. A short prefix matches most of a
+// large catalogue, each table keeps its best matches only, with a sort bounded by the limit, before they are joined.
+const suggestCandidates = 1000
+
+// types of suggestions
+const (
+	SuggestionAlbum  = "album"
+	SuggestionArtist = "artist"
+)
+
+// ErrFuzzySearchUnsupported is returned by Fuzzy when the database does not have pg_trgm
+var ErrFuzzySearchUnsupported = errors.New("fuzzy search needs the pg_trgm extension")
+
+// SearchHit is an album matching a search, Score is its relevance on the backend that found it, higher is better
+type SearchHit struct {
+	AlbumId uint
+	Score   float64
+}
+
+// CatalogueEntry is the title and the artist name of an album
+type CatalogueEntry struct {
+	AlbumId uint
+	Title   string
+	Artist  string
+}
+
+// Suggestion is an album title or an artist name that completes what was typed
+type Suggestion struct {
+	Type string
+	Id   uint
+	Text string
+}
+
+type AlbumSearchRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+	// trigrams tells whether pg_trgm is installed once it is known, guarded by trigramsLock
+	trigrams     *bool
+	trigramsLock sync.Mutex
+}
+
+// AlbumSearchRepository constructor
+func NewAlbumSearchRepository(conn *sql.DB) *AlbumSearchRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &AlbumSearchRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+// Search returns up to limit albums whose title and artist name together have every term of query, best first.
+// Albums are found through the text indexes of titles and artist names, with any word of the query, and then
+// checked and ranked on both. Title words rank above artist words, the score is between 0 and 1.
+func (repo *AlbumSearchRepository) Search(query libs.SearchQuery, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	all, any := tsQuery(query, " & "), tsQuery(anyTerm(query), " | ")
+	result := repo.dbContext.Debug().Raw(`WITH candidates AS (
+			SELECT id FROM albums WHERE to_tsvector('simple', title) @@ to_tsquery('simple', ?)
+			UNION
+			SELECT albums.id FROM albums JOIN artists ON artists.id = albums.artist_id
+			WHERE to_tsvector('simple', artists.name) @@ to_tsquery('simple', ?)
+		)
+		SELECT albums.id AS album_id, ts_rank(search.document, to_tsquery('simple', ?), 32) AS score
+		FROM candidates
+		JOIN albums ON albums.id = candidates.id
+		JOIN artists ON artists.id = albums.artist_id,
+		LATERAL (SELECT setweight(to_tsvector('simple', albums.title), 'A') || setweight(to_tsvector('simple', artists.name), 'B') AS document) search
+		WHERE search.document @@ to_tsquery('simple', ?)
+		ORDER BY score DESC, albums.id
+		LIMIT ?`, any, any, all, all, limit).Scan(&hits)
+	return hits, result.Error
+}
+
+// Fuzzy returns up to limit albums whose title or artist name has words similar to those of text, most similar first.
+// The score is the word_similarity of pg_trgm, albums below threshold are left out.
+func (repo *AlbumSearchRepository) Fuzzy(text string, threshold float64, limit int) ([]SearchHit, error) {
+	supported, err := repo.hasTrigrams()
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		return nil, ErrFuzzySearchUnsupported
+	}
+
+	var hits []SearchHit
+	text = strings.ToLower(text)
+	err = repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		// <% matches what is above the threshold and is what the trigram indexes can answer, it is set for this transaction only
+		if err := tx.Exec(`SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)`, strconv.FormatFloat(threshold, 'f', -1, 64)).Error; err != nil {
+			return err
+		}
+		return tx.Debug().Raw(`WITH candidates AS (
+				SELECT id FROM albums WHERE ? <% lower(title)
+				UNION
+				SELECT albums.id FROM albums JOIN artists ON artists.id = albums.artist_id WHERE ? <% lower(artists.name)
+			)
+			SELECT albums.id AS album_id, GREATEST(word_similarity(?, lower(albums.title)), word_similarity(?, lower(artists.name))) AS score
+			FROM candidates
+			JOIN albums ON albums.id = candidates.id
+			JOIN artists ON artists.id = albums.artist_id
+			ORDER BY score DESC, albums.id
+			LIMIT ?`, text, text, text, text, limit).Scan(&hits).Error
+	})
+	return hits, err
+}
+
+// Catalogue returns the title and the artist name of every album, for searching them where the database can not
+func (repo *AlbumSearchRepository) Catalogue() ([]CatalogueEntry, error) {
+	var entries []CatalogueEntry
+	result := repo.dbContext.Debug().Raw(`SELECT albums.id AS album_id, albums.title, artists.name AS artist
+		FROM albums JOIN artists ON artists.id = albums.artist_id
+		ORDER BY albums.id`).Scan(&entries)
+	return entries, result.Error
+}
+
+// Suggest returns up to limit album titles and artist names having every term of query, those starting with the
+// first word first, then the shortest. Each table gives its best matches in that order as candidates.
+func (repo *AlbumSearchRepository) Suggest(query libs.SearchQuery, limit int) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	all := tsQuery(query, " & ")
+	startsWith := "%"
+	if len(query.Terms) > 0 && len(query.Terms[0].Words) > 0 {
+		// words are letters and digits only, so they need no escaping for LIKE
+		startsWith = query.Terms[0].Words[0] + "%"
+	}
+	result := repo.dbContext.Debug().Raw(`SELECT type, id, text FROM (
+			(SELECT ? AS type, id, title AS text FROM albums WHERE to_tsvector('simple', title) @@ to_tsquery('simple', ?)
+				ORDER BY lower(title) LIKE ? DESC, length(title), title, id LIMIT ?)
+			UNION ALL
+			(SELECT ? AS type, id, name AS text FROM artists WHERE to_tsvector('simple', name) @@ to_tsquery('simple', ?)
+				ORDER BY lower(name) LIKE ? DESC, length(name), name, id LIMIT ?)
+		) suggestions
+		ORDER BY lower(text) LIKE ? DESC, length(text), text, type, id
+		LIMIT ?`, SuggestionAlbum, all, startsWith, suggestCandidates, SuggestionArtist, all, startsWith, suggestCandidates, startsWith, limit).Scan(&suggestions)
+	return suggestions, result.Error
+}
+
+// hasTrigrams tells whether pg_trgm is installed, it is looked up on first use because repositories are made
+// before the migrations run
+func (repo *AlbumSearchRepository) hasTrigrams() (bool, error) {
+	repo.trigramsLock.Lock()
+	defer repo.trigramsLock.Unlock()
+	if repo.trigrams == nil {
+		var installed bool
+		if err := repo.dbContext.Debug().Raw(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&installed).Error; err != nil {
+			return false, err
+		}
+		repo.trigrams = &installed
+	}
+	return *repo.trigrams, nil
+}
+
+// tsQuery writes query for to_tsquery, terms joined by operator, phrases with <-> and prefixes with :*.
+// Words are letters and digits only, so they need no quoting.
+func tsQuery(query libs.SearchQuery, operator string) string {
+	terms := []string{}
+	for _, term := range query.Terms {
+		phrase := strings.Join(term.Words, " <-> ")
+		if term.Prefix {
+			phrase += ":*"
+		}
+		terms = append(terms, "("+phrase+")")
+	}
+	return strings.Join(terms, operator)
+}
+
+// anyTerm splits the phrases of query into words, a prefix stays on the last word of its phrase
+func anyTerm(query libs.SearchQuery) libs.SearchQuery {
+	words := libs.SearchQuery{}
+	for _, term := range query.Terms {
+		for i, word := range term.Words {
+			words.Terms = append(words.Terms, libs.SearchTerm{Words: []string{word}, Prefix: term.Prefix && i == len(term.Words)-1})
+		}
+	}
+	return words
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type IStatsMongoDBRepository interface {
+	ContentStats() (ContentStats, error)
+}
+
+// ContentLengthBuckets are the upper bounds in bytes of the content length buckets, the last bucket is open ended
+var ContentLengthBuckets = []int64{1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
+// ContentStats are figures about the content of every album document
+type ContentStats struct {
+	// WithContent counts the documents that have content, the bytes are theirs
+	WithContent int64
+	TotalBytes  int64
+	MaxBytes    int64
+	Lengths     []ContentLengthBucketCount
+}
+
+// ContentLengthBucketCount counts the contents from From bytes up to To, To is nil for the last bucket
+type ContentLengthBucketCount struct {
+	From  int64
+	To    *int64
+	Count int64
+}
+
+type statsMongoDBRepository struct {
+	dbContext *mongo.Database
+}
+
+// StatsMongoDBRepository constructor
+func StatsMongoDBRepository(db *mongo.Database) *statsMongoDBRepository {
+	return &statsMongoDBRepository{dbContext: db}
+}
+
+/* interface implementations */
+
+// ContentStats aggregates the content length of every album document in one pipeline, the documents are not read
+// into the application. Content kept in GridFS counts with the size recorded in its document.
+func (repo *statsMongoDBRepository) ContentStats() (ContentStats, error) {
+	stats := ContentStats{Lengths: []ContentLengthBucketCount{}}
+	cursor, err := repo.dbContext.Collection("albums").Aggregate(context.TODO(), ContentStatsPipeline())
+	if err != nil {
+		return stats, err
+	}
+	var results []struct {
+		Summary []struct {
+			WithContent int64 `bson:"withContent"`
+			TotalBytes  int64 `bson:"totalBytes"`
+			MaxBytes    int64 `bson:"maxBytes"`
+		} `bson:"summary"`
+		Lengths []struct {
+			From  int64 `bson:"_id"`
+			Count int64 `bson:"count"`
+		} `bson:"lengths"`
+	}
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return stats, err
+	}
+
+	counts := map[int64]int64{}
+	// $facet returns one document, its summary is empty when there are no documents
+	for _, result := range results {
+		for _, summary := range result.Summary {
+			stats.WithContent, stats.TotalBytes, stats.MaxBytes = summary.WithContent, summary.TotalBytes, summary.MaxBytes
+		}
+		for _, length := range result.Lengths {
+			counts[length.From] = length.Count
+		}
+	}
+	from := int64(0)
+	for i := 0; i <= len(ContentLengthBuckets); i++ {
+		length := ContentLengthBucketCount{From: from, Count: counts[from]}
+		if i < len(ContentLengthBuckets) {
+			to := ContentLengthBuckets[i]
+			length.To, from = &to, to
+		}
+		stats.Lengths = append(stats.Lengths, length)
+	}
+	return stats, nil
+}
+
+// ContentStatsPipeline sums up the content sizes of the album documents in one document, with a summary and the
+// count of every bucket of ContentLengthBuckets that has documents
+func ContentStatsPipeline() mongo.Pipeline {
+	boundaries := bson.A{int64(0)}
+	for _, bound := range ContentLengthBuckets {
+		boundaries = append(boundaries, bound)
+	}
+	boundaries = append(boundaries, int64(math.MaxInt64))
+
+	// documents written before contentSize was recorded have their content inline
+	size := bson.M{"$ifNull": bson.A{"$contentSize", bson.M{"$strLenBytes": bson.M{"$ifNull": bson.A{"$content", ""}}}}}
+	return mongo.Pipeline{
+		{{Key: "$project", Value: bson.M{"_id": 0, "size": size}}},
+		{{Key: "$facet", Value: bson.M{
+			"summary": bson.A{bson.M{"$group": bson.M{
+				"_id":         nil,
+				"withContent": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$size", 0}}, 1, 0}}},
+				"totalBytes":  bson.M{"$sum": "$size"},
+				"maxBytes":    bson.M{"$max": "$size"},
+			}}},
+			"lengths": bson.A{bson.M{"$bucket": bson.M{
+				"groupBy":    "$size",
+				"boundaries": boundaries,
+				"output":     bson.M{"count": bson.M{"$sum": 1}},
+			}}},
+		}}},
+	}
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	libs "acy.com/api/src/lib"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IStatsRepository interface {
+	AlbumStats(groupBy string) (AlbumStats, error)
+}
+
+// what album stats are broken down by
+const (
+	StatsGroupByArtist      = "artist"
+	StatsGroupByPriceBucket = "price_bucket"
+)
+
+// PricePercentiles are the percentiles of the prices of every currency
+var PricePercentiles = []int{25, 50, 75, 90, 99}
+
+// StatsArtistLimit bounds the artists of the stats to those with the most albums
+const StatsArtistLimit = 100
+
+// AlbumStats are figures about every album, Artists or PriceBuckets is set depending on what they are grouped by
+type AlbumStats struct {
+	Total int64
+	// ArtistCount counts the artists having albums
+	ArtistCount int64
+	Prices      []CurrencyPriceStats
+	Artists     []FacetCount
+	// PriceBuckets are in the order of Prices
+	PriceBuckets []CurrencyPriceBuckets
+}
+
+// CurrencyPriceStats are figures about the prices of the albums priced in a currency, prices of different
+// currencies are not comparable
+type CurrencyPriceStats struct {
+	Currency string
+	Count    int64
+	Min      decimal.Decimal
+	Max      decimal.Decimal
+	Avg      decimal.Decimal
+	// Percentiles are prices of albums, one for each of PricePercentiles
+	Percentiles pq.StringArray `gorm:"type:numeric[]"`
+}
+
+type StatsRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// StatsRepository constructor
+func NewStatsRepository(conn *sql.DB) *StatsRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &StatsRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+// AlbumStats aggregates every album in the database, groupBy is StatsGroupByArtist or StatsGroupByPriceBucket
+func (repo *StatsRepository) AlbumStats(groupBy string) (AlbumStats, error) {
+	stats := AlbumStats{Prices: []CurrencyPriceStats{}}
+
+	// percentile_disc picks prices of albums, they are exact like every price
+	fractions := pq.Float64Array{}
+	for _, percentile := range PricePercentiles {
+		fractions = append(fractions, float64(percentile)/100)
+	}
+	err := repo.dbContext.Debug().Table("albums").
+		Select("currency, count(*) AS count, min(price) AS min, max(price) AS max, avg(price) AS avg, "+
+			"percentile_disc(?::float8[]) WITHIN GROUP (ORDER BY price) AS percentiles", fractions).
+		Group("currency").
+		Order("count DESC, currency").
+		Scan(&stats.Prices).Error
+	if err != nil {
+		return stats, err
+	}
+	for i, prices := range stats.Prices {
+		stats.Total += prices.Count
+		stats.Prices[i].Avg = prices.Avg.Round(libs.CurrencyMinorUnits(prices.Currency))
+	}
+
+	err = repo.dbContext.Debug().Table("albums").Select("count(DISTINCT artist_id)").Scan(&stats.ArtistCount).Error
+	if err != nil {
+		return stats, err
+	}
+
+	switch groupBy {
+	case StatsGroupByArtist:
+		err = repo.dbContext.Debug().Table("albums").
+			Select("CAST(artists.id AS TEXT) AS value, artists.name AS name, count(*) AS count").
+			Joins("JOIN artists ON artists.id = albums.artist_id").
+			Group("artists.id, artists.name").
+			Order("count DESC, artists.name").
+			Limit(StatsArtistLimit).
+			Scan(&stats.Artists).Error
+	case StatsGroupByPriceBucket:
+		stats.PriceBuckets, err = countPriceBuckets(repo.dbContext.Debug().Table("albums"))
+	default:
+		err = fmt.Errorf("album stats can not be grouped by %q", groupBy)
+	}
+	return stats, err
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type ITrackRepository interface {
+	FindByAlbumIds(albumIds []uint) ([]entities.Track, error)
+	FindById(albumId, id uint) (entities.Track, error)
+	Insert(track *entities.Track) (entities.Track, error)
+	Move(track *entities.Track) (entities.Track, error)
+	Delete(albumId, id uint) error
+	TotalDurations(albumIds []uint) (map[uint]uint, error)
+	Transaction(fn func(txRepo ITrackRepository) error) error
+}
+
+var ErrTrackNotFound = errors.New("track not found")
+
+type TrackRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// TrackRepository constructor
+func NewTrackRepository(conn *sql.DB) *TrackRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &TrackRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+// FindByAlbumIds returns the tracks of the albums in listing order
+func (repo *TrackRepository) FindByAlbumIds(albumIds []uint) ([]entities.Track, error) {
+	tracks := []entities.Track{}
+	if len(albumIds) == 0 {
+		return tracks, nil
+	}
+	result := repo.dbContext.Debug().Where("album_id IN ?", albumIds).Order("album_id").Order("disc_number").Order("number").Find(&tracks)
+	return tracks, result.Error
+}
+
+func (repo *TrackRepository) FindById(albumId, id uint) (entities.Track, error) {
+	track := entities.Track{}
+	result := repo.dbContext.Debug().Find(&track, "album_id = ? AND id = ?", albumId, id)
+	if result.Error == nil && track.Id == 0 {
+		return track, ErrTrackNotFound
+	}
+	return track, result.Error
+}
+
+// Insert puts the track at its number and shifts the following tracks of the disc down by one.
+// A track without a number, or with one past the last track, is appended.
+func (repo *TrackRepository) Insert(track *entities.Track) (entities.Track, error) {
+	err := repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		if err := lockAlbum(tx, track.AlbumId); err != nil {
+			return err
+		}
+		last, err := lastTrackNumber(tx, track.AlbumId, track.DiscNumber, 0)
+		if err != nil {
+			return err
+		}
+		if track.Number == 0 || track.Number > last {
+			track.Number = last + 1
+		} else if err := shiftTracks(tx, track.AlbumId, track.DiscNumber, track.Number, 0, 1); err != nil {
+			return err
+		}
+		return tx.Debug().Create(track).Error
+	})
+	return *track, err
+}
+
+// Move saves the title and duration of the track and moves it to its disc and number,
+// closing the gap it leaves and shifting the tracks at its new position down by one.
+// A number past the last track of the disc moves the track to the end.
+func (repo *TrackRepository) Move(track *entities.Track) (entities.Track, error) {
+	err := repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		if err := lockAlbum(tx, track.AlbumId); err != nil {
+			return err
+		}
+		current := entities.Track{}
+		if err := tx.Debug().Find(&current, "album_id = ? AND id = ?", track.AlbumId, track.Id).Error; err != nil {
+			return err
+		}
+		if current.Id == 0 {
+			return ErrTrackNotFound
+		}
+
+		if err := shiftTracks(tx, current.AlbumId, current.DiscNumber, current.Number+1, current.Id, -1); err != nil {
+			return err
+		}
+		last, err := lastTrackNumber(tx, track.AlbumId, track.DiscNumber, track.Id)
+		if err != nil {
+			return err
+		}
+		if track.Number == 0 || track.Number > last {
+			track.Number = last + 1
+		} else if err := shiftTracks(tx, track.AlbumId, track.DiscNumber, track.Number, track.Id, 1); err != nil {
+			return err
+		}
+
+		return tx.Debug().Model(track).Select("disc_number", "number", "title", "duration_seconds", "updated_at", "updated_by").Updates(track).Error
+	})
+	return *track, err
+}
+
+// Delete removes the track and moves the following tracks of the disc up by one
+func (repo *TrackRepository) Delete(albumId, id uint) error {
+	return repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		if err := lockAlbum(tx, albumId); err != nil {
+			return err
+		}
+		track := entities.Track{}
+		if err := tx.Debug().Find(&track, "album_id = ? AND id = ?", albumId, id).Error; err != nil {
+			return err
+		}
+		if track.Id == 0 {
+			return ErrTrackNotFound
+		}
+		if err := tx.Debug().Delete(&track).Error; err != nil {
+			return err
+		}
+		return shiftTracks(tx, albumId, track.DiscNumber, track.Number+1, 0, -1)
+	})
+}
+
+// TotalDurations sums the track durations of each album, albums without tracks are left out
+func (repo *TrackRepository) TotalDurations(albumIds []uint) (map[uint]uint, error) {
+	totals := map[uint]uint{}
+	if len(albumIds) == 0 {
+		return totals, nil
+	}
+	var rows []struct {
+		AlbumId uint
+		Total   uint
+	}
+	result := repo.dbContext.Debug().Model(&entities.Track{}).
+		Select("album_id, SUM(duration_seconds) AS total").
+		Where("album_id IN ?", albumIds).
+		Group("album_id").
+		Scan(&rows)
+	for _, row := range rows {
+		totals[row.AlbumId] = row.Total
+	}
+	return totals, result.Error
+}
+
+// Transaction runs fn against a repository bound to a single database transaction
+func (repo *TrackRepository) Transaction(fn func(txRepo ITrackRepository) error) error {
+	return repo.dbContext.Transaction(func(tx *gorm.DB) error {
+		return fn(&TrackRepository{dbContext: tx, logger: repo.logger})
+	})
+}
+
+// lockAlbum serializes the track changes of an album, it fails with ErrAlbumNotFound when there is no such album
+func lockAlbum(tx *gorm.DB, albumId uint) error {
+	var ids []uint
+	if err := tx.Debug().Raw(`SELECT id FROM albums WHERE id = ? FOR UPDATE`, albumId).Scan(&ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// lastTrackNumber is the highest number on the disc, ignoring the track excluded
+func lastTrackNumber(tx *gorm.DB, albumId, discNumber, excluded uint) (uint, error) {
+	var last uint
+	result := tx.Debug().Model(&entities.Track{}).
+		Select("COALESCE(MAX(number), 0)").
+		Where("album_id = ? AND disc_number = ? AND id <> ?", albumId, discNumber, excluded).
+		Scan(&last)
+	return last, result.Error
+}
+
+// shiftTracks adds delta to the number of the disc's tracks from number on, ignoring the track excluded
+func shiftTracks(tx *gorm.DB, albumId, discNumber, from, excluded uint, delta int) error {
+	return tx.Debug().Model(&entities.Track{}).
+		Where("album_id = ? AND disc_number = ? AND number >= ? AND id <> ?", albumId, discNumber, from, excluded).
+		Update("number", gorm.Expr("number + ?", delta)).Error
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IUserAlbumReadRepository interface {
+	FindByUser(userId string, albumIds []uint) ([]entities.UserAlbumRead, error)
+	Upsert(read *entities.UserAlbumRead) (entities.UserAlbumRead, error)
+	Delete(userId string, albumId uint) error
+	CountByUser(userId string) (int64, error)
+}
+
+type UserAlbumReadRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// UserAlbumReadRepository constructor
+func NewUserAlbumReadRepository(conn *sql.DB) *UserAlbumReadRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &UserAlbumReadRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *UserAlbumReadRepository) FindByUser(userId string, albumIds []uint) ([]entities.UserAlbumRead, error) {
+	var reads []entities.UserAlbumRead
+	if len(albumIds) == 0 {
+		return reads, nil
+	}
+	result := repo.dbContext.Debug().Where("user_id = ? AND album_id IN ?", userId, albumIds).Find(&reads)
+	return reads, result.Error
+}
+
+// Upsert keeps the first read timestamp when the album is already marked as read
+func (repo *UserAlbumReadRepository) Upsert(read *entities.UserAlbumRead) (entities.UserAlbumRead, error) {
+	result := repo.dbContext.Debug().Clauses(clause.OnConflict{DoNothing: true}).Create(read)
+	if result.Error != nil {
+		return *read, result.Error
+	}
+	result = repo.dbContext.Debug().Find(read, "user_id = ? AND album_id = ?", read.UserId, read.AlbumId)
+	return *read, result.Error
+}
+
+func (repo *UserAlbumReadRepository) Delete(userId string, albumId uint) error {
+	result := repo.dbContext.Debug().Delete(&entities.UserAlbumRead{}, "user_id = ? AND album_id = ?", userId, albumId)
+	return result.Error
+}
+
+// CountByUser counts the albums userId has read
+func (repo *UserAlbumReadRepository) CountByUser(userId string) (int64, error) {
+	var count int64
+	result := repo.dbContext.Debug().Model(&entities.UserAlbumRead{}).Where("user_id = ?", userId).Count(&count)
+	return count, result.Error
+}
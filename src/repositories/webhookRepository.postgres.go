@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type IWebhookRepository interface {
+	FindSubscriptions() ([]entities.WebhookSubscription, error)
+	FindSubscription(id uint) (entities.WebhookSubscription, error)
+	FindSubscriptionsByEvent(eventType string) ([]entities.WebhookSubscription, error)
+	CreateSubscription(subscription *entities.WebhookSubscription) (entities.WebhookSubscription, error)
+	DeleteSubscription(id uint) error
+	CreateDelivery(delivery *entities.WebhookDelivery) (entities.WebhookDelivery, error)
+	FindDelivery(id uint) (entities.WebhookDelivery, error)
+	FindDeliveries(subscriptionId uint, page, pageSize int) ([]entities.WebhookDelivery, error)
+	ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]entities.WebhookDelivery, error)
+	SaveDelivery(delivery *entities.WebhookDelivery) error
+}
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepository struct {
+	dbContext *gorm.DB
+	logger    *zap.Logger
+}
+
+// WebhookRepository constructor
+func NewWebhookRepository(conn *sql.DB) *WebhookRepository {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	logger := libs.NewZapLogger()
+
+	if err != nil {
+		logger.Error("gorm connection error",
+			zap.String("error", err.Error()),
+		)
+	}
+	return &WebhookRepository{dbContext: gormDB, logger: logger}
+}
+
+/* interface implementations */
+
+func (repo *WebhookRepository) FindSubscriptions() ([]entities.WebhookSubscription, error) {
+	subscriptions := []entities.WebhookSubscription{}
+	result := repo.dbContext.Debug().Order("id").Find(&subscriptions)
+	return subscriptions, result.Error
+}
+
+func (repo *WebhookRepository) FindSubscription(id uint) (entities.WebhookSubscription, error) {
+	subscription := entities.WebhookSubscription{}
+	result := repo.dbContext.Debug().Find(&subscription, "id = ?", id)
+	if result.Error == nil && subscription.Id == 0 {
+		return subscription, ErrWebhookNotFound
+	}
+	return subscription, result.Error
+}
+
+// FindSubscriptionsByEvent returns the active subscriptions listening to eventType, "*" listens to every event
+func (repo *WebhookRepository) FindSubscriptionsByEvent(eventType string) ([]entities.WebhookSubscription, error) {
+	var subscriptions []entities.WebhookSubscription
+	result := repo.dbContext.Debug().
+		Where("active AND (? = ANY(event_types) OR '*' = ANY(event_types))", eventType).
+		Find(&subscriptions)
+	return subscriptions, result.Error
+}
+
+func (repo *WebhookRepository) CreateSubscription(subscription *entities.WebhookSubscription) (entities.WebhookSubscription, error) {
+	result := repo.dbContext.Debug().Create(subscription)
+	return *subscription, result.Error
+}
+
+// DeleteSubscription removes the subscription together with its delivery history
+func (repo *WebhookRepository) DeleteSubscription(id uint) error {
+	result := repo.dbContext.Debug().Delete(&entities.WebhookSubscription{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return result.Error
+}
+
+func (repo *WebhookRepository) CreateDelivery(delivery *entities.WebhookDelivery) (entities.WebhookDelivery, error) {
+	result := repo.dbContext.Debug().Create(delivery)
+	return *delivery, result.Error
+}
+
+func (repo *WebhookRepository) FindDelivery(id uint) (entities.WebhookDelivery, error) {
+	delivery := entities.WebhookDelivery{}
+	result := repo.dbContext.Debug().Find(&delivery, "id = ?", id)
+	if result.Error == nil && delivery.Id == 0 {
+		return delivery, ErrWebhookDeliveryNotFound
+	}
+	return delivery, result.Error
+}
+
+// FindDeliveries returns the delivery history of a subscription, newest first
+func (repo *WebhookRepository) FindDeliveries(subscriptionId uint, page, pageSize int) ([]entities.WebhookDelivery, error) {
+	deliveries := []entities.WebhookDelivery{}
+	query := repo.dbContext.Debug().Where("subscription_id = ?", subscriptionId).Order("id DESC")
+	if pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	result := query.Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries that are due and pushes their next attempt back by lease,
+// so other workers skip them while they are being sent. A worker that dies leaves them due again after the lease.
+func (repo *WebhookRepository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]entities.WebhookDelivery, error) {
+	var deliveries []entities.WebhookDelivery
+	result := repo.dbContext.Debug().Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), entities.WebhookDeliveryPending, now, limit).Scan(&deliveries)
+	return deliveries, result.Error
+}
+
+func (repo *WebhookRepository) SaveDelivery(delivery *entities.WebhookDelivery) error {
+	result := repo.dbContext.Debug().Save(delivery)
+	return result.Error
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"acy.com/api/src/controllers"
+	"acy.com/api/src/db"
+	"acy.com/api/src/dependencies"
 	libs "acy.com/api/src/lib"
 	"acy.com/api/src/middlewares"
+	"acy.com/api/src/services"
 	
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -28,10 +34,47 @@ import (
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 // @BasePath  /api/v1
 // @query.collection.format multi
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the JWT.
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	logger := libs.NewZapLogger()
+	// migrations and new albums are priced in it, a wrong one fails here rather than on the first album
+	libs.DefaultCurrency()
+
+	if err := db.MigratePostgres(db.PostgresDbProvider()); err != nil {
+		panic(err.Error())
+	}
+	if err := db.MigrateMongo(db.GetMongoDb()); err != nil {
+		panic(err.Error())
+	}
+
 	r := gin.New() // disable default router and some common middleware
-	r.Use(middlewares.GinLogger(logger), middlewares.GinRecovery(logger, true))
+	redactor := libs.RedactorProvider()
+	r.Use(middlewares.RequestId(), middlewares.GinLogger(logger, redactor), middlewares.GinRecovery(logger, true, redactor))
+
+	bodyLimits := middlewares.BodyLimits{
+		Default: middlewares.DefaultMaxBodyBytesProvider(),
+		Routes: map[string]int64{
+			"POST /api/v1/albums/batch": 8 << 20,
+			// room for the multipart framing around the image
+			"PUT /api/v1/albums/:id/cover":   services.CoverConfigProvider().MaxBytes + 1<<20,
+			"PUT /api/v1/albums/:id/content": libs.ContentStorageProvider().MaxBytes,
+		},
+		// content is stored while it is received rather than read into memory first
+		Streamed: map[string]bool{"PUT /api/v1/albums/:id/content": true},
+	}
+	r.Use(
+		middlewares.Cors(middlewares.CorsConfigProvider()),
+		middlewares.SecurityHeaders(middlewares.SecurityHeadersProvider()),
+		middlewares.MaxBodySize(bodyLimits),
+	)
 
 	r.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Hello world!")
@@ -43,19 +86,121 @@ func main() {
 		URL: "http://localhost:3000/swagger/doc.json", //The url pointing to API definition
 	}
 
+	// swagger ui runs inline scripts and styles, which the api content security policy blocks
+	swaggerHeaders := middlewares.SecurityHeadersProvider()
+	swaggerHeaders.ContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:"
+	swaggerHeaders.FrameOptions = "SAMEORIGIN"
+
 	// docs route
-	r.GET("/swagger/*any", ginSwagger.CustomWrapHandler(config, swaggerFiles.Handler))
+	r.GET("/swagger/*any", middlewares.SecurityHeaders(swaggerHeaders), ginSwagger.CustomWrapHandler(config, swaggerFiles.Handler))
+
+	keySet := libs.JwtKeySetProvider()
+	apiKeyService := dependencies.InitializeApiKeyService()
+
+	rateLimitPolicies := middlewares.RateLimitPolicies{
+		Default: libs.RateLimitPolicy{Name: "default", Limit: 120, Period: time.Minute},
+		Routes: map[string]libs.RateLimitPolicy{
+			// GetAlbums reads the whole mongo collection
+			"GET /api/v1/albums/":       {Name: "albums-list", Limit: 30, Period: time.Minute},
+			"GET /api/v1/albums/facets": {Name: "albums-facets", Limit: 30, Period: time.Minute},
+			"POST /api/v1/albums/batch": {Name: "albums-batch", Limit: 10, Period: time.Minute},
+			"GET /api/v1/search":        {Name: "search", Limit: 60, Period: time.Minute},
+			// a search box asks for suggestions as one types
+			"GET /api/v1/albums/suggest": {Name: "albums-suggest", Limit: 300, Period: time.Minute},
+			"GET /api/v1/stats":          {Name: "stats", Limit: 30, Period: time.Minute},
+		},
+	}
 
-	v1 := r.Group("/api/v1")
+	rateLimitStore := libs.RateLimitStoreProvider()
+	// every ip gets a budget above the per route policies, it only stops clients hammering authentication
+	ipRateLimitPolicy := libs.RateLimitPolicy{Name: "ip", Limit: 600, Period: time.Minute}
+
+	v1 := r.Group("/api/v1",
+		middlewares.IpRateLimit(rateLimitStore, ipRateLimitPolicy, logger),
+		middlewares.Authenticate(keySet, apiKeyService),
+		middlewares.RateLimit(rateLimitStore, rateLimitPolicies, logger),
+	)
 	{
 		albums := v1.Group("/albums")
 
-		albums.GET("/", controllers.GetAlbums)
-		albums.GET("/:id", controllers.GetAlbumById)
-		albums.POST("/", controllers.CreateAlbum)
-		albums.DELETE("/:id", controllers.DeleteAlbumById)
+		albums.GET("/", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbums)
+		albums.GET("/events", middlewares.RequireRole(middlewares.RoleReader), controllers.StreamAlbumEvents)
+		albums.GET("/facets", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumFacets)
+		albums.GET("/suggest", middlewares.RequireRole(middlewares.RoleReader), controllers.SuggestAlbums)
+		albums.GET("/:id", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumById)
+		albums.POST("/", middlewares.RequireRole(middlewares.RoleEditor), controllers.CreateAlbum)
+		albums.POST("/batch", middlewares.RequireRole(middlewares.RoleEditor), controllers.BatchAlbums)
+		albums.PUT("/:id", middlewares.RequireRole(middlewares.RoleEditor), controllers.UpdateAlbum)
+		albums.DELETE("/:id", middlewares.RequireRole(middlewares.RoleAdmin), controllers.DeleteAlbumById)
+		albums.POST("/:id/read", middlewares.RequireRole(middlewares.RoleReader), controllers.MarkAlbumRead)
+		albums.DELETE("/:id/read", middlewares.RequireRole(middlewares.RoleReader), controllers.MarkAlbumUnread)
+		albums.GET("/:id/tracks", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumTracks)
+		albums.GET("/:id/tracks/:trackId", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumTrackById)
+		albums.POST("/:id/tracks", middlewares.RequireRole(middlewares.RoleEditor), controllers.CreateAlbumTrack)
+		albums.PUT("/:id/tracks/:trackId", middlewares.RequireRole(middlewares.RoleEditor), controllers.UpdateAlbumTrack)
+		albums.DELETE("/:id/tracks/:trackId", middlewares.RequireRole(middlewares.RoleEditor), controllers.DeleteAlbumTrack)
+		albums.GET("/:id/reviews", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumReviews)
+		albums.POST("/:id/reviews", middlewares.RequireRole(middlewares.RoleReader), controllers.CreateAlbumReview)
+		albums.PUT("/:id/reviews/:reviewId", middlewares.RequireRole(middlewares.RoleReader), controllers.UpdateAlbumReview)
+		albums.DELETE("/:id/reviews/:reviewId", middlewares.RequireRole(middlewares.RoleReader), controllers.DeleteAlbumReview)
+		albums.GET("/:id/content", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumContent)
+		albums.PUT("/:id/content", middlewares.RequireRole(middlewares.RoleEditor), controllers.PutAlbumContent)
+		albums.GET("/:id/content/revisions", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumContentRevisions)
+		albums.GET("/:id/content/revisions/:rev", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumContentRevision)
+		albums.GET("/:id/content/revisions/:rev/diff", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumContentDiff)
+		albums.POST("/:id/content/revisions/:rev/restore", middlewares.RequireRole(middlewares.RoleEditor), controllers.RestoreAlbumContentRevision)
+		albums.PUT("/:id/cover", middlewares.RequireRole(middlewares.RoleEditor), controllers.PutAlbumCover)
+		albums.GET("/:id/cover", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumCover)
+		albums.DELETE("/:id/cover", middlewares.RequireRole(middlewares.RoleEditor), controllers.DeleteAlbumCover)
+		albums.GET("/:id/prices", middlewares.RequireRole(middlewares.RoleReader), controllers.GetAlbumPrices)
+		albums.POST("/:id/prices", middlewares.RequireRole(middlewares.RoleEditor), controllers.ScheduleAlbumPrice)
+		albums.DELETE("/:id/prices/:priceId", middlewares.RequireRole(middlewares.RoleEditor), controllers.CancelAlbumPrice)
+
+		artists := v1.Group("/artists")
+
+		artists.GET("/", middlewares.RequireRole(middlewares.RoleReader), controllers.GetArtists)
+		artists.GET("/:id", middlewares.RequireRole(middlewares.RoleReader), controllers.GetArtistById)
+		artists.GET("/:id/albums", middlewares.RequireRole(middlewares.RoleReader), controllers.GetArtistAlbums)
+		artists.POST("/", middlewares.RequireRole(middlewares.RoleEditor), controllers.CreateArtist)
+		artists.PUT("/:id", middlewares.RequireRole(middlewares.RoleEditor), controllers.UpdateArtist)
+		artists.DELETE("/:id", middlewares.RequireRole(middlewares.RoleAdmin), controllers.DeleteArtistById)
+
+		genres := v1.Group("/genres")
+
+		genres.GET("/", middlewares.RequireRole(middlewares.RoleReader), controllers.GetGenres)
+		genres.POST("/", middlewares.RequireRole(middlewares.RoleEditor), controllers.CreateGenre)
+		genres.DELETE("/:slug", middlewares.RequireRole(middlewares.RoleAdmin), controllers.DeleteGenre)
+
+		v1.GET("/search", middlewares.RequireRole(middlewares.RoleReader), controllers.Search)
+
+		v1.GET("/stats", middlewares.RequireRole(middlewares.RoleReader), controllers.GetStats)
+
+		v1.GET("/exchange-rates", middlewares.RequireRole(middlewares.RoleReader), controllers.GetExchangeRates)
+
+		admin := v1.Group("/admin", middlewares.RequireRole(middlewares.RoleAdmin))
+
+		admin.GET("/api-keys", controllers.GetApiKeys)
+		admin.POST("/api-keys", controllers.CreateApiKey)
+		admin.DELETE("/api-keys/:id", controllers.RevokeApiKey)
+		admin.GET("/audit", controllers.GetAuditEntries)
+		admin.GET("/audit/export", controllers.ExportAuditEntries)
+		admin.PUT("/exchange-rates", controllers.ReplaceExchangeRates)
+		admin.GET("/webhooks", controllers.GetWebhooks)
+		admin.POST("/webhooks", controllers.CreateWebhook)
+		admin.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", controllers.GetWebhookDeliveries)
+		admin.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", controllers.RedeliverWebhook)
 	}
 
+	if path := services.ExchangeRatesFileProvider(); path != "" {
+		if err := (*dependencies.InitializeExchangeRateService()).Import(path, "file:"+path); err != nil {
+			logger.Error("exchange rates import failed", zap.String("file", path), zap.String("error", err.Error()))
+		}
+	}
+
+	go services.RunWebhookWorker(context.Background(), dependencies.InitializeWebhookService(), libs.EventBusProvider(), services.WebhookConfigProvider(), logger)
+	go services.RunPriceScheduler(context.Background(), dependencies.InitializePriceService(), services.PriceSchedulerConfigProvider(), logger)
+
 	err := r.Run(":3000")
 
 	if err != nil {
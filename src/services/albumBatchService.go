@@ -0,0 +1,438 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+type IAlbumBatchService interface {
+	Execute(batch *models.BatchAlbumDto, actor AuditActor) models.BatchAlbumResponse
+}
+
+type albumBatchService struct {
+	repo      *repositories.IAlbumRepository
+	mongoRepo *repositories.IAlbumMongoDBRepository
+	artists   *IArtistService
+	genres    *IGenreService
+	audit     *IAuditService
+	revisions *IContentRevisionService
+	reviews   *IReviewService
+	covers    *ICoverService
+	bus       *libs.EventBus
+	logger    *zap.Logger
+}
+
+var errBatchAborted = errors.New("batch aborted")
+
+// AlbumBatchService constructor
+func AlbumBatchService(repo *repositories.IAlbumRepository, mongoRepo *repositories.IAlbumMongoDBRepository, artists *IArtistService, genres *IGenreService, audit *IAuditService, revisions *IContentRevisionService, reviews *IReviewService, covers *ICoverService, bus *libs.EventBus) *albumBatchService {
+	return &albumBatchService{repo: repo, mongoRepo: mongoRepo, artists: artists, genres: genres, audit: audit, revisions: revisions, reviews: reviews, covers: covers, bus: bus, logger: libs.NewZapLogger()}
+}
+
+/*** interface implementations ***/
+
+// Execute applies every operation of the batch inside a single postgres transaction and sends the
+// content changes to mongodb as one bulk write before committing.
+// In all_or_nothing mode the first failure rolls the whole batch back, in best_effort mode every
+// operation runs in its own savepoint so a failure only discards that operation. A delete, which cascades to the
+// rows of the album, deletes its content before its savepoint is released so that a failure rolls them back too.
+// Every applied operation is recorded in the audit log within the transaction, and gets a content revision and
+// an event on the event bus once the batch is committed.
+func (service *albumBatchService) Execute(batch *models.BatchAlbumDto, actor AuditActor) models.BatchAlbumResponse {
+	mode := batch.Mode
+	if mode == "" {
+		mode = models.BatchModeAllOrNothing
+	}
+	allOrNothing := mode == models.BatchModeAllOrNothing
+
+	results := make([]models.BatchAlbumResultResponse, len(batch.Operations))
+	genres := make([][]entities.Genre, len(batch.Operations))
+	invalid := false
+	for i, operation := range batch.Operations {
+		results[i] = models.BatchAlbumResultResponse{Index: i, Op: operation.Op, Id: operation.Id}
+		if err := validateBatchOperation(&operation); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		if err := service.resolveArtist(&operation, actor); err != nil {
+			results[i].Status = http.StatusInternalServerError
+			if errors.Is(err, repositories.ErrArtistNotFound) {
+				results[i].Status = http.StatusNotFound
+			}
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		var err error
+		if genres[i], err = service.resolveGenres(&operation); err != nil {
+			results[i].Status = http.StatusInternalServerError
+			if errors.Is(err, repositories.ErrGenreNotFound) {
+				results[i].Status = http.StatusBadRequest
+			}
+			results[i].Error = err.Error()
+			invalid = true
+		}
+	}
+
+	if allOrNothing && invalid {
+		return batchResponse(mode, abortBatch(batch, results))
+	}
+
+	repo, mongoRepo := (*service.repo).WithActor(actor.Actor), (*service.mongoRepo).WithActor(actor.Actor)
+	previousContent := service.previousContent(batch, results)
+	var writes []repositories.AlbumMongoDBWrite
+	var writeIndexes []int
+	var bulk repositories.AlbumMongoDBBulkResult
+	previous := map[int]entities.Album{}
+	written := map[int]entities.Album{}
+	// deletes of best_effort batches write their content on their own, contentDeleted tells which operations did
+	var contentDeletes []repositories.AlbumMongoDBBulkResult
+	contentDeleted := map[int]bool{}
+
+	err := repo.Transaction(func(txRepo repositories.IAlbumRepository) error {
+
+		for i, operation := range batch.Operations {
+			if results[i].Status != 0 {
+				continue
+			}
+
+			var album, before entities.Album
+			apply := func(repo repositories.IAlbumRepository) error {
+				var err error
+				album, before, err = applyBatchOperation(repo, &operation, genres[i])
+				return err
+			}
+
+			var err, contentErr error
+			if allOrNothing {
+				err = apply(txRepo)
+			} else {
+				err = txRepo.Transaction(func(savepointRepo repositories.IAlbumRepository) error {
+					if err := apply(savepointRepo); err != nil || operation.Op != models.BatchOpDelete {
+						return err
+					}
+					// the delete cascades to the tracks, prices, reads and covers of the album, its content is deleted
+					// before the savepoint is released so that a failure rolls them back with the row
+					var deleted repositories.AlbumMongoDBBulkResult
+					if deleted, contentErr = deleteContent(mongoRepo, contentWrite(&operation, album)); contentErr != nil {
+						return contentErr
+					}
+					contentDeletes = append(contentDeletes, deleted)
+					contentDeleted[i] = true
+					return nil
+				})
+			}
+
+			if err != nil {
+				results[i].Status = http.StatusInternalServerError
+				if errors.Is(err, repositories.ErrAlbumNotFound) {
+					results[i].Status = http.StatusNotFound
+				}
+				results[i].Error = err.Error()
+				if contentErr != nil {
+					results[i].Status = http.StatusBadGateway
+					results[i].Error = fmt.Sprintf("unable to save content to db: %s", contentErr.Error())
+				}
+				if allOrNothing {
+					return errBatchAborted
+				}
+				continue
+			}
+
+			results[i].Id = album.Id
+			results[i].Status = batchOperationStatus(operation.Op)
+			previous[i] = before
+			written[i] = album
+			writes = append(writes, contentWrite(&operation, album))
+			writeIndexes = append(writeIndexes, i)
+		}
+
+		// the content of every other operation goes in one bulk write
+		var pending []repositories.AlbumMongoDBWrite
+		var pendingIndexes []int
+		for w, i := range writeIndexes {
+			if !contentDeleted[i] {
+				pending = append(pending, writes[w])
+				pendingIndexes = append(pendingIndexes, i)
+			}
+		}
+		var err error
+		bulk, err = mongoRepo.BulkWrite(pending, allOrNothing)
+		if err != nil {
+			for _, i := range pendingIndexes {
+				results[i].Status = http.StatusBadGateway
+				results[i].Error = fmt.Sprintf("unable to save content to db: %s", err.Error())
+			}
+			return err
+		}
+
+		if allOrNothing && len(bulk.Failed) > 0 {
+			for p, i := range pendingIndexes {
+				if writeErr, ok := bulk.Failed[p]; ok {
+					results[i].Status = http.StatusBadGateway
+					results[i].Error = fmt.Sprintf("unable to save content to db: %s", writeErr.Error())
+				}
+			}
+			return errBatchAborted
+		}
+
+		for p, writeErr := range bulk.Failed {
+			i := pendingIndexes[p]
+			results[i].Status = http.StatusBadGateway
+			results[i].Error = fmt.Sprintf("unable to save content to db: %s", writeErr.Error())
+			if err := revertBatchOperation(txRepo, &batch.Operations[i], results[i].Id, previous[i]); err != nil {
+				return err
+			}
+		}
+
+		// the audit entries commit with the batch, a batch that can not be audited is not applied
+		audit := (*service.audit).WithRepository(txRepo.Audits())
+		for w, i := range writeIndexes {
+			if results[i].Status >= http.StatusBadRequest {
+				continue
+			}
+			if err := recordOperation(audit, actor, &batch.Operations[i], previous[i], previousContent[previous[i].Id], writes[w].Album, written[i]); err != nil {
+				for _, j := range writeIndexes {
+					if results[j].Status < http.StatusBadRequest {
+						results[j].Status = http.StatusInternalServerError
+						results[j].Error = fmt.Sprintf("unable to write the audit log: %s", err.Error())
+					}
+				}
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		// postgres is rolled back, the content documents are put back as they were before the batch
+		for _, result := range append(contentDeletes, bulk) {
+			if revertErr := mongoRepo.Revert(result); revertErr != nil {
+				for _, i := range writeIndexes {
+					results[i].Status = http.StatusInternalServerError
+					results[i].Error = fmt.Sprintf("batch rolled back but its content could not be restored: %s", revertErr.Error())
+				}
+			}
+		}
+		return batchResponse(mode, abortBatch(batch, results))
+	}
+	// the batch is applied, a failure can only leave content files of the replaced documents behind
+	for _, result := range append(contentDeletes, bulk) {
+		mongoRepo.Release(result)
+	}
+
+	for w, i := range writeIndexes {
+		if results[i].Status < http.StatusBadRequest {
+			if writes[w].Op != repositories.AlbumMongoDBWriteDelete {
+				// the batch is committed, a missing revision leaves a gap in the history but not in the content
+				if err := (*service.revisions).Record(previousContent[previous[i].Id], writes[w].Album, actor.Actor); err != nil {
+					service.logger.Error("unable to record the content revision of a batch operation",
+						zap.Int("index", i),
+						zap.Uint("album_id", results[i].Id),
+						zap.String("error", err.Error()),
+					)
+				}
+			} else {
+				deleteReviews(*service.reviews, results[i].Id, service.logger)
+				deleteCoverImages(*service.covers, results[i].Id, service.logger)
+			}
+			service.publishOperation(&batch.Operations[i], written[i])
+		}
+	}
+	return batchResponse(mode, results)
+}
+
+func (service *albumBatchService) publishOperation(operation *models.BatchAlbumOperationDto, album entities.Album) {
+	switch operation.Op {
+	case models.BatchOpCreate:
+		service.bus.Publish(models.AlbumEventCreated, models.AlbumEventResponse{AlbumId: album.Id, Album: &album})
+	case models.BatchOpUpdate:
+		service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: album.Id, Album: &album})
+	default:
+		service.bus.Publish(models.AlbumEventDeleted, models.AlbumEventResponse{AlbumId: album.Id})
+	}
+}
+
+// previousContent loads the content documents that update and delete operations are about to replace
+func (service *albumBatchService) previousContent(batch *models.BatchAlbumDto, results []models.BatchAlbumResultResponse) map[uint]entities.AlbumMongoDB {
+	var albumIds []uint
+	for i, operation := range batch.Operations {
+		if results[i].Status == 0 && operation.Op != models.BatchOpCreate {
+			albumIds = append(albumIds, operation.Id)
+		}
+	}
+
+	contents := map[uint]entities.AlbumMongoDB{}
+	for _, content := range (*service.mongoRepo).FindByAlbumIds(albumIds) {
+		contents[content.AlbumId] = content
+	}
+	return contents
+}
+
+func recordOperation(audit IAuditService, actor AuditActor, operation *models.BatchAlbumOperationDto, before entities.Album, beforeContent entities.AlbumMongoDB, afterContent entities.AlbumMongoDB, after entities.Album) error {
+	var beforeSnapshot, afterSnapshot *AlbumSnapshot
+	if operation.Op != models.BatchOpCreate {
+		beforeSnapshot = &AlbumSnapshot{Album: before}
+		if beforeContent.AlbumId != 0 {
+			beforeSnapshot.Content = &beforeContent
+		}
+	}
+	if operation.Op != models.BatchOpDelete {
+		if operation.Op == models.BatchOpUpdate {
+			// updates upsert by album id and keep the document id
+			afterContent.ID = beforeContent.ID
+		}
+		afterSnapshot = &AlbumSnapshot{Album: after, Content: &afterContent}
+	}
+	return audit.RecordAlbum(actor, operation.Op, beforeSnapshot, afterSnapshot)
+}
+
+// resolveArtist replaces the artist name of a create or update by the id of the artist it refers to.
+// Artists are created outside of the batch transaction, an artist created for a rolled back batch is kept.
+func (service *albumBatchService) resolveArtist(operation *models.BatchAlbumOperationDto, actor AuditActor) error {
+	if operation.Album == nil {
+		return nil
+	}
+	artist, err := (*service.artists).Resolve(operation.Album.ArtistId, operation.Album.Artist, actor.Actor)
+	operation.Album.ArtistId = artist.Id
+	return err
+}
+
+// resolveGenres looks up the genres of a create or update by their slugs
+func (service *albumBatchService) resolveGenres(operation *models.BatchAlbumOperationDto) ([]entities.Genre, error) {
+	if operation.Album == nil {
+		return nil, nil
+	}
+	return (*service.genres).FindBySlugs(operation.Album.Genres)
+}
+
+func validateBatchOperation(operation *models.BatchAlbumOperationDto) error {
+	if err := binding.Validator.ValidateStruct(operation); err != nil {
+		return err
+	}
+	if operation.Op != models.BatchOpDelete && operation.Album == nil {
+		return fmt.Errorf("album is required for %s", operation.Op)
+	}
+	if operation.Op != models.BatchOpCreate && operation.Id == 0 {
+		return fmt.Errorf("id is required for %s", operation.Op)
+	}
+	if operation.Album == nil {
+		return nil
+	}
+	if len(operation.Album.Tracks) > 0 {
+		return errors.New("tracks are not supported in batches, use /albums/{id}/tracks")
+	}
+	if operation.Album.Currency == "" {
+		operation.Album.Currency = libs.DefaultCurrency()
+	}
+	return libs.ValidatePrice(*operation.Album.Price, operation.Album.Currency)
+}
+
+// applyBatchOperation returns the album as written and, for update and delete, the row it replaced
+func applyBatchOperation(repo repositories.IAlbumRepository, operation *models.BatchAlbumOperationDto, genres []entities.Genre) (entities.Album, entities.Album, error) {
+	if operation.Op == models.BatchOpCreate {
+		album := entities.Album{Title: operation.Album.Title, ArtistId: operation.Album.ArtistId, Price: *operation.Album.Price, Currency: operation.Album.Currency, Genres: genres, Tags: operation.Album.Tags}
+		album, err := repo.Create(&album)
+		return album, entities.Album{}, err
+	}
+
+	before, err := repo.FindById(operation.Id)
+	if err != nil {
+		return before, before, err
+	}
+	if before.Id == 0 {
+		return before, before, repositories.ErrAlbumNotFound
+	}
+
+	if operation.Op == models.BatchOpDelete {
+		return before, before, repo.Delete(before.Id)
+	}
+
+	album := before
+	album.Title, album.ArtistId, album.Artist = operation.Album.Title, operation.Album.ArtistId, nil
+	album.Price, album.Currency = *operation.Album.Price, operation.Album.Currency
+	album.Genres, album.Tags = genres, operation.Album.Tags
+	album, err = repo.Save(&album)
+	return album, before, err
+}
+
+// revertBatchOperation undoes a create or an update whose content write failed in best_effort mode, deletes are
+// rolled back to their savepoint instead
+func revertBatchOperation(repo repositories.IAlbumRepository, operation *models.BatchAlbumOperationDto, id uint, before entities.Album) error {
+	if operation.Op == models.BatchOpCreate {
+		return repo.Delete(id)
+	}
+	_, err := repo.Save(&before)
+	return err
+}
+
+// deleteContent deletes the content document of a deleted album in a bulk write of its own. A delete that may have
+// been applied when the write failed is reverted, the album stays and so does its content.
+func deleteContent(mongoRepo repositories.IAlbumMongoDBRepository, write repositories.AlbumMongoDBWrite) (repositories.AlbumMongoDBBulkResult, error) {
+	result, err := mongoRepo.BulkWrite([]repositories.AlbumMongoDBWrite{write}, true)
+	if err != nil {
+		if revertErr := mongoRepo.Revert(result); revertErr != nil {
+			return result, fmt.Errorf("%w, and the content could not be restored: %s", err, revertErr.Error())
+		}
+		return result, err
+	}
+	return result, result.Failed[0]
+}
+
+func contentWrite(operation *models.BatchAlbumOperationDto, album entities.Album) repositories.AlbumMongoDBWrite {
+	content := entities.AlbumMongoDB{AlbumId: album.Id}
+	if operation.Album != nil {
+		content.Name = operation.Album.Title
+		content.Content = operation.Album.Content
+	}
+	if operation.Op == models.BatchOpCreate {
+		content.ID = primitive.NewObjectID()
+	}
+	return repositories.AlbumMongoDBWrite{Op: operation.Op, Album: content}
+}
+
+func batchOperationStatus(op string) int {
+	switch op {
+	case models.BatchOpCreate:
+		return http.StatusCreated
+	case models.BatchOpDelete:
+		return http.StatusAccepted
+	default:
+		return http.StatusOK
+	}
+}
+
+// abortBatch marks every operation that did not fail itself as not applied
+func abortBatch(batch *models.BatchAlbumDto, results []models.BatchAlbumResultResponse) []models.BatchAlbumResultResponse {
+	for i := range results {
+		if results[i].Status < http.StatusBadRequest {
+			results[i].Id = batch.Operations[i].Id
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "not applied, another operation in the batch failed"
+		}
+	}
+	return results
+}
+
+func batchResponse(mode string, results []models.BatchAlbumResultResponse) models.BatchAlbumResponse {
+	response := models.BatchAlbumResponse{Mode: mode, Results: results}
+	for _, result := range results {
+		if result.Status < http.StatusBadRequest {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	return response
+}
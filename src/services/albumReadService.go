@@ -0,0 +1,54 @@
+package services
+
+import (
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+)
+
+type IAlbumReadService interface {
+	ReadState(userId string, albumIds []uint) (map[uint]time.Time, error)
+	MarkRead(userId string, albumId uint) (entities.UserAlbumRead, error)
+	MarkUnread(userId string, albumId uint) error
+}
+
+type albumReadService struct {
+	repo *repositories.IUserAlbumReadRepository
+	bus  *libs.EventBus
+}
+
+// AlbumReadService constructor
+func AlbumReadService(repo *repositories.IUserAlbumReadRepository, bus *libs.EventBus) *albumReadService {
+	return &albumReadService{repo: repo, bus: bus}
+}
+
+/*** interface implementations ***/
+
+// ReadState returns when userId read each of albumIds, albums the user has not read are left out
+func (service *albumReadService) ReadState(userId string, albumIds []uint) (map[uint]time.Time, error) {
+	state := map[uint]time.Time{}
+	if userId == "" {
+		return state, nil
+	}
+
+	reads, err := (*service.repo).FindByUser(userId, albumIds)
+	for _, read := range reads {
+		state[read.AlbumId] = read.ReadAt
+	}
+	return state, err
+}
+
+func (service *albumReadService) MarkRead(userId string, albumId uint) (entities.UserAlbumRead, error) {
+	read, err := (*service.repo).Upsert(&entities.UserAlbumRead{UserId: userId, AlbumId: albumId, ReadAt: time.Now()})
+	if err == nil {
+		service.bus.Publish(models.AlbumEventRead, models.AlbumEventResponse{AlbumId: albumId, UserId: userId, ReadAt: &read.ReadAt})
+	}
+	return read, err
+}
+
+func (service *albumReadService) MarkUnread(userId string, albumId uint) error {
+	return (*service.repo).Delete(userId, albumId)
+}
@@ -1,24 +1,32 @@
 package services
 
 import (
+	"io"
+
 	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
 	"acy.com/api/src/repositories"
 )
 
 type IAlbumMongoService interface {
 	FindAll() []entities.AlbumMongoDB
 	FindById(id uint) entities.AlbumMongoDB
-	Create(newAlbum *entities.AlbumMongoDB) string
+	FindByAlbumIds(albumIds []uint) []entities.AlbumMongoDB
+	Create(newAlbum *entities.AlbumMongoDB, actor string) string
 	Delete(id uint) bool
+	OpenContent(albumId uint, format string) (io.ReadSeekCloser, entities.AlbumMongoDB, error)
+	WriteContent(album *entities.AlbumMongoDB, reader io.Reader, actor string) error
 }
 
 type albumMongoService struct {
 	repo *repositories.IAlbumMongoDBRepository
+	bus  *libs.EventBus
 }
 
 // AlbumMongoService constructor
-func AlbumMongoService(repo *repositories.IAlbumMongoDBRepository) *albumMongoService {
-	return &albumMongoService{repo: repo}
+func AlbumMongoService(repo *repositories.IAlbumMongoDBRepository, bus *libs.EventBus) *albumMongoService {
+	return &albumMongoService{repo: repo, bus: bus}
 }
 
 /*** interface implementations ***/
@@ -31,10 +39,39 @@ func (service *albumMongoService) FindById(id uint) entities.AlbumMongoDB {
 	return (*service.repo).FindById(id)
 }
 
-func (service *albumMongoService) Create(newAlbum *entities.AlbumMongoDB) string {
-	return (*service.repo).Create(newAlbum)
+func (service *albumMongoService) FindByAlbumIds(albumIds []uint) []entities.AlbumMongoDB {
+	return (*service.repo).FindByAlbumIds(albumIds)
+}
+
+func (service *albumMongoService) Create(newAlbum *entities.AlbumMongoDB, actor string) string {
+	return (*service.repo).WithActor(actor).Create(newAlbum)
 }
 
 func (service *albumMongoService) Delete(id uint) bool {
 	return (*service.repo).Delete(id)
 }
+
+// OpenContent opens the content of an album in a content format for reading from any offset, the document is
+// returned without its content. Plain text is made from the html as it is read when it is asked for.
+func (service *albumMongoService) OpenContent(albumId uint, format string) (io.ReadSeekCloser, entities.AlbumMongoDB, error) {
+	if format == libs.ContentFormatMarkdown {
+		return (*service.repo).OpenContent(albumId, false)
+	}
+	content, album, err := (*service.repo).OpenContent(albumId, true)
+	if err != nil || format == libs.ContentFormatHtml {
+		return content, album, err
+	}
+
+	text, err := libs.OpenContentText(content)
+	return text, album, err
+}
+
+// WriteContent streams the content of an album from reader, album holds the album id and name and is filled with
+// what was written
+func (service *albumMongoService) WriteContent(album *entities.AlbumMongoDB, reader io.Reader, actor string) error {
+	if err := (*service.repo).WithActor(actor).WriteContent(album, reader); err != nil {
+		return err
+	}
+	service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: album.AlbumId})
+	return nil
+}
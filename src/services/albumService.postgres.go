@@ -1,47 +1,190 @@
 package services
 
 import (
+	"errors"
+
 	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
 	"acy.com/api/src/repositories"
+	"go.uber.org/zap"
 )
 
 type IAlbumService interface {
-	FindAll(page, pageSize int) ([]entities.Album, error)
+	FindAll(filter repositories.AlbumFilter, page, pageSize int) ([]entities.Album, error)
 	FindById(id uint) (entities.Album, error)
-	Create(newAlbum *entities.Album) (entities.Album, error)
-	Delete(id uint) error
+	Facets(filter repositories.AlbumFilter) (repositories.AlbumFacets, error)
+	Create(newAlbum *entities.Album, content *entities.AlbumMongoDB, actor AuditActor) (entities.Album, error)
+	Update(album *entities.Album, actor AuditActor) (entities.Album, error)
+	ContentWritten(id uint, before, after *entities.AlbumMongoDB, action string, actor AuditActor) (entities.Album, error)
+	Delete(id uint, actor AuditActor) error
 }
 
+var ErrAlbumContentNotSaved = errors.New("Unable to save content to db")
+
 type albumService struct {
-	repo *repositories.IAlbumRepository
+	repo      *repositories.IAlbumRepository
+	mongoRepo *repositories.IAlbumMongoDBRepository
+	reviews   *IReviewService
+	covers    *ICoverService
+	audit     *IAuditService
+	bus       *libs.EventBus
+	logger    *zap.Logger
 }
 
 // AlbumService constructor
-func AlbumService(repo *repositories.IAlbumRepository) *albumService {
-	return &albumService{repo: repo}
+func AlbumService(repo *repositories.IAlbumRepository, mongoRepo *repositories.IAlbumMongoDBRepository, reviews *IReviewService, covers *ICoverService, audit *IAuditService, bus *libs.EventBus) *albumService {
+	return &albumService{repo: repo, mongoRepo: mongoRepo, reviews: reviews, covers: covers, audit: audit, bus: bus, logger: libs.NewZapLogger()}
 }
 
 /*** interface implementations ***/
 
-func (service *albumService) FindAll(page, pageSize int) ([]entities.Album, error) {
-	albums, err := (*service.repo).FindAll(page, pageSize)
+func (service *albumService) FindAll(filter repositories.AlbumFilter, page, pageSize int) ([]entities.Album, error) {
+	albums, err := (*service.repo).FindAll(filter, page, pageSize)
 	return albums, err
 }
 
 func (service *albumService) FindById(id uint) (entities.Album, error) {
 	albumInDb, err := (*service.repo).FindById(id)
-	if !albumInDb.HasRead {
-		(*service.repo).Update(albumInDb.Id, "has_read", true)
-	}
 	return albumInDb, err
 }
 
-func (service *albumService) Create(newAlbum *entities.Album) (entities.Album, error) {
-	album, err := (*service.repo).Create(newAlbum)
-	return album, err
+func (service *albumService) Facets(filter repositories.AlbumFilter) (repositories.AlbumFacets, error) {
+	return (*service.repo).Facets(filter)
+}
+
+// Create writes the album with its track listing and its content document and records the audit entry in the
+// same transaction, the content document is removed again when the transaction does not commit
+func (service *albumService) Create(newAlbum *entities.Album, content *entities.AlbumMongoDB, actor AuditActor) (entities.Album, error) {
+	repo, mongoRepo := (*service.repo).WithActor(actor.Actor), (*service.mongoRepo).WithActor(actor.Actor)
+	var album entities.Album
+	tracks := newAlbum.Tracks
+	contentSaved := false
+	err := repo.Transaction(func(txRepo repositories.IAlbumRepository) error {
+		var err error
+		if album, err = txRepo.Create(newAlbum); err != nil {
+			return err
+		}
+		if len(tracks) > 0 {
+			if album.Tracks, err = insertTrackListing(txRepo.Tracks(), album.Id, tracks, actor.Actor); err != nil {
+				return err
+			}
+		}
+		content.AlbumId = album.Id
+		if mongoRepo.Create(content) == "" {
+			return ErrAlbumContentNotSaved
+		}
+		contentSaved = true
+		return (*service.audit).WithRepository(txRepo.Audits()).RecordAlbum(actor, entities.AuditActionCreate, nil, &AlbumSnapshot{Album: album, Content: content})
+	})
+	if err != nil {
+		if contentSaved {
+			mongoRepo.Delete(album.Id)
+		}
+		return album, err
+	}
+	service.bus.Publish(models.AlbumEventCreated, models.AlbumEventResponse{AlbumId: album.Id, Album: &album})
+	return album, nil
+}
+
+// Update replaces the title, artist, price, genres and tags of the album and records the audit entry in the same
+// transaction
+func (service *albumService) Update(album *entities.Album, actor AuditActor) (entities.Album, error) {
+	var updated entities.Album
+	err := (*service.repo).WithActor(actor.Actor).Transaction(func(txRepo repositories.IAlbumRepository) error {
+		before, err := txRepo.FindById(album.Id)
+		if err != nil {
+			return err
+		}
+		if before.Id == 0 {
+			return repositories.ErrAlbumNotFound
+		}
+
+		changed := before
+		changed.Title, changed.ArtistId, changed.Artist = album.Title, album.ArtistId, nil
+		changed.Price, changed.Currency = album.Price, album.Currency
+		changed.Genres, changed.Tags = album.Genres, album.Tags
+		if updated, err = txRepo.Save(&changed); err != nil {
+			return err
+		}
+		return (*service.audit).WithRepository(txRepo.Audits()).RecordAlbum(actor, entities.AuditActionUpdate, &AlbumSnapshot{Album: before}, &AlbumSnapshot{Album: updated})
+	})
+	if err != nil {
+		return updated, err
+	}
+	service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: updated.Id, Album: &updated})
+	return updated, nil
+}
+
+// ContentWritten marks the album as updated by actor after its content was written and records the audit entry of
+// the write in the same transaction, before is nil when the album had no content. No event is published, the
+// content write publishes its own.
+func (service *albumService) ContentWritten(id uint, before, after *entities.AlbumMongoDB, action string, actor AuditActor) (entities.Album, error) {
+	var touched entities.Album
+	err := (*service.repo).WithActor(actor.Actor).Transaction(func(txRepo repositories.IAlbumRepository) error {
+		album, err := txRepo.FindById(id)
+		if err != nil {
+			return err
+		}
+		if touched, err = txRepo.Touch(id); err != nil {
+			return err
+		}
+		return (*service.audit).WithRepository(txRepo.Audits()).RecordAlbum(actor, action, &AlbumSnapshot{Album: album, Content: before}, &AlbumSnapshot{Album: touched, Content: after})
+	})
+	return touched, err
+}
+
+// Delete removes the album and records what was deleted in the same transaction, the content document is
+// deleted once the transaction committed. It returns ErrAlbumNotFound when there is neither an album nor content.
+func (service *albumService) Delete(id uint, actor AuditActor) error {
+	content := (*service.mongoRepo).FindById(id)
+	err := (*service.repo).WithActor(actor.Actor).Transaction(func(txRepo repositories.IAlbumRepository) error {
+		before, err := txRepo.FindById(id)
+		if err != nil {
+			return err
+		}
+		if before.Id == 0 && content.AlbumId == 0 {
+			return repositories.ErrAlbumNotFound
+		}
+		if err := txRepo.Delete(id); err != nil {
+			return err
+		}
+		snapshot := AlbumSnapshot{Album: before}
+		snapshot.Album.Id = id
+		if content.AlbumId != 0 {
+			snapshot.Content = &content
+		}
+		return (*service.audit).WithRepository(txRepo.Audits()).RecordAlbum(actor, entities.AuditActionDelete, &snapshot, nil)
+	})
+	if err != nil {
+		return err
+	}
+	// the audit entry keeps the content, a document left behind by a failed delete belongs to no album
+	if content.AlbumId != 0 {
+		(*service.mongoRepo).Delete(id)
+	}
+	deleteReviews(*service.reviews, id, service.logger)
+	deleteCoverImages(*service.covers, id, service.logger)
+	service.bus.Publish(models.AlbumEventDeleted, models.AlbumEventResponse{AlbumId: id})
+	return nil
+}
+
+// deleteReviews removes the reviews of a deleted album, the album is gone already so a failure is only logged
+func deleteReviews(reviews IReviewService, albumId uint, logger *zap.Logger) {
+	if err := reviews.DeleteByAlbum(albumId); err != nil {
+		logger.Error("unable to delete the reviews of a deleted album",
+			zap.Uint("album_id", albumId),
+			zap.String("error", err.Error()),
+		)
+	}
 }
 
-func (service *albumService) Delete(id uint) error {
-	err := (*service.repo).Delete(id)
-	return err
+// deleteCoverImages removes the cover images of a deleted album, its cover row is deleted with the album
+func deleteCoverImages(covers ICoverService, albumId uint, logger *zap.Logger) {
+	if err := covers.DeleteImages(albumId); err != nil {
+		logger.Error("unable to delete the cover images of a deleted album",
+			zap.Uint("album_id", albumId),
+			zap.String("error", err.Error()),
+		)
+	}
 }
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+type IApiKeyService interface {
+	FindAll() ([]entities.ApiKey, error)
+	Create(name string, scopes []string, expiresAt *time.Time) (entities.ApiKey, string, error)
+	Revoke(id uint) error
+	Authenticate(key string) (entities.ApiKey, error)
+}
+
+type apiKeyService struct {
+	repo *repositories.IApiKeyRepository
+}
+
+const (
+	apiKeyPrefix = "acy_"
+	// last_used_at is only written once per interval, so an api key does not cost a write on every request
+	apiKeyTouchInterval = time.Minute
+)
+
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+// ApiKeyService constructor
+func ApiKeyService(repo *repositories.IApiKeyRepository) *apiKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+/*** interface implementations ***/
+
+func (service *apiKeyService) FindAll() ([]entities.ApiKey, error) {
+	return (*service.repo).FindAll()
+}
+
+// Create stores a new api key and returns it together with the plain key.
+// Only the sha256 hash is stored, the plain key can not be read again afterwards.
+func (service *apiKeyService) Create(name string, scopes []string, expiresAt *time.Time) (entities.ApiKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return entities.ApiKey{}, "", err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := entities.ApiKey{
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashApiKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	apiKey, err := (*service.repo).Create(&apiKey)
+	return apiKey, key, err
+}
+
+func (service *apiKeyService) Revoke(id uint) error {
+	return (*service.repo).Revoke(id, time.Now())
+}
+
+// Authenticate resolves a plain key to its api key, rejecting unknown, revoked and expired keys
+func (service *apiKeyService) Authenticate(key string) (entities.ApiKey, error) {
+	apiKey, err := (*service.repo).FindByHash(hashApiKey(key))
+	if err != nil {
+		return apiKey, err
+	}
+
+	now := time.Now()
+	if apiKey.Id == 0 || apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+		return entities.ApiKey{}, ErrInvalidApiKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		(*service.repo).TouchLastUsed(apiKey.Id, now)
+		apiKey.LastUsedAt = &now
+	}
+	return apiKey, nil
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
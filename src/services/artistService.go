@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+type IArtistService interface {
+	FindAll(name string, page, pageSize int) ([]entities.Artist, error)
+	FindById(id uint) (entities.Artist, error)
+	Create(name string, actor string) (entities.Artist, error)
+	Update(id uint, name string, actor string) (entities.Artist, error)
+	Delete(id uint) error
+	Resolve(artistId uint, name string, actor string) (entities.Artist, error)
+}
+
+type artistService struct {
+	repo *repositories.IArtistRepository
+}
+
+// ArtistService constructor
+func ArtistService(repo *repositories.IArtistRepository) *artistService {
+	return &artistService{repo: repo}
+}
+
+/*** interface implementations ***/
+
+func (service *artistService) FindAll(name string, page, pageSize int) ([]entities.Artist, error) {
+	return (*service.repo).FindAll(name, page, pageSize)
+}
+
+func (service *artistService) FindById(id uint) (entities.Artist, error) {
+	return (*service.repo).FindById(id)
+}
+
+func (service *artistService) Create(name string, actor string) (entities.Artist, error) {
+	artist := entities.Artist{Name: strings.Join(strings.Fields(name), " "), CreatedBy: actor, UpdatedBy: actor}
+	return (*service.repo).Create(&artist)
+}
+
+func (service *artistService) Update(id uint, name string, actor string) (entities.Artist, error) {
+	artist := entities.Artist{Id: id, Name: strings.Join(strings.Fields(name), " "), UpdatedAt: time.Now(), UpdatedBy: actor}
+	if _, err := (*service.repo).Save(&artist); err != nil {
+		return artist, err
+	}
+	return (*service.repo).FindById(id)
+}
+
+func (service *artistService) Delete(id uint) error {
+	return (*service.repo).Delete(id)
+}
+
+// Resolve returns the artist an album refers to, by id when one is given and by name otherwise.
+// An unknown name creates the artist, spellings that only differ in case or whitespace share one artist.
+func (service *artistService) Resolve(artistId uint, name string, actor string) (entities.Artist, error) {
+	if artistId != 0 {
+		return (*service.repo).FindById(artistId)
+	}
+	return (*service.repo).FindOrCreate(name, actor)
+}
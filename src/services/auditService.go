@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+)
+
+type IAuditService interface {
+	RecordAlbum(actor AuditActor, action string, before, after *AlbumSnapshot) error
+	Find(filter repositories.AuditFilter, page, pageSize int) ([]entities.AuditEntry, error)
+	Export(filter repositories.AuditFilter, fn func(entry entities.AuditEntry) error) error
+	// WithRepository returns a service writing to repo, like the audit repository of an album transaction
+	WithRepository(repo repositories.IAuditRepository) IAuditService
+}
+
+// AuditActor is who made a change and from where
+type AuditActor struct {
+	Actor     string
+	RequestId string
+	SourceIp  string
+}
+
+// AlbumSnapshot is the state of an album across both databases, Content is nil when there is no content document
+type AlbumSnapshot struct {
+	Album   entities.Album
+	Content *entities.AlbumMongoDB
+}
+
+type auditService struct {
+	repo *repositories.IAuditRepository
+}
+
+// AuditService constructor
+func AuditService(repo *repositories.IAuditRepository) *auditService {
+	return &auditService{repo: repo}
+}
+
+/*** interface implementations ***/
+
+// RecordAlbum appends an entry for a change of an album. before is nil for a create, after is nil for a delete.
+func (service *auditService) RecordAlbum(actor AuditActor, action string, before, after *AlbumSnapshot) error {
+	entry := entities.AuditEntry{
+		Entity:    entities.AuditEntityAlbum,
+		Action:    action,
+		Actor:     actor.Actor,
+		RequestId: actor.RequestId,
+		SourceIp:  actor.SourceIp,
+		Before:    albumDocument(before),
+		After:     albumDocument(after),
+		CreatedAt: time.Now(),
+	}
+	if after != nil {
+		entry.EntityId = strconv.FormatUint(uint64(after.Album.Id), 10)
+	} else if before != nil {
+		entry.EntityId = strconv.FormatUint(uint64(before.Album.Id), 10)
+	}
+	entry.Changes = diffDocuments(entry.Before, entry.After)
+
+	return (*service.repo).Create(&entry)
+}
+
+func (service *auditService) Find(filter repositories.AuditFilter, page, pageSize int) ([]entities.AuditEntry, error) {
+	return (*service.repo).Find(filter, page, pageSize)
+}
+
+func (service *auditService) Export(filter repositories.AuditFilter, fn func(entry entities.AuditEntry) error) error {
+	return (*service.repo).Each(filter, fn)
+}
+
+func (service *auditService) WithRepository(repo repositories.IAuditRepository) IAuditService {
+	return &auditService{repo: &repo}
+}
+
+func albumDocument(snapshot *AlbumSnapshot) entities.JSONDocument {
+	if snapshot == nil {
+		return nil
+	}
+	genres := []string{}
+	for _, genre := range snapshot.Album.Genres {
+		genres = append(genres, genre.Slug)
+	}
+	tags := []string{}
+	tags = append(tags, snapshot.Album.Tags...)
+	document := entities.JSONDocument{
+		"album": map[string]interface{}{
+			"id":        snapshot.Album.Id,
+			"title":     snapshot.Album.Title,
+			"artist_id": snapshot.Album.ArtistId,
+			"price":     snapshot.Album.Price.String(),
+			"currency":  snapshot.Album.Currency,
+			"genres":    genres,
+			"tags":      tags,
+		},
+	}
+	if snapshot.Content != nil {
+		content := map[string]interface{}{
+			"id":      snapshot.Content.ID.Hex(),
+			"name":    snapshot.Content.Name,
+			"content": snapshot.Content.Content,
+		}
+		// content kept in GridFS is too large for an audit entry, the content revisions keep it
+		if snapshot.Content.ContentFileId != nil {
+			delete(content, "content")
+			content["content_size"] = snapshot.Content.ContentSize
+		} else if len(snapshot.Content.Content) > libs.ContentStorageProvider().InlineMaxBytes {
+			delete(content, "content")
+			content["content_size"] = int64(len(snapshot.Content.Content))
+		}
+		document["content"] = content
+	}
+	return document
+}
+
+// diffDocuments lists every field that differs between before and after as "section.field": {"from", "to"}
+func diffDocuments(before, after entities.JSONDocument) entities.JSONDocument {
+	flatBefore, flatAfter := flattenDocument(before), flattenDocument(after)
+	changes := entities.JSONDocument{}
+	for field, from := range flatBefore {
+		if to, ok := flatAfter[field]; !ok || !reflect.DeepEqual(from, to) {
+			changes[field] = map[string]interface{}{"from": from, "to": flatAfter[field]}
+		}
+	}
+	for field, to := range flatAfter {
+		if _, ok := flatBefore[field]; !ok {
+			changes[field] = map[string]interface{}{"from": nil, "to": to}
+		}
+	}
+	return changes
+}
+
+func flattenDocument(document entities.JSONDocument) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for section, fields := range document {
+		for field, value := range fields.(map[string]interface{}) {
+			flat[fmt.Sprintf("%s.%s", section, field)] = value
+		}
+	}
+	return flat
+}
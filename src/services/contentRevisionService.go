@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.uber.org/zap"
+)
+
+type IContentRevisionService interface {
+	FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error)
+	FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error)
+	Record(before, after entities.AlbumMongoDB, actor string) error
+	Diff(albumId uint, from, to int) (ContentDiff, error)
+	Restore(album entities.Album, revision int, actor AuditActor) (entities.AlbumContentRevisionMongoDB, error)
+}
+
+var ErrContentUnchanged = errors.New("the content is at this revision already")
+
+// ContentDiff is a line diff between two revisions of an album content in unified format
+type ContentDiff struct {
+	From      int
+	To        int
+	Additions int
+	Deletions int
+	Unified   string
+}
+
+// contents are compared in chunks of this size, neither is held in memory whole
+const contentCompareChunk = 32 << 10
+
+type contentRevisionService struct {
+	repo      *repositories.IContentRevisionMongoDBRepository
+	albumRepo *repositories.IAlbumMongoDBRepository
+	albums    *IAlbumService
+	bus       *libs.EventBus
+	logger    *zap.Logger
+}
+
+// ContentRevisionService constructor
+func ContentRevisionService(repo *repositories.IContentRevisionMongoDBRepository, albumRepo *repositories.IAlbumMongoDBRepository, albums *IAlbumService, bus *libs.EventBus) *contentRevisionService {
+	return &contentRevisionService{repo: repo, albumRepo: albumRepo, albums: albums, bus: bus, logger: libs.NewZapLogger()}
+}
+
+/*** interface implementations ***/
+
+func (service *contentRevisionService) FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error) {
+	return (*service.repo).FindByAlbum(albumId, page, pageSize)
+}
+
+func (service *contentRevisionService) FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error) {
+	return (*service.repo).FindByRevision(albumId, revision)
+}
+
+// Record stores the content written by actor as a new revision unless the latest revision has it already, before is
+// the content it replaced and has no album id for new content. Content that was written before revisions were kept
+// becomes the first revision. Contents are compared and stored as they are read.
+func (service *contentRevisionService) Record(before, after entities.AlbumMongoDB, actor string) error {
+	content, err := (*service.albumRepo).ReadContent(after)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	latest, err := (*service.repo).Latest(after.AlbumId)
+	if err == nil {
+		recorded, _, err := (*service.repo).OpenContent(after.AlbumId, latest.Revision)
+		if err != nil {
+			return err
+		}
+		same, err := sameContent(recorded, content)
+		recorded.Close()
+		if err != nil || same {
+			return err
+		}
+	} else if !errors.Is(err, repositories.ErrContentRevisionNotFound) {
+		return err
+	} else if before.AlbumId != 0 {
+		if changed, err := service.recordBaseline(before, content); err != nil || !changed {
+			return err
+		}
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	revision := entities.AlbumContentRevisionMongoDB{AlbumId: after.AlbumId, Author: actor, CreatedAt: time.Now()}
+	_, err = (*service.repo).Create(&revision, content)
+	return err
+}
+
+// Diff compares the content of revision from with revision to line by line
+func (service *contentRevisionService) Diff(albumId uint, from, to int) (ContentDiff, error) {
+	diff := ContentDiff{From: from, To: to}
+	fromRevision, err := (*service.repo).FindByRevision(albumId, from)
+	if err != nil {
+		return diff, err
+	}
+	toRevision, err := (*service.repo).FindByRevision(albumId, to)
+	if err != nil {
+		return diff, err
+	}
+
+	fromLines, toLines := difflib.SplitLines(fromRevision.Content), difflib.SplitLines(toRevision.Content)
+	for _, opcode := range difflib.NewMatcher(fromLines, toLines).GetOpCodes() {
+		if opcode.Tag != 'e' {
+			diff.Deletions += opcode.I2 - opcode.I1
+			diff.Additions += opcode.J2 - opcode.J1
+		}
+	}
+	diff.Unified, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        fromLines,
+		B:        toLines,
+		FromFile: "revision " + strconv.Itoa(from),
+		ToFile:   "revision " + strconv.Itoa(to),
+		Context:  3,
+	})
+	diff.Unified = strings.TrimSuffix(diff.Unified, "\n")
+	return diff, err
+}
+
+// Restore writes the content of a revision back to the album, records it as a new revision and marks the album as
+// updated. The content is streamed from the revision to the album and to the new revision.
+func (service *contentRevisionService) Restore(album entities.Album, revision int, actor AuditActor) (entities.AlbumContentRevisionMongoDB, error) {
+	content, restoring, err := (*service.repo).OpenContent(album.Id, revision)
+	if err != nil {
+		return restoring, err
+	}
+	defer content.Close()
+	current := (*service.albumRepo).FindById(album.Id)
+	if current.AlbumId != 0 {
+		currentContent, err := (*service.albumRepo).ReadContent(current)
+		if err != nil {
+			return restoring, err
+		}
+		same, err := sameContent(currentContent, content)
+		currentContent.Close()
+		if err != nil {
+			return restoring, err
+		}
+		if same {
+			return restoring, ErrContentUnchanged
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return restoring, err
+		}
+	}
+
+	restored := entities.AlbumMongoDB{AlbumId: album.Id, Name: album.Title}
+	if err := (*service.albumRepo).WithActor(actor.Actor).WriteContent(&restored, content); err != nil {
+		return restoring, err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return restoring, err
+	}
+	created := entities.AlbumContentRevisionMongoDB{AlbumId: album.Id, RestoredFrom: revision, Author: actor.Actor, CreatedAt: time.Now()}
+	if created, err = (*service.repo).Create(&created, content); err != nil {
+		return created, err
+	}
+
+	var before *entities.AlbumMongoDB
+	if current.AlbumId != 0 {
+		before = &current
+	}
+	// the content is restored already, mongodb has no transaction to take it back with
+	touched, err := (*service.albums).ContentWritten(album.Id, before, &restored, entities.AuditActionRestore, actor)
+	if err != nil {
+		service.logger.Error("unable to mark the album updated and write the audit log of a restored revision",
+			zap.Uint("album_id", album.Id),
+			zap.Int("revision", revision),
+			zap.String("error", err.Error()),
+		)
+		touched = album
+	}
+	service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: album.Id, Album: &touched})
+	return created, nil
+}
+
+// recordBaseline stores before, content written before revisions were kept, as the first revision of its album
+// unless content is the same. It tells whether content is a change to record.
+func (service *contentRevisionService) recordBaseline(before entities.AlbumMongoDB, content io.Reader) (bool, error) {
+	previous, err := (*service.albumRepo).ReadContent(before)
+	if err != nil {
+		return false, err
+	}
+	defer previous.Close()
+	if same, err := sameContent(previous, content); err != nil || same {
+		return false, err
+	}
+	if _, err := previous.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	baseline := entities.AlbumContentRevisionMongoDB{AlbumId: before.AlbumId, Author: before.UpdatedBy, CreatedAt: before.UpdatedAt}
+	if _, err := (*service.repo).Create(&baseline, previous); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sameContent reads a and b chunk by chunk until they differ or end and tells whether they are the same
+func sameContent(a, b io.Reader) (bool, error) {
+	chunkA, chunkB := make([]byte, contentCompareChunk), make([]byte, contentCompareChunk)
+	for {
+		n, errA := io.ReadFull(a, chunkA)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, errA
+		}
+		m, errB := io.ReadFull(b, chunkB[:n])
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+		if m != n || !bytes.Equal(chunkA[:n], chunkB[:m]) {
+			return false, nil
+		}
+		if errA != nil {
+			// a has ended, b has to end with it
+			m, errB = io.ReadFull(b, chunkB[:1])
+			if errB != nil && errB != io.EOF {
+				return false, errB
+			}
+			return m == 0, nil
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the gif decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the webp decoder
+)
+
+type ICoverService interface {
+	FindByAlbumIds(albumIds []uint) (map[uint]entities.AlbumCover, error)
+	FindByAlbumId(albumId uint) (entities.AlbumCover, error)
+	Upload(albumId uint, reader io.Reader, actor string) (entities.AlbumCover, error)
+	Open(cover entities.AlbumCover, size string) (io.ReadSeekCloser, libs.BlobInfo, string, error)
+	Delete(albumId uint) error
+	DeleteImages(albumId uint) error
+}
+
+// CoverSizeOriginal is the uploaded image, the other sizes are thumbnails that fit in a square of CoverSizes pixels
+const CoverSizeOriginal = "original"
+
+var CoverSizes = map[string]int{"small": 150, "medium": 300, "large": 600}
+
+// content types a cover can be uploaded as, thumbnails of anything but jpeg are png
+var coverTypes = map[string]string{"image/jpeg": "jpg", "image/png": "png", "image/gif": "gif", "image/webp": "webp"}
+
+var (
+	ErrCoverTooLarge        = errors.New("cover image is too large")
+	ErrUnsupportedCoverType = errors.New("cover must be a jpeg, png, gif or webp image")
+	ErrInvalidCover         = errors.New("cover image can not be decoded")
+	ErrInvalidCoverSize     = errors.New("size must be original, small, medium or large")
+)
+
+// CoverConfig limits cover uploads
+type CoverConfig struct {
+	MaxBytes int64
+	// MaxDimension is the largest width and height accepted, it keeps decoding an upload within bounds
+	MaxDimension int
+}
+
+type coverService struct {
+	repo    *repositories.ICoverRepository
+	storage libs.IBlobStorage
+	config  CoverConfig
+	bus     *libs.EventBus
+}
+
+// CoverService constructor
+func CoverService(repo *repositories.ICoverRepository, storage libs.IBlobStorage, config CoverConfig, bus *libs.EventBus) *coverService {
+	return &coverService{repo: repo, storage: storage, config: config, bus: bus}
+}
+
+// CoverConfigProvider reads COVER_MAX_BYTES and COVER_MAX_DIMENSION as numbers
+func CoverConfigProvider() CoverConfig {
+	utils.InitEnv()
+	config := CoverConfig{MaxBytes: 10 << 20, MaxDimension: 6000}
+	if value, err := strconv.ParseInt(os.Getenv("COVER_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+		config.MaxBytes = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("COVER_MAX_DIMENSION")); err == nil && value > 0 {
+		config.MaxDimension = value
+	}
+	return config
+}
+
+// CoverUrls are the urls of every size of a cover, they change with every upload so they can be cached for good
+func CoverUrls(cover entities.AlbumCover) map[string]string {
+	base := fmt.Sprintf("/api/v1/albums/%d/cover?v=%s", cover.AlbumId, cover.Version)
+	urls := map[string]string{CoverSizeOriginal: base}
+	for size := range CoverSizes {
+		urls[size] = base + "&size=" + size
+	}
+	return urls
+}
+
+// coverKey is where a size of a cover is stored
+func coverKey(cover entities.AlbumCover, size string) string {
+	extension := coverTypes[cover.ContentType]
+	if size != CoverSizeOriginal && cover.ContentType != "image/jpeg" {
+		extension = "png"
+	}
+	return fmt.Sprintf("%s/%s/%s.%s", coverPrefix(cover.AlbumId), cover.Version, size, extension)
+}
+
+func coverPrefix(albumId uint) string {
+	return "covers/" + strconv.FormatUint(uint64(albumId), 10)
+}
+
+// thumbnail scales source down to fit in a square of size pixels, it never scales up
+func thumbnail(source image.Image, size int) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return source
+	}
+	if width >= height {
+		width, height = size, height*size/width
+	} else {
+		width, height = width*size/height, size
+	}
+	// a very long and thin image keeps at least a pixel
+	if width == 0 {
+		width = 1
+	}
+	if height == 0 {
+		height = 1
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), source, bounds, draw.Over, nil)
+	return scaled
+}
+
+/*** interface implementations ***/
+
+func (service *coverService) FindByAlbumIds(albumIds []uint) (map[uint]entities.AlbumCover, error) {
+	lookup := map[uint]entities.AlbumCover{}
+	covers, err := (*service.repo).FindByAlbumIds(albumIds)
+	for _, cover := range covers {
+		lookup[cover.AlbumId] = cover
+	}
+	return lookup, err
+}
+
+func (service *coverService) FindByAlbumId(albumId uint) (entities.AlbumCover, error) {
+	return (*service.repo).FindByAlbumId(albumId)
+}
+
+// Upload validates the image, stores it with its thumbnails under a new version and drops the previous version.
+// The type is sniffed from the content, what the client claims is not trusted.
+func (service *coverService) Upload(albumId uint, reader io.Reader, actor string) (entities.AlbumCover, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, service.config.MaxBytes+1))
+	if err != nil {
+		return entities.AlbumCover{}, err
+	}
+	if int64(len(data)) > service.config.MaxBytes {
+		return entities.AlbumCover{}, fmt.Errorf("%w: at most %d bytes", ErrCoverTooLarge, service.config.MaxBytes)
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := coverTypes[contentType]; !ok {
+		return entities.AlbumCover{}, ErrUnsupportedCoverType
+	}
+
+	// check the dimensions from the header before decoding the pixels
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return entities.AlbumCover{}, ErrInvalidCover
+	}
+	if imageConfig.Width > service.config.MaxDimension || imageConfig.Height > service.config.MaxDimension {
+		return entities.AlbumCover{}, fmt.Errorf("%w: at most %d pixels wide and high", ErrCoverTooLarge, service.config.MaxDimension)
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return entities.AlbumCover{}, ErrInvalidCover
+	}
+
+	hash := sha256.Sum256(data)
+	cover := entities.AlbumCover{AlbumId: albumId, Version: hex.EncodeToString(hash[:8]), ContentType: contentType,
+		Width: imageConfig.Width, Height: imageConfig.Height, SizeBytes: int64(len(data)), UpdatedAt: time.Now(), UpdatedBy: actor}
+
+	previous, err := (*service.repo).FindByAlbumId(albumId)
+	if err != nil && !errors.Is(err, repositories.ErrCoverNotFound) {
+		return cover, err
+	}
+	// an upload that fails drops what it stored, but the same image uploaded again has the version of the live
+	// cover and its images stay
+	discard := func() {
+		if cover.Version != previous.Version {
+			service.storage.Delete(coverPrefix(albumId) + "/" + cover.Version)
+		}
+	}
+
+	if err := service.storage.Put(coverKey(cover, CoverSizeOriginal), bytes.NewReader(data)); err != nil {
+		discard()
+		return cover, err
+	}
+	for size, pixels := range CoverSizes {
+		var encoded bytes.Buffer
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&encoded, thumbnail(source, pixels), &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&encoded, thumbnail(source, pixels))
+		}
+		if err == nil {
+			err = service.storage.Put(coverKey(cover, size), &encoded)
+		}
+		if err != nil {
+			discard()
+			return cover, err
+		}
+	}
+
+	if cover, err = (*service.repo).Save(&cover); err != nil {
+		discard()
+		return cover, err
+	}
+	if previous.Version != "" && previous.Version != cover.Version {
+		service.storage.Delete(coverPrefix(albumId) + "/" + previous.Version)
+	}
+
+	service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: albumId})
+	return cover, nil
+}
+
+// Open returns a size of the cover with its content type, an empty size is the original
+func (service *coverService) Open(cover entities.AlbumCover, size string) (io.ReadSeekCloser, libs.BlobInfo, string, error) {
+	if size == "" {
+		size = CoverSizeOriginal
+	}
+	if _, ok := CoverSizes[size]; !ok && size != CoverSizeOriginal {
+		return nil, libs.BlobInfo{}, "", ErrInvalidCoverSize
+	}
+
+	contentType := cover.ContentType
+	if size != CoverSizeOriginal && contentType != "image/jpeg" {
+		contentType = "image/png"
+	}
+	blob, info, err := service.storage.Open(coverKey(cover, size))
+	return blob, info, contentType, err
+}
+
+// Delete removes the cover of an album with all its images
+func (service *coverService) Delete(albumId uint) error {
+	err := (*service.repo).Delete(albumId)
+	if storageErr := service.storage.Delete(coverPrefix(albumId)); storageErr != nil && err == nil {
+		err = storageErr
+	}
+	if err == nil {
+		service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: albumId})
+	}
+	return err
+}
+
+// DeleteImages removes every image stored for an album once the album is deleted and its cover row went with it,
+// an album without a cover has none
+func (service *coverService) DeleteImages(albumId uint) error {
+	return service.storage.Delete(coverPrefix(albumId))
+}
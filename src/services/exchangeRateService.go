@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+	"github.com/shopspring/decimal"
+)
+
+type IExchangeRateService interface {
+	FindAll() ([]entities.ExchangeRate, error)
+	Replace(rates []entities.ExchangeRate, actor string) ([]entities.ExchangeRate, error)
+	Import(path string, actor string) error
+	Table() (ExchangeRateTable, error)
+}
+
+var (
+	ErrUnknownCurrency      = errors.New("no exchange rate for currency")
+	ErrInvalidExchangeRates = errors.New("invalid exchange rates")
+
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ExchangeRateTable is the rate table by currency
+type ExchangeRateTable map[string]entities.ExchangeRate
+
+// ConvertedPrice is a price in another currency, Rate is how many units of Currency one unit of the original buys
+// and AsOf is when the older of the two rates it was computed from was quoted
+type ConvertedPrice struct {
+	Price    decimal.Decimal
+	Currency string
+	Rate     decimal.Decimal
+	AsOf     time.Time
+}
+
+// ExchangeRateCache keeps the rate table between reads. Replace and Import refresh it, after TTL it is loaded again
+// so rates written by another process show up too.
+type ExchangeRateCache struct {
+	TTL time.Duration
+
+	mutex    sync.RWMutex
+	table    ExchangeRateTable
+	loadedAt time.Time
+}
+
+const defaultExchangeRateCacheTTL = time.Minute
+
+var (
+	exchangeRateCache     *ExchangeRateCache
+	exchangeRateCacheOnce sync.Once
+)
+
+type exchangeRateService struct {
+	repo  *repositories.IExchangeRateRepository
+	cache *ExchangeRateCache
+}
+
+// ExchangeRateService constructor
+func ExchangeRateService(repo *repositories.IExchangeRateRepository, cache *ExchangeRateCache) *exchangeRateService {
+	return &exchangeRateService{repo: repo, cache: cache}
+}
+
+// ExchangeRateCacheProvider returns the rate table cache shared by the whole process, EXCHANGE_RATES_CACHE_TTL sets
+// how long the table is kept
+func ExchangeRateCacheProvider() *ExchangeRateCache {
+	exchangeRateCacheOnce.Do(func() {
+		utils.InitEnv()
+		exchangeRateCache = &ExchangeRateCache{TTL: defaultExchangeRateCacheTTL}
+		if ttl, err := time.ParseDuration(os.Getenv("EXCHANGE_RATES_CACHE_TTL")); err == nil && ttl > 0 {
+			exchangeRateCache.TTL = ttl
+		}
+	})
+	return exchangeRateCache
+}
+
+// ExchangeRatesFileProvider reads EXCHANGE_RATES_FILE, the rate table loaded on startup, empty when there is none
+func ExchangeRatesFileProvider() string {
+	utils.InitEnv()
+	return strings.TrimSpace(os.Getenv("EXCHANGE_RATES_FILE"))
+}
+
+// Convert converts price from one currency to the other through the base currency of the table
+// and rounds the result by rules
+func (table ExchangeRateTable) Convert(price decimal.Decimal, from, to string, rules libs.RoundingRules) (ConvertedPrice, error) {
+	source, ok := table[from]
+	if !ok {
+		return ConvertedPrice{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	target, ok := table[to]
+	if !ok {
+		return ConvertedPrice{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+
+	asOf := source.AsOf
+	if target.AsOf.Before(asOf) {
+		asOf = target.AsOf
+	}
+	// multiply before dividing, so nothing is lost before the final rounding
+	converted := price.Mul(target.Rate).DivRound(source.Rate, 16)
+	return ConvertedPrice{Price: rules.Round(converted, to), Currency: to, Rate: target.Rate.DivRound(source.Rate, 8), AsOf: asOf}, nil
+}
+
+// AcceptedCurrencies orders the currencies of an Accept-Currency header by their q value, leaving out q=0
+func AcceptedCurrencies(header string) []string {
+	type accepted struct {
+		currency string
+		quality  float64
+	}
+	candidates := []accepted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		candidate := accepted{currency: strings.ToUpper(strings.TrimSpace(fields[0])), quality: 1}
+		for _, param := range fields[1:] {
+			if value := strings.TrimSpace(param); strings.HasPrefix(value, "q=") {
+				if quality, err := strconv.ParseFloat(strings.TrimPrefix(value, "q="), 64); err == nil {
+					candidate.quality = quality
+				}
+			}
+		}
+		if candidate.currency != "" && candidate.quality > 0 {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	currencies := []string{}
+	for _, candidate := range candidates {
+		currencies = append(currencies, candidate.currency)
+	}
+	return currencies
+}
+
+// ParseExchangeRates reads a rate table as json, in the format of models.ExchangeRatesDto, or as csv
+// with a header row naming the currency and rate columns and optionally an as_of column
+func ParseExchangeRates(reader io.Reader, format string) ([]entities.ExchangeRate, error) {
+	if format == "csv" {
+		return parseExchangeRatesCsv(reader)
+	}
+
+	var table models.ExchangeRatesDto
+	if err := json.NewDecoder(reader).Decode(&table); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeRates, err.Error())
+	}
+	asOf := time.Now()
+	if table.AsOf != nil {
+		asOf = *table.AsOf
+	}
+	base := strings.ToUpper(table.Base)
+	rates := []entities.ExchangeRate{{Currency: base, Rate: decimal.NewFromInt(1), AsOf: asOf}}
+	for currency, rate := range table.Rates {
+		if currency = strings.ToUpper(currency); currency != base {
+			rates = append(rates, entities.ExchangeRate{Currency: currency, Rate: rate, AsOf: asOf})
+		}
+	}
+	return rates, nil
+}
+
+func parseExchangeRatesCsv(reader io.Reader) ([]entities.ExchangeRate, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeRates, err.Error())
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the csv has no header", ErrInvalidExchangeRates)
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	currencyColumn, hasCurrency := columns["currency"]
+	rateColumn, hasRate := columns["rate"]
+	asOfColumn, hasAsOf := columns["as_of"]
+	if !hasCurrency || !hasRate {
+		return nil, fmt.Errorf("%w: the csv header needs currency and rate columns", ErrInvalidExchangeRates)
+	}
+
+	now := time.Now()
+	rates := []entities.ExchangeRate{}
+	for line, record := range records[1:] {
+		rate, err := decimal.NewFromString(strings.TrimSpace(record[rateColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidExchangeRates, line+2, err.Error())
+		}
+		exchangeRate := entities.ExchangeRate{Currency: strings.ToUpper(strings.TrimSpace(record[currencyColumn])), Rate: rate, AsOf: now}
+		if hasAsOf && strings.TrimSpace(record[asOfColumn]) != "" {
+			if exchangeRate.AsOf, err = time.Parse(time.RFC3339, strings.TrimSpace(record[asOfColumn])); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidExchangeRates, line+2, err.Error())
+			}
+		}
+		rates = append(rates, exchangeRate)
+	}
+	return rates, nil
+}
+
+/*** interface implementations ***/
+
+func (service *exchangeRateService) FindAll() ([]entities.ExchangeRate, error) {
+	return (*service.repo).FindAll()
+}
+
+// Replace validates the rates and swaps them in for the whole table
+func (service *exchangeRateService) Replace(rates []entities.ExchangeRate, actor string) ([]entities.ExchangeRate, error) {
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: the table is empty", ErrInvalidExchangeRates)
+	}
+	seen := map[string]bool{}
+	now := time.Now()
+	for i := range rates {
+		rate := &rates[i]
+		if !currencyCode.MatchString(rate.Currency) {
+			return nil, fmt.Errorf("%w: %q is not a currency code", ErrInvalidExchangeRates, rate.Currency)
+		}
+		if !rate.Rate.IsPositive() {
+			return nil, fmt.Errorf("%w: the rate of %s must be positive", ErrInvalidExchangeRates, rate.Currency)
+		}
+		if seen[rate.Currency] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidExchangeRates, rate.Currency)
+		}
+		seen[rate.Currency] = true
+		rate.UpdatedAt, rate.UpdatedBy = now, actor
+	}
+
+	if err := (*service.repo).Replace(rates); err != nil {
+		return nil, err
+	}
+	replaced, err := (*service.repo).FindAll()
+	if err != nil {
+		// the table changed, the next read loads it again
+		service.cache.store(nil)
+		return nil, err
+	}
+	service.cache.store(newExchangeRateTable(replaced))
+	return replaced, nil
+}
+
+// Import replaces the table with the rates of a file, a .csv file is read as csv and any other as json
+func (service *exchangeRateService) Import(path string, actor string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format := "json"
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = "csv"
+	}
+	rates, err := ParseExchangeRates(file, format)
+	if err != nil {
+		return err
+	}
+	_, err = service.Replace(rates, actor)
+	return err
+}
+
+// Table returns the cached rate table, loading it when it is older than the TTL of the cache.
+// The table is shared, callers must not modify it.
+func (service *exchangeRateService) Table() (ExchangeRateTable, error) {
+	if table, ok := service.cache.load(); ok {
+		return table, nil
+	}
+	rates, err := (*service.repo).FindAll()
+	if err != nil {
+		return ExchangeRateTable{}, err
+	}
+	table := newExchangeRateTable(rates)
+	service.cache.store(table)
+	return table, nil
+}
+
+func newExchangeRateTable(rates []entities.ExchangeRate) ExchangeRateTable {
+	table := ExchangeRateTable{}
+	for _, rate := range rates {
+		table[rate.Currency] = rate
+	}
+	return table
+}
+
+// load returns the table unless it was never loaded, dropped or is older than the TTL
+func (cache *ExchangeRateCache) load() (ExchangeRateTable, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	if cache.table == nil || time.Since(cache.loadedAt) > cache.TTL {
+		return nil, false
+	}
+	return cache.table, true
+}
+
+// store keeps table as the current one, a nil table drops it
+func (cache *ExchangeRateCache) store(table ExchangeRateTable) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.table, cache.loadedAt = table, time.Now()
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+type IGenreService interface {
+	FindAll() ([]entities.Genre, error)
+	FindBySlugs(slugs []string) ([]entities.Genre, error)
+	Create(name string, actor string) (entities.Genre, error)
+	Delete(slug string) error
+}
+
+var ErrInvalidGenreName = errors.New("genre name needs at least one letter or digit")
+
+type genreService struct {
+	repo *repositories.IGenreRepository
+}
+
+// GenreService constructor
+func GenreService(repo *repositories.IGenreRepository) *genreService {
+	return &genreService{repo: repo}
+}
+
+/*** interface implementations ***/
+
+func (service *genreService) FindAll() ([]entities.Genre, error) {
+	return (*service.repo).FindAll()
+}
+
+// FindBySlugs resolves the genres of an album payload, slugs are matched regardless of case
+func (service *genreService) FindBySlugs(slugs []string) ([]entities.Genre, error) {
+	normalized := make([]string, len(slugs))
+	for i, slug := range slugs {
+		normalized[i] = strings.ToLower(strings.TrimSpace(slug))
+	}
+	return (*service.repo).FindBySlugs(normalized)
+}
+
+func (service *genreService) Create(name string, actor string) (entities.Genre, error) {
+	genre := entities.Genre{Name: strings.Join(strings.Fields(name), " "), CreatedBy: actor}
+	if repositories.GenreSlug(genre.Name) == "" {
+		return genre, ErrInvalidGenreName
+	}
+	return (*service.repo).Create(&genre)
+}
+
+func (service *genreService) Delete(slug string) error {
+	return (*service.repo).Delete(slug)
+}
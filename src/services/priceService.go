@@ -0,0 +1,102 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+)
+
+type IPriceService interface {
+	FindHistory(albumId uint) ([]entities.PriceChange, error)
+	FindScheduled(albumId uint) ([]entities.ScheduledPrice, error)
+	Schedule(price *entities.ScheduledPrice, actor string) (entities.ScheduledPrice, error)
+	Cancel(albumId, id uint) error
+	CurrentPrices(albumIds []uint) (map[uint]entities.ScheduledPrice, error)
+	ApplyDue() (int, error)
+}
+
+// PriceSchedulerConfig controls the worker that applies scheduled prices
+type PriceSchedulerConfig struct {
+	PollInterval time.Duration
+	// BatchSize is how many due prices are claimed per transaction
+	BatchSize int
+}
+
+type priceService struct {
+	repo      *repositories.IPriceRepository
+	albumRepo *repositories.IAlbumRepository
+	config    PriceSchedulerConfig
+	bus       *libs.EventBus
+}
+
+// PriceService constructor
+func PriceService(repo *repositories.IPriceRepository, albumRepo *repositories.IAlbumRepository, config PriceSchedulerConfig, bus *libs.EventBus) *priceService {
+	return &priceService{repo: repo, albumRepo: albumRepo, config: config, bus: bus}
+}
+
+// PriceSchedulerConfigProvider reads PRICE_SCHEDULER_POLL_INTERVAL as a duration and PRICE_SCHEDULER_BATCH_SIZE as a number
+func PriceSchedulerConfigProvider() PriceSchedulerConfig {
+	utils.InitEnv()
+	config := PriceSchedulerConfig{PollInterval: 30 * time.Second, BatchSize: 100}
+	if value, err := time.ParseDuration(os.Getenv("PRICE_SCHEDULER_POLL_INTERVAL")); err == nil && value > 0 {
+		config.PollInterval = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("PRICE_SCHEDULER_BATCH_SIZE")); err == nil && value > 0 {
+		config.BatchSize = value
+	}
+	return config
+}
+
+/*** interface implementations ***/
+
+func (service *priceService) FindHistory(albumId uint) ([]entities.PriceChange, error) {
+	return (*service.repo).FindHistory(albumId)
+}
+
+func (service *priceService) FindScheduled(albumId uint) ([]entities.ScheduledPrice, error) {
+	return (*service.repo).FindScheduled(albumId)
+}
+
+func (service *priceService) Schedule(price *entities.ScheduledPrice, actor string) (entities.ScheduledPrice, error) {
+	price.CreatedBy = actor
+	return (*service.repo).Schedule(price)
+}
+
+func (service *priceService) Cancel(albumId, id uint) error {
+	return (*service.repo).Cancel(albumId, id)
+}
+
+// CurrentPrices returns the prices that are due for the albums but not applied by the scheduler yet,
+// reads show them in place of the stored price so a price takes effect exactly at its effective time.
+// A price written to an album supersedes the prices due at the time, they never show over a newer price.
+func (service *priceService) CurrentPrices(albumIds []uint) (map[uint]entities.ScheduledPrice, error) {
+	return (*service.repo).DuePrices(albumIds, time.Now())
+}
+
+// ApplyDue applies a batch of due prices and publishes an update event for every album it changed.
+// It returns how many scheduled prices it claimed, which is config.BatchSize when more may be due.
+func (service *priceService) ApplyDue() (int, error) {
+	claimed, err := (*service.repo).ApplyDue(time.Now(), service.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, price := range claimed {
+		if price.Status != entities.ScheduledPriceApplied {
+			continue
+		}
+		album, err := (*service.albumRepo).FindById(price.AlbumId)
+		if err != nil || album.Id == 0 {
+			service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: price.AlbumId})
+			continue
+		}
+		service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: album.Id, Album: &album})
+	}
+	return len(claimed), nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunPriceScheduler applies due scheduled prices every config.PollInterval until ctx is done
+func RunPriceScheduler(ctx context.Context, service *IPriceService, config PriceSchedulerConfig, logger *zap.Logger) {
+	ticker := time.NewTicker(config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// keep applying while full batches come back, so a backlog does not wait for the next tick
+			for {
+				claimed, err := (*service).ApplyDue()
+				if err != nil {
+					logger.Error("applying scheduled prices failed", zap.String("error", err.Error()))
+				}
+				if err != nil || claimed < config.BatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
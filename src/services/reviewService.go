@@ -0,0 +1,58 @@
+package services
+
+import (
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type IReviewService interface {
+	FindByAlbum(albumId uint, page, pageSize int) ([]entities.ReviewMongoDB, error)
+	FindById(albumId uint, id primitive.ObjectID) (entities.ReviewMongoDB, error)
+	Create(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error)
+	Update(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error)
+	Delete(albumId uint, id primitive.ObjectID) error
+	Ratings(albumIds []uint) (map[uint]entities.AlbumRatingMongoDB, error)
+	DeleteByAlbum(albumId uint) error
+}
+
+type reviewService struct {
+	repo *repositories.IReviewMongoDBRepository
+}
+
+// ReviewService constructor
+func ReviewService(repo *repositories.IReviewMongoDBRepository) *reviewService {
+	return &reviewService{repo: repo}
+}
+
+/*** interface implementations ***/
+
+func (service *reviewService) FindByAlbum(albumId uint, page, pageSize int) ([]entities.ReviewMongoDB, error) {
+	return (*service.repo).FindByAlbum(albumId, page, pageSize)
+}
+
+func (service *reviewService) FindById(albumId uint, id primitive.ObjectID) (entities.ReviewMongoDB, error) {
+	return (*service.repo).FindById(albumId, id)
+}
+
+func (service *reviewService) Create(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	return (*service.repo).Create(review)
+}
+
+func (service *reviewService) Update(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	return (*service.repo).Update(review)
+}
+
+func (service *reviewService) Delete(albumId uint, id primitive.ObjectID) error {
+	return (*service.repo).Delete(albumId, id)
+}
+
+// Ratings returns the rating of each album that has reviews, albums without reviews are left out
+func (service *reviewService) Ratings(albumIds []uint) (map[uint]entities.AlbumRatingMongoDB, error) {
+	return (*service.repo).Ratings(albumIds)
+}
+
+// DeleteByAlbum removes the reviews and the rating of an album that is deleted
+func (service *reviewService) DeleteByAlbum(albumId uint) error {
+	return (*service.repo).DeleteByAlbum(albumId)
+}
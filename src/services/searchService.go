@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+)
+
+type ISearchService interface {
+	Search(q string, page, pageSize int) (SearchResults, error)
+	Fuzzy(q string, page, pageSize int) (SearchResults, error)
+	Suggest(prefix string, limit int) ([]Suggestion, error)
+}
+
+const (
+	// SearchWindow is how many matches are taken from each backend, results are merged and paged within them
+	SearchWindow = 1000
+	// searchContentWeight makes a match in the content count for less than one in the title or the artist
+	searchContentWeight = 0.5
+	// searchSnippetBytes is the length of the content snippets
+	searchSnippetBytes = 240
+)
+
+// SearchResults is a page of search results, Total counts the results of every page
+type SearchResults struct {
+	Total   int
+	Results []SearchResult
+}
+
+// SearchResult is an album matching a search with its score and its matching fields highlighted
+type SearchResult struct {
+	Album entities.Album
+	// Score adds up the relevance of the album on both backends, higher is better
+	Score float64
+	// Highlights are html snippets of the fields that match, keyed by title, artist and content
+	Highlights map[string]string
+}
+
+// Suggestion is an album title or an artist name completing a prefix, Highlight has what was typed in <mark>
+type Suggestion struct {
+	repositories.Suggestion
+	Highlight string
+}
+
+// FuzzySearchConfig tunes the typo tolerant search
+type FuzzySearchConfig struct {
+	// Threshold is the least similarity, from 0 to 1, of an album to what was typed
+	Threshold float64
+	// CatalogueRefresh is how long the titles and artist names searched in memory are kept, when the database
+	// can not search them itself
+	CatalogueRefresh time.Duration
+}
+
+type searchService struct {
+	repo        *repositories.IAlbumSearchRepository
+	contentRepo *repositories.IAlbumSearchMongoDBRepository
+	albumRepo   *repositories.IAlbumRepository
+	config      FuzzySearchConfig
+	catalogue   fuzzyCatalogue
+}
+
+// fuzzyCatalogue is what fuzzy search looks through without pg_trgm, loaded again once it is older than the refresh
+type fuzzyCatalogue struct {
+	sync.Mutex
+	entries  []repositories.CatalogueEntry
+	loadedAt time.Time
+}
+
+// SearchService constructor
+func SearchService(repo *repositories.IAlbumSearchRepository, contentRepo *repositories.IAlbumSearchMongoDBRepository, albumRepo *repositories.IAlbumRepository, config FuzzySearchConfig) *searchService {
+	return &searchService{repo: repo, contentRepo: contentRepo, albumRepo: albumRepo, config: config}
+}
+
+// FuzzySearchConfigProvider reads FUZZY_SIMILARITY_THRESHOLD as a number between 0 and 1 and FUZZY_CATALOGUE_REFRESH as a duration
+func FuzzySearchConfigProvider() FuzzySearchConfig {
+	utils.InitEnv()
+	config := FuzzySearchConfig{Threshold: 0.5, CatalogueRefresh: time.Minute}
+	if value, err := strconv.ParseFloat(os.Getenv("FUZZY_SIMILARITY_THRESHOLD"), 64); err == nil && value > 0 && value <= 1 {
+		config.Threshold = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("FUZZY_CATALOGUE_REFRESH")); err == nil && value > 0 {
+		config.CatalogueRefresh = value
+	}
+	return config
+}
+
+// rankedIds are the albums of scores, highest score first
+func rankedIds(scores map[uint]float64) []uint {
+	albumIds := make([]uint, 0, len(scores))
+	for albumId := range scores {
+		albumIds = append(albumIds, albumId)
+	}
+	sort.Slice(albumIds, func(i, j int) bool {
+		if scores[albumIds[i]] != scores[albumIds[j]] {
+			return scores[albumIds[i]] > scores[albumIds[j]]
+		}
+		return albumIds[i] < albumIds[j]
+	})
+	return albumIds
+}
+
+// pageOf is a page of albumIds, empty past the last one
+func pageOf(albumIds []uint, page, pageSize int) []uint {
+	from, to := (page-1)*pageSize, page*pageSize
+	if from >= len(albumIds) {
+		return nil
+	}
+	if to > len(albumIds) {
+		to = len(albumIds)
+	}
+	return albumIds[from:to]
+}
+
+// albumLookup loads albumIds, albums deleted in the meantime are missing
+func (service *searchService) albumLookup(albumIds []uint) (map[uint]entities.Album, error) {
+	lookup := map[uint]entities.Album{}
+	albums, err := (*service.albumRepo).FindAll(repositories.AlbumFilter{Ids: albumIds}, 0, 0)
+	for _, album := range albums {
+		lookup[album.Id] = album
+	}
+	return lookup, err
+}
+
+// fuzzyHits is Fuzzy of the repository done in memory, on titles and artist names loaded from the database
+func (service *searchService) fuzzyHits(text string, limit int) ([]repositories.SearchHit, error) {
+	service.catalogue.Lock()
+	if service.catalogue.loadedAt.IsZero() || time.Since(service.catalogue.loadedAt) > service.config.CatalogueRefresh {
+		entries, err := (*service.repo).Catalogue()
+		if err != nil {
+			service.catalogue.Unlock()
+			return nil, err
+		}
+		service.catalogue.entries, service.catalogue.loadedAt = entries, time.Now()
+	}
+	entries := service.catalogue.entries
+	service.catalogue.Unlock()
+
+	hits := []repositories.SearchHit{}
+	// an artist has many albums, its name is compared once
+	artistScores := map[string]float64{}
+	for _, entry := range entries {
+		artistScore, ok := artistScores[entry.Artist]
+		if !ok {
+			artistScore = libs.WordSimilarity(text, entry.Artist)
+			artistScores[entry.Artist] = artistScore
+		}
+		score := libs.WordSimilarity(text, entry.Title)
+		if artistScore > score {
+			score = artistScore
+		}
+		if score >= service.config.Threshold {
+			hits = append(hits, repositories.SearchHit{AlbumId: entry.AlbumId, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].AlbumId < hits[j].AlbumId
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+/*** interface implementations ***/
+
+// Search finds albums whose title and artist, or whose content, have every term of q. Both backends rank their
+// matches between 0 and 1, an album found by both adds up the two.
+func (service *searchService) Search(q string, page, pageSize int) (SearchResults, error) {
+	results := SearchResults{Results: []SearchResult{}}
+	query, err := libs.ParseSearchQuery(q)
+	if err != nil {
+		return results, err
+	}
+
+	metadataHits, err := (*service.repo).Search(query, SearchWindow)
+	if err != nil {
+		return results, err
+	}
+	contentHits, err := (*service.contentRepo).Search(query, SearchWindow)
+	if err != nil {
+		return results, err
+	}
+
+	scores := map[uint]float64{}
+	for _, hit := range metadataHits {
+		scores[hit.AlbumId] += hit.Score
+	}
+	for _, hit := range contentHits {
+		// text scores are unbounded, this maps them between 0 and 1 like ts_rank does
+		scores[hit.AlbumId] += searchContentWeight * hit.Score / (hit.Score + 1)
+	}
+	albumIds := rankedIds(scores)
+	results.Total = len(albumIds)
+	if albumIds = pageOf(albumIds, page, pageSize); len(albumIds) == 0 {
+		return results, nil
+	}
+
+	albumLookup, err := service.albumLookup(albumIds)
+	if err != nil {
+		return results, err
+	}
+	sources, err := (*service.contentRepo).Sources(albumIds)
+	if err != nil {
+		return results, err
+	}
+	contentLookup := map[uint]entities.AlbumMongoDB{}
+	for _, source := range sources {
+		contentLookup[source.AlbumId] = source
+	}
+
+	for _, albumId := range albumIds {
+		// content of an album that is being deleted
+		album, ok := albumLookup[albumId]
+		if !ok {
+			continue
+		}
+		result := SearchResult{Album: album, Score: scores[albumId], Highlights: map[string]string{}}
+		if highlighted, ok := libs.HighlightSearch(album.Title, query, 0); ok {
+			result.Highlights["title"] = highlighted
+		}
+		if album.Artist != nil {
+			if highlighted, ok := libs.HighlightSearch(album.Artist.Name, query, 0); ok {
+				result.Highlights["artist"] = highlighted
+			}
+		}
+		if source, ok := contentLookup[albumId]; ok {
+			text := source.Content
+			if source.ContentHtml != "" {
+				text = libs.ContentText(source.ContentHtml)
+			} else if source.ContentText != "" {
+				// content kept in GridFS
+				text = source.ContentText
+			}
+			text = strings.ReplaceAll(text, "\n", " ")
+			if highlighted, ok := libs.HighlightSearch(text, query, searchSnippetBytes); ok {
+				result.Highlights["content"] = highlighted
+			}
+		}
+		results.Results = append(results.Results, result)
+	}
+	return results, nil
+}
+
+// Fuzzy finds albums whose title or artist has words like those of q, misspelt ones too, most similar first.
+// The database compares them with pg_trgm, a database without it has them compared in memory.
+func (service *searchService) Fuzzy(q string, page, pageSize int) (SearchResults, error) {
+	results := SearchResults{Results: []SearchResult{}}
+	if _, err := libs.ParseSearchQuery(q); err != nil {
+		return results, err
+	}
+
+	hits, err := (*service.repo).Fuzzy(q, service.config.Threshold, SearchWindow)
+	if errors.Is(err, repositories.ErrFuzzySearchUnsupported) {
+		hits, err = service.fuzzyHits(q, SearchWindow)
+	}
+	if err != nil {
+		return results, err
+	}
+
+	scores := map[uint]float64{}
+	for _, hit := range hits {
+		scores[hit.AlbumId] = hit.Score
+	}
+	albumIds := rankedIds(scores)
+	results.Total = len(albumIds)
+	if albumIds = pageOf(albumIds, page, pageSize); len(albumIds) == 0 {
+		return results, nil
+	}
+
+	albumLookup, err := service.albumLookup(albumIds)
+	if err != nil {
+		return results, err
+	}
+	for _, albumId := range albumIds {
+		album, ok := albumLookup[albumId]
+		if !ok {
+			continue
+		}
+		result := SearchResult{Album: album, Score: scores[albumId], Highlights: map[string]string{}}
+		if highlighted, ok := libs.HighlightSimilar(album.Title, q, service.config.Threshold, 0); ok {
+			result.Highlights["title"] = highlighted
+		}
+		if album.Artist != nil {
+			if highlighted, ok := libs.HighlightSimilar(album.Artist.Name, q, service.config.Threshold, 0); ok {
+				result.Highlights["artist"] = highlighted
+			}
+		}
+		results.Results = append(results.Results, result)
+	}
+	return results, nil
+}
+
+// Suggest completes prefix with album titles and artist names, for a search box that suggests as one types
+func (service *searchService) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	suggestions := []Suggestion{}
+	query, err := libs.ParsePrefixQuery(prefix)
+	if err != nil {
+		return suggestions, err
+	}
+	found, err := (*service.repo).Suggest(query, limit)
+	for _, suggestion := range found {
+		highlighted, _ := libs.HighlightSearch(suggestion.Text, query, 0)
+		suggestions = append(suggestions, Suggestion{Suggestion: suggestion, Highlight: highlighted})
+	}
+	return suggestions, err
+}
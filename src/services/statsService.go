@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+)
+
+type IStatsService interface {
+	Stats(groupBy, userId string) (Stats, error)
+}
+
+var ErrInvalidStatsGroupBy = errors.New("group_by must be artist or price_bucket")
+
+// Stats are figures about the whole catalogue. Albums and Content are computed at ComputedAt and shared by every
+// caller until they are refreshed, Read is the albums the caller has read and is always current.
+type Stats struct {
+	Albums     repositories.AlbumStats
+	Content    repositories.ContentStats
+	Read       int64
+	ComputedAt time.Time
+}
+
+// StatsConfig says how long the catalogue stats are kept before they are computed again
+type StatsConfig struct {
+	Refresh time.Duration
+}
+
+type statsService struct {
+	repo        *repositories.IStatsRepository
+	contentRepo *repositories.IStatsMongoDBRepository
+	readRepo    *repositories.IUserAlbumReadRepository
+	config      StatsConfig
+	// cache holds the stats of every group_by, computing them is guarded by cacheLock so that callers arriving
+	// together wait for one computation
+	cache     map[string]Stats
+	cacheLock sync.Mutex
+}
+
+// StatsService constructor
+func StatsService(repo *repositories.IStatsRepository, contentRepo *repositories.IStatsMongoDBRepository, readRepo *repositories.IUserAlbumReadRepository, config StatsConfig) *statsService {
+	return &statsService{repo: repo, contentRepo: contentRepo, readRepo: readRepo, config: config, cache: map[string]Stats{}}
+}
+
+// StatsConfigProvider reads STATS_REFRESH as a duration
+func StatsConfigProvider() StatsConfig {
+	utils.InitEnv()
+	config := StatsConfig{Refresh: 5 * time.Minute}
+	if value, err := time.ParseDuration(os.Getenv("STATS_REFRESH")); err == nil && value > 0 {
+		config.Refresh = value
+	}
+	return config
+}
+
+// catalogueStats returns the cached stats grouped by groupBy, computed again once they are older than the refresh
+func (service *statsService) catalogueStats(groupBy string) (Stats, error) {
+	service.cacheLock.Lock()
+	defer service.cacheLock.Unlock()
+	if stats, ok := service.cache[groupBy]; ok && time.Since(stats.ComputedAt) < service.config.Refresh {
+		return stats, nil
+	}
+
+	stats := Stats{ComputedAt: time.Now()}
+	var err error
+	if stats.Albums, err = (*service.repo).AlbumStats(groupBy); err != nil {
+		return stats, err
+	}
+	if stats.Content, err = (*service.contentRepo).ContentStats(); err != nil {
+		return stats, err
+	}
+	service.cache[groupBy] = stats
+	return stats, nil
+}
+
+/*** interface implementations ***/
+
+// Stats returns the catalogue stats broken down by groupBy, StatsGroupByArtist or StatsGroupByPriceBucket, with
+// how many albums userId has read
+func (service *statsService) Stats(groupBy, userId string) (Stats, error) {
+	if groupBy != repositories.StatsGroupByArtist && groupBy != repositories.StatsGroupByPriceBucket {
+		return Stats{}, ErrInvalidStatsGroupBy
+	}
+	stats, err := service.catalogueStats(groupBy)
+	if err != nil || userId == "" {
+		return stats, err
+	}
+
+	stats.Read, err = (*service.readRepo).CountByUser(userId)
+	// albums read since the stats were computed
+	if stats.Read > stats.Albums.Total {
+		stats.Read = stats.Albums.Total
+	}
+	return stats, err
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+)
+
+type ITrackService interface {
+	FindByAlbum(albumId uint) ([]entities.Track, error)
+	FindByAlbumIds(albumIds []uint) ([]entities.Track, error)
+	FindById(albumId, id uint) (entities.Track, error)
+	Create(track *entities.Track, actor string) (entities.Track, error)
+	Update(track *entities.Track, actor string) (entities.Track, error)
+	Delete(albumId, id uint) error
+	TotalDurations(albumIds []uint) (map[uint]uint, error)
+}
+
+type trackService struct {
+	repo *repositories.ITrackRepository
+	bus  *libs.EventBus
+}
+
+// TrackService constructor
+func TrackService(repo *repositories.ITrackRepository, bus *libs.EventBus) *trackService {
+	return &trackService{repo: repo, bus: bus}
+}
+
+/*** interface implementations ***/
+
+func (service *trackService) FindByAlbum(albumId uint) ([]entities.Track, error) {
+	return (*service.repo).FindByAlbumIds([]uint{albumId})
+}
+
+func (service *trackService) FindByAlbumIds(albumIds []uint) ([]entities.Track, error) {
+	return (*service.repo).FindByAlbumIds(albumIds)
+}
+
+func (service *trackService) FindById(albumId, id uint) (entities.Track, error) {
+	return (*service.repo).FindById(albumId, id)
+}
+
+func (service *trackService) Create(track *entities.Track, actor string) (entities.Track, error) {
+	if track.DiscNumber == 0 {
+		track.DiscNumber = 1
+	}
+	track.CreatedBy, track.UpdatedBy = actor, actor
+	created, err := (*service.repo).Insert(track)
+	if err == nil {
+		service.publishUpdated(created.AlbumId)
+	}
+	return created, err
+}
+
+// Update changes the track in place, a zero disc or number keeps the current one
+func (service *trackService) Update(track *entities.Track, actor string) (entities.Track, error) {
+	current, err := (*service.repo).FindById(track.AlbumId, track.Id)
+	if err != nil {
+		return current, err
+	}
+	if track.DiscNumber == 0 {
+		track.DiscNumber = current.DiscNumber
+	}
+	if track.Number == 0 {
+		track.Number = current.Number
+	}
+	track.UpdatedAt, track.UpdatedBy = time.Now(), actor
+
+	updated, err := (*service.repo).Move(track)
+	if err != nil {
+		return updated, err
+	}
+	service.publishUpdated(updated.AlbumId)
+	return (*service.repo).FindById(updated.AlbumId, updated.Id)
+}
+
+func (service *trackService) Delete(albumId, id uint) error {
+	err := (*service.repo).Delete(albumId, id)
+	if err == nil {
+		service.publishUpdated(albumId)
+	}
+	return err
+}
+
+func (service *trackService) TotalDurations(albumIds []uint) (map[uint]uint, error) {
+	return (*service.repo).TotalDurations(albumIds)
+}
+
+// insertTrackListing adds the track listing of a new album through repo, which the album's transaction is bound to.
+// Tracks are ordered on each disc by their number, tracks without one follow in the order given, and are then
+// numbered from 1.
+func insertTrackListing(repo repositories.ITrackRepository, albumId uint, tracks []entities.Track, actor string) ([]entities.Track, error) {
+	ordered := make([]entities.Track, len(tracks))
+	copy(ordered, tracks)
+	for i := range ordered {
+		if ordered[i].DiscNumber == 0 {
+			ordered[i].DiscNumber = 1
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].DiscNumber != ordered[j].DiscNumber {
+			return ordered[i].DiscNumber < ordered[j].DiscNumber
+		}
+		if ordered[i].Number == 0 || ordered[j].Number == 0 {
+			return ordered[j].Number == 0 && ordered[i].Number != 0
+		}
+		return ordered[i].Number < ordered[j].Number
+	})
+
+	created := []entities.Track{}
+	for _, track := range ordered {
+		track.AlbumId, track.Number = albumId, 0
+		track.CreatedBy, track.UpdatedBy = actor, actor
+		inserted, err := repo.Insert(&track)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, inserted)
+	}
+	return created, nil
+}
+
+// publishUpdated tells subscribers the track listing of the album changed
+func (service *trackService) publishUpdated(albumId uint) {
+	service.bus.Publish(models.AlbumEventUpdated, models.AlbumEventResponse{AlbumId: albumId})
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/utils"
+)
+
+type IWebhookService interface {
+	FindAll() ([]entities.WebhookSubscription, error)
+	Create(url string, eventTypes []string, secret string, actor string) (entities.WebhookSubscription, string, error)
+	Delete(id uint) error
+	Deliveries(subscriptionId uint, page, pageSize int) ([]entities.WebhookDelivery, error)
+	Redeliver(subscriptionId, deliveryId uint) (entities.WebhookDelivery, error)
+	Enqueue(event libs.Event) error
+	DeliverDue() (int, error)
+}
+
+// WebhookConfig controls the delivery worker
+type WebhookConfig struct {
+	PollInterval time.Duration
+	// BatchSize is how many due deliveries are sent per poll
+	BatchSize int
+	// Timeout is the request timeout of a single attempt
+	Timeout time.Duration
+	// a failed attempt is retried after BackoffBase * 2^(attempts-1), at most BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// MaxAttempts is how many attempts a delivery gets before it is dead-lettered
+	MaxAttempts int
+	// AllowPrivateNetworks lets deliveries reach loopback, private and link-local addresses, for local development only
+	AllowPrivateNetworks bool
+}
+
+const (
+	webhookSecretPrefix = "whsec_"
+
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+
+	// how much of a failed response is kept in last_error
+	webhookErrorLimit = 1024
+)
+
+var ErrWebhookAddressBlocked = errors.New("webhook address is not public")
+
+// nonPublicNetworks are the reserved ranges net.IP has no method for
+var nonPublicNetworks = parseNetworks("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96")
+
+// WebhookPayload is the json body posted to subscribers
+type WebhookPayload struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type webhookService struct {
+	repo   *repositories.IWebhookRepository
+	config WebhookConfig
+	client *http.Client
+}
+
+// WebhookService constructor
+func WebhookService(repo *repositories.IWebhookRepository, config WebhookConfig) *webhookService {
+	return &webhookService{repo: repo, config: config, client: webhookClient(config)}
+}
+
+// webhookClient sends deliveries without proxies and without following redirects. Unless private networks are
+// allowed every address is checked as the connection is made, after the name is resolved, so a subscription can
+// not reach internal services through its url, a redirect or a name that resolves differently later.
+func webhookClient(config WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: config.Timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		// a redirect is reported as the unexpected status it is
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// WebhookConfigProvider reads WEBHOOK_POLL_INTERVAL, WEBHOOK_TIMEOUT, WEBHOOK_BACKOFF_BASE and WEBHOOK_BACKOFF_MAX
+// as durations, WEBHOOK_BATCH_SIZE and WEBHOOK_MAX_ATTEMPTS as numbers and WEBHOOK_ALLOW_PRIVATE_NETWORKS as a flag
+func WebhookConfigProvider() WebhookConfig {
+	utils.InitEnv()
+	duration := func(key string, fallback time.Duration) time.Duration {
+		if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+			return value
+		}
+		return fallback
+	}
+	number := func(key string, fallback int) int {
+		if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+			return value
+		}
+		return fallback
+	}
+
+	return WebhookConfig{
+		PollInterval: duration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		BatchSize:    number("WEBHOOK_BATCH_SIZE", 50),
+		Timeout:      duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		BackoffBase:  duration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		BackoffMax:   duration("WEBHOOK_BACKOFF_MAX", 6*time.Hour),
+		MaxAttempts:  number("WEBHOOK_MAX_ATTEMPTS", 10),
+
+		AllowPrivateNetworks: os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+	}
+}
+
+/*** interface implementations ***/
+
+func (service *webhookService) FindAll() ([]entities.WebhookSubscription, error) {
+	return (*service.repo).FindSubscriptions()
+}
+
+// Create stores a subscription and returns it together with its signing secret.
+// A secret is generated when none is given, it can not be read again afterwards.
+func (service *webhookService) Create(url string, eventTypes []string, secret string, actor string) (entities.WebhookSubscription, string, error) {
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return entities.WebhookSubscription{}, "", err
+		}
+		secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(random)
+	}
+
+	subscription := entities.WebhookSubscription{
+		Url:        url,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  time.Now(),
+		CreatedBy:  actor,
+	}
+	subscription, err := (*service.repo).CreateSubscription(&subscription)
+	return subscription, secret, err
+}
+
+func (service *webhookService) Delete(id uint) error {
+	return (*service.repo).DeleteSubscription(id)
+}
+
+func (service *webhookService) Deliveries(subscriptionId uint, page, pageSize int) ([]entities.WebhookDelivery, error) {
+	if _, err := (*service.repo).FindSubscription(subscriptionId); err != nil {
+		return nil, err
+	}
+	return (*service.repo).FindDeliveries(subscriptionId, page, pageSize)
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts, typically one that was dead-lettered
+func (service *webhookService) Redeliver(subscriptionId, deliveryId uint) (entities.WebhookDelivery, error) {
+	delivery, err := (*service.repo).FindDelivery(deliveryId)
+	if err != nil {
+		return delivery, err
+	}
+	if delivery.SubscriptionId != subscriptionId {
+		return entities.WebhookDelivery{}, repositories.ErrWebhookDeliveryNotFound
+	}
+
+	delivery.Status = entities.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	return delivery, (*service.repo).SaveDelivery(&delivery)
+}
+
+// Enqueue stores a pending delivery of event for every subscription listening to it.
+// Deliveries are stored before they are sent, so they survive a restart.
+func (service *webhookService) Enqueue(event libs.Event) error {
+	subscriptions, err := (*service.repo).FindSubscriptionsByEvent(event.Type)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	// event ids restart with the process, partners get an id that is unique across restarts
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	data := event.Data
+	// partners learn that an album was read, not who read it
+	if album, ok := data.(models.AlbumEventResponse); ok {
+		album.UserId = ""
+		data = album
+	}
+	payload := WebhookPayload{Id: "evt_" + hex.EncodeToString(random), Type: event.Type, CreatedAt: event.Time, Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		delivery := entities.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        payload.Id,
+			EventType:      event.Type,
+			Payload:        string(body),
+			Status:         entities.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+			CreatedAt:      time.Now(),
+		}
+		if _, err := (*service.repo).CreateDelivery(&delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverDue sends the deliveries that are due and returns how many were attempted
+func (service *webhookService) DeliverDue() (int, error) {
+	// twice the request timeout, a claimed delivery is either sent or due again by then
+	deliveries, err := (*service.repo).ClaimDueDeliveries(time.Now(), service.config.BatchSize, 2*service.config.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		subscription, err := (*service.repo).FindSubscription(delivery.SubscriptionId)
+		if err == nil && !subscription.Active {
+			err = errors.New("subscription is not active")
+		}
+
+		if err != nil {
+			delivery.Status, delivery.LastError = entities.WebhookDeliveryDead, err.Error()
+		} else {
+			service.attempt(&subscription, delivery)
+		}
+
+		if err := (*service.repo).SaveDelivery(delivery); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt posts the delivery once and schedules the next attempt or dead-letters it when it fails
+func (service *webhookService) attempt(subscription *entities.WebhookSubscription, delivery *entities.WebhookDelivery) {
+	delivery.Attempts++
+	statusCode, err := service.post(subscription, delivery)
+	delivery.LastStatusCode = statusCode
+
+	now := time.Now()
+	if err == nil {
+		delivery.Status, delivery.LastError, delivery.DeliveredAt = entities.WebhookDeliverySucceeded, "", &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= service.config.MaxAttempts {
+		delivery.Status = entities.WebhookDeliveryDead
+		return
+	}
+	delivery.NextAttemptAt = now.Add(WebhookBackoff(service.config, delivery.Attempts))
+}
+
+func (service *webhookService) post(subscription *entities.WebhookSubscription, delivery *entities.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequest(http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "acy-webhooks/1.0")
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.Id), 10))
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(response.Body, webhookErrorLimit))
+		return response.StatusCode, fmt.Errorf("unexpected status %d: %s", response.StatusCode, excerpt)
+	}
+	io.Copy(io.Discard, io.LimitReader(response.Body, webhookErrorLimit))
+	return response.StatusCode, nil
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature value, "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret. Receivers should also reject old timestamps.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff is the delay before the attempt following attempts failed ones
+func WebhookBackoff(config WebhookConfig, attempts int) time.Duration {
+	delay := config.BackoffBase
+	for i := 1; i < attempts && delay < config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > config.BackoffMax {
+		delay = config.BackoffMax
+	}
+	return delay
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	libs "acy.com/api/src/lib"
+	"go.uber.org/zap"
+)
+
+// RunWebhookWorker queues a delivery for every event published on bus and sends due deliveries every
+// config.PollInterval until ctx is done.
+func RunWebhookWorker(ctx context.Context, service *IWebhookService, bus *libs.EventBus, config WebhookConfig, logger *zap.Logger) {
+	go enqueueWebhookEvents(ctx, service, bus, logger)
+
+	ticker := time.NewTicker(config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// keep sending while full batches come back, so a backlog does not wait for the next tick
+			for {
+				sent, err := (*service).DeliverDue()
+				if err != nil {
+					logger.Error("webhook delivery failed", zap.String("error", err.Error()))
+				}
+				if err != nil || sent < config.BatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+func enqueueWebhookEvents(ctx context.Context, service *IWebhookService, bus *libs.EventBus, logger *zap.Logger) {
+	subscription := bus.Subscribe(0)
+	var lastEventId uint64
+	for {
+		select {
+		case <-ctx.Done():
+			subscription.Close()
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// dropped for falling behind, resume from the replay buffer
+				subscription = bus.Subscribe(lastEventId)
+				if subscription.Missed {
+					logger.Warn("webhook events were lost", zap.Uint64("after_event_id", lastEventId))
+				}
+				for _, event := range subscription.Replay {
+					enqueueWebhookEvent(service, event, logger)
+					lastEventId = event.Id
+				}
+				continue
+			}
+			enqueueWebhookEvent(service, event, logger)
+			lastEventId = event.Id
+		}
+	}
+}
+
+func enqueueWebhookEvent(service *IWebhookService, event libs.Event, logger *zap.Logger) {
+	if err := (*service).Enqueue(event); err != nil {
+		logger.Error("unable to queue webhook deliveries",
+			zap.Uint64("event_id", event.Id),
+			zap.String("event_type", event.Type),
+			zap.String("error", err.Error()),
+		)
+	}
+}
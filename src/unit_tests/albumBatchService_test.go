@@ -0,0 +1,177 @@
+package repository_test
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/shopspring/decimal"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+var _ = Describe("Album batches", func() {
+	var albumRepo *fakeAlbumRepository
+	var mongoRepo *fakeAlbumMongoRepository
+	var revisions *fakeRevisionService
+	var reviews *fakeReviewService
+	var covers *fakeCoverService
+	var batchService services.IAlbumBatchService
+
+	price := decimal.RequireFromString("12.50")
+	album := func(title, content string) *models.CreateAlbumDto {
+		return &models.CreateAlbumDto{Title: title, ArtistId: 1, Price: &price, Currency: "USD", Content: content}
+	}
+	actor := services.AuditActor{Actor: "editor"}
+
+	BeforeEach(func() {
+		albumRepo = newFakeAlbumRepository(
+			entities.Album{Id: 1, Title: "Blue Train", ArtistId: 1, Price: price, Currency: "USD"},
+			entities.Album{Id: 2, Title: "Giant Steps", ArtistId: 1, Price: price, Currency: "USD"},
+		)
+		mongoRepo = newFakeAlbumMongoRepository(
+			entities.AlbumMongoDB{AlbumId: 1, Name: "Blue Train", Content: "blue"},
+			entities.AlbumMongoDB{AlbumId: 2, Name: "Giant Steps", Content: "giant"},
+		)
+		revisions = &fakeRevisionService{}
+		reviews = &fakeReviewService{}
+		covers = &fakeCoverService{}
+		var repo repositories.IAlbumRepository = albumRepo
+		var contentRepo repositories.IAlbumMongoDBRepository = mongoRepo
+		var artists services.IArtistService = fakeArtistService{}
+		var genres services.IGenreService = fakeGenreService{}
+		var audit services.IAuditService = &fakeAuditService{repo: albumRepo.audits}
+		var revisionService services.IContentRevisionService = revisions
+		var reviewService services.IReviewService = reviews
+		var coverService services.ICoverService = covers
+		batchService = services.AlbumBatchService(&repo, &contentRepo, &artists, &genres, &audit, &revisionService, &reviewService, &coverService, libs.NewEventBus(10))
+	})
+
+	operations := func() []models.BatchAlbumOperationDto {
+		return []models.BatchAlbumOperationDto{
+			{Op: models.BatchOpUpdate, Id: 1, Album: album("Blue Train (Remastered)", "bluer")},
+			{Op: models.BatchOpDelete, Id: 2},
+			{Op: models.BatchOpCreate, Album: album("A Love Supreme", "supreme")},
+		}
+	}
+
+	It("applies every operation to both databases", func() {
+		response := batchService.Execute(&models.BatchAlbumDto{Operations: operations()}, actor)
+
+		Expect(response.Succeeded).To(Equal(3))
+		Expect(albumRepo.albums).To(HaveLen(2))
+		Expect(albumRepo.albums[1].Title).To(Equal("Blue Train (Remastered)"))
+		Expect(mongoRepo.documents[1].Content).To(Equal("bluer"))
+		Expect(mongoRepo.documents).NotTo(HaveKey(uint(2)))
+		Expect(mongoRepo.released).To(Equal(1))
+		Expect(revisions.recorded).To(ConsistOf("bluer", "supreme"))
+		Expect(reviews.deleted).To(Equal([]uint{2}))
+		Expect(covers.deleted).To(Equal([]uint{2}))
+		Expect(albumRepo.audits.actions()).To(Equal([]string{models.BatchOpUpdate, models.BatchOpDelete, models.BatchOpCreate}))
+	})
+
+	It("stays applied when a content revision can not be recorded", func() {
+		revisions.err = errors.New("revisions collection is unavailable")
+
+		response := batchService.Execute(&models.BatchAlbumDto{Operations: operations()}, actor)
+
+		Expect(response.Succeeded).To(Equal(3))
+		Expect(mongoRepo.documents[1].Content).To(Equal("bluer"))
+		Expect(revisions.recorded).To(BeEmpty())
+	})
+
+	It("puts the content back when a content write fails in all_or_nothing mode", func() {
+		mongoRepo.failOn[101] = true
+
+		response := batchService.Execute(&models.BatchAlbumDto{Operations: operations()}, actor)
+
+		Expect(response.Succeeded).To(Equal(0))
+		Expect(response.Results[2].Status).To(Equal(http.StatusBadGateway))
+		Expect(response.Results[0].Status).To(Equal(http.StatusFailedDependency))
+		Expect(albumRepo.albums).To(HaveLen(2))
+		Expect(albumRepo.albums[1].Title).To(Equal("Blue Train"))
+		Expect(mongoRepo.reverted).To(Equal(1))
+		Expect(mongoRepo.documents[1].Content).To(Equal("blue"))
+		Expect(mongoRepo.documents[2].Content).To(Equal("giant"))
+		Expect(revisions.recorded).To(BeEmpty())
+		Expect(albumRepo.audits.entries).To(BeEmpty())
+	})
+
+	It("is not applied when the audit log can not be written", func() {
+		albumRepo.audits.err = errors.New("audit table is locked")
+
+		response := batchService.Execute(&models.BatchAlbumDto{Mode: models.BatchModeBestEffort, Operations: operations()}, actor)
+
+		Expect(response.Succeeded).To(Equal(0))
+		Expect(response.Results[0].Status).To(Equal(http.StatusInternalServerError))
+		Expect(response.Results[0].Error).To(ContainSubstring("audit table is locked"))
+		Expect(albumRepo.albums).To(HaveLen(2))
+		Expect(albumRepo.albums[1].Title).To(Equal("Blue Train"))
+		// the delete wrote its content on its own
+		Expect(mongoRepo.reverted).To(Equal(2))
+		Expect(mongoRepo.documents[2].Content).To(Equal("giant"))
+		Expect(revisions.recorded).To(BeEmpty())
+	})
+
+	It("reports content that could not be put back", func() {
+		mongoRepo.failOn[101] = true
+		mongoRepo.revertErr = errors.New("mongodb is gone")
+
+		response := batchService.Execute(&models.BatchAlbumDto{Operations: operations()}, actor)
+
+		Expect(response.Results[0].Status).To(Equal(http.StatusInternalServerError))
+		Expect(response.Results[0].Error).To(ContainSubstring("mongodb is gone"))
+	})
+
+	It("only discards the failing operation in best_effort mode", func() {
+		mongoRepo.failOn[2] = true
+
+		response := batchService.Execute(&models.BatchAlbumDto{Mode: models.BatchModeBestEffort, Operations: operations()}, actor)
+
+		Expect(response.Succeeded).To(Equal(2))
+		Expect(response.Results[1].Status).To(Equal(http.StatusBadGateway))
+		Expect(albumRepo.albums).To(HaveKey(uint(2)))
+		Expect(albumRepo.albums).To(HaveKey(uint(101)))
+		Expect(mongoRepo.documents[2].Content).To(Equal("giant"))
+		Expect(mongoRepo.reverted).To(Equal(0))
+		Expect(albumRepo.audits.actions()).To(Equal([]string{models.BatchOpUpdate, models.BatchOpCreate}))
+	})
+
+	It("keeps the rows of an album whose content can not be deleted in best_effort mode", func() {
+		albumRepo.tracks.tracks = []entities.Track{{Id: 1, AlbumId: 2, Title: "Giant Steps"}, {Id: 2, AlbumId: 1, Title: "Moment's Notice"}}
+		mongoRepo.failOn[2] = true
+
+		response := batchService.Execute(&models.BatchAlbumDto{Mode: models.BatchModeBestEffort, Operations: operations()}, actor)
+
+		Expect(response.Results[1].Status).To(Equal(http.StatusBadGateway))
+		Expect(albumRepo.albums).To(HaveKey(uint(2)))
+		Expect(albumRepo.tracks.tracks).To(HaveLen(2))
+		Expect(mongoRepo.released).To(Equal(1))
+
+		// a delete that succeeds takes the tracks with it
+		mongoRepo.failOn[2] = false
+		response = batchService.Execute(&models.BatchAlbumDto{Mode: models.BatchModeBestEffort, Operations: operations()[1:2]}, actor)
+		Expect(response.Succeeded).To(Equal(1))
+		Expect(albumRepo.tracks.tracks).To(Equal([]entities.Track{{Id: 2, AlbumId: 1, Title: "Moment's Notice"}}))
+		Expect(mongoRepo.documents).NotTo(HaveKey(uint(2)))
+	})
+
+	It("rolls back every operation when one is invalid in all_or_nothing mode", func() {
+		invalid := operations()
+		invalid[0].Album.Price = &decimal.Decimal{}
+		*invalid[0].Album.Price = decimal.RequireFromString("1.234")
+
+		response := batchService.Execute(&models.BatchAlbumDto{Operations: invalid}, actor)
+
+		Expect(response.Results[0].Status).To(Equal(http.StatusBadRequest))
+		Expect(response.Failed).To(Equal(3))
+		Expect(albumRepo.albums).To(HaveLen(2))
+		Expect(mongoRepo.documents[1].Content).To(Equal("blue"))
+	})
+})
@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Album filters", func() {
+	var repository *repositories.AlbumRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repository = repositories.NewAlbumRepository(db)
+	})
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ShouldNot(HaveOccurred())
+	})
+
+	const genrePredicate = `(EXISTS (SELECT 1 FROM album_genres JOIN genres ON genres.id = album_genres.genre_id WHERE album_genres.album_id = albums.id AND genres.slug = `
+	min, max := decimal.RequireFromString("10"), decimal.RequireFromString("20")
+	filter := repositories.AlbumFilter{ArtistId: 3, Genres: []string{"Jazz"}, Tags: []string{" Live "}, Currency: "USD", MinPrice: &min, MaxPrice: &max}
+	tags := pq.StringArray{"live"}
+
+	It("narrows FindAll by artist, genre, tags and price", func() {
+		sqlSelect := `SELECT * FROM "albums" WHERE artist_id = $1 AND ` + genrePredicate + `$2)) AND tags @> $3 AND currency = $4 AND price >= $5 AND price < $6 LIMIT 3`
+		mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs(3, "jazz", tags, "USD", min, max).WillReturnRows(sqlmock.NewRows(nil))
+
+		albums, err := repository.FindAll(filter, 1, 3)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(albums).Should(Equal([]entities.Album{}))
+	})
+
+	It("counts every facet without its own filter", func() {
+		counts := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"value", "name", "count"}) }
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "albums" WHERE artist_id = $1 AND `+genrePredicate+`$2)) AND tags @> $3 AND currency = $4 AND price >= $5 AND price < $6`)).
+			WithArgs(3, "jazz", tags, "USD", min, max).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "album_genres" JOIN genres ON genres.id = album_genres.genre_id WHERE album_genres.album_id IN (SELECT albums.id FROM "albums" WHERE artist_id = $1 AND tags @> $2 AND currency = $3 AND price >= $4 AND price < $5)`)).
+			WithArgs(3, tags, "USD", min, max).
+			WillReturnRows(counts().AddRow("jazz", "Jazz", 1).AddRow("soul", "Soul", 4))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM albums, unnest(albums.tags) AS tag WHERE albums.id IN (SELECT albums.id FROM "albums" WHERE artist_id = $1 AND `+genrePredicate+`$2)) AND currency = $3 AND price >= $4 AND price < $5)`)).
+			WithArgs(3, "jazz", "USD", min, max).
+			WillReturnRows(counts())
+		mock.ExpectQuery(regexp.QuoteMeta(`JOIN artists ON artists.id = albums.artist_id WHERE albums.id IN (SELECT albums.id FROM "albums" WHERE `+genrePredicate+`$1)) AND tags @> $2 AND currency = $3 AND price >= $4 AND price < $5)`)).
+			WithArgs("jazz", tags, "USD", min, max).
+			WillReturnRows(counts())
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE albums.id IN (SELECT albums.id FROM "albums" WHERE artist_id = $2 AND `+genrePredicate+`$3)) AND tags @> $4 AND currency = $5) GROUP BY currency, bucket`)).
+			WithArgs(sqlmock.AnyArg(), 3, "jazz", tags, "USD").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "bucket", "count"}).AddRow("USD", 1, 1).AddRow("USD", 4, 2))
+
+		facets, err := repository.Facets(filter)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(facets.Total).Should(Equal(int64(1)))
+		Expect(facets.Genres).Should(HaveLen(2))
+		Expect(facets.Genres[1]).Should(Equal(repositories.FacetCount{Value: "soul", Name: "Soul", Count: 4}))
+		Expect(facets.Prices).Should(HaveLen(1))
+		Expect(facets.Prices[0].Currency).Should(Equal("USD"))
+		prices := facets.Prices[0].Buckets
+		Expect(prices).Should(HaveLen(len(repositories.AlbumPriceBuckets) + 1))
+		Expect(prices[1].Count).Should(Equal(int64(1)))
+		Expect(prices[4].Count).Should(Equal(int64(2)))
+		Expect(prices[4].To).Should(BeNil())
+	})
+
+	It("buckets the prices of every currency on their own, the currencies with the most albums first", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "albums"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+		mock.ExpectQuery(`FROM "album_genres"`).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(`unnest`).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(`JOIN artists`).WillReturnRows(sqlmock.NewRows(nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`GROUP BY currency, bucket`)).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "bucket", "count"}).
+				AddRow("EUR", 1, 1).AddRow("JPY", 4, 3).AddRow("USD", 0, 1).AddRow("USD", 2, 1))
+
+		facets, err := repository.Facets(repositories.AlbumFilter{})
+		Expect(err).ShouldNot(HaveOccurred())
+		currencies := []string{}
+		for _, prices := range facets.Prices {
+			currencies = append(currencies, prices.Currency)
+		}
+		Expect(currencies).Should(Equal([]string{"JPY", "USD", "EUR"}))
+		Expect(facets.Prices[0].Buckets[4].Count).Should(Equal(int64(3)))
+		Expect(facets.Prices[1].Buckets[0].Count).Should(Equal(int64(1)))
+		Expect(facets.Prices[1].Buckets[2].Count).Should(Equal(int64(1)))
+	})
+
+	It("never compares prices without their currency", func() {
+		_, err := repository.FindAll(repositories.AlbumFilter{MinPrice: &min}, 1, 3)
+		Expect(err).Should(Equal(repositories.ErrPriceRangeWithoutCurrency))
+	})
+})
@@ -3,11 +3,13 @@ package repository_test
 import (
 	"database/sql"
 	"regexp"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
 
 	"acy.com/api/src/entities"
 	"acy.com/api/src/repositories"
@@ -32,19 +34,30 @@ var _ = Describe("Test Repository", func() {
 		Expect(err).ShouldNot(HaveOccurred())
 	})
 
+	const sqlSelectArtist = `SELECT * FROM "artists" WHERE "artists"."id" = $1`
+	const sqlSelectAlbumGenres = `SELECT * FROM "album_genres" WHERE "album_genres"."album_id"`
+	fakeArtist := &entities.Artist{ Id: 1, Name: "Fake Artist", NameKey: "fake artist" }
+	artistRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "name_key"}).AddRow(fakeArtist.Id, fakeArtist.Name, fakeArtist.NameKey)
+	}
+
 	Context("FindAll", func() {
 		It("found", func(){
 			const  sqlSelectAll = `SELECT * FROM "albums"`
-			fakeAlbum1 := &entities.Album{ Id: 1, Title: "fake title 1", Artist: "fake artist 1", Price: 100.20, HasRead: true }
-			fakeAlbum2 := &entities.Album{ Id: 2, Title: "fake title 2", Artist: "fake artist 1", Price: 20.40, HasRead: false }
+			fakeAlbum1 := &entities.Album{ Id: 1, Title: "fake title 1", ArtistId: 1, Price: decimal.RequireFromString("100.20"), Currency: "USD" }
+			fakeAlbum2 := &entities.Album{ Id: 2, Title: "fake title 2", ArtistId: 1, Price: decimal.RequireFromString("20.40"), Currency: "USD" }
 			rows := sqlmock.
-							NewRows([]string{"id", "title", "artist", "price", "has_read"}).
-							AddRow(fakeAlbum1.Id, fakeAlbum1.Title, fakeAlbum1.Artist, fakeAlbum1.Price, fakeAlbum1.HasRead).
-							AddRow(fakeAlbum2.Id, fakeAlbum2.Title, fakeAlbum2.Artist, fakeAlbum2.Price, fakeAlbum2.HasRead)
+							NewRows([]string{"id", "title", "artist_id", "price", "currency"}).
+							AddRow(fakeAlbum1.Id, fakeAlbum1.Title, fakeAlbum1.ArtistId, fakeAlbum1.Price.StringFixed(2), fakeAlbum1.Currency).
+							AddRow(fakeAlbum2.Id, fakeAlbum2.Title, fakeAlbum2.ArtistId, fakeAlbum2.Price.StringFixed(2), fakeAlbum2.Currency)
 
 				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAll)).WillReturnRows(rows)
+				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectArtist)).WithArgs(fakeArtist.Id).WillReturnRows(artistRows())
+				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAlbumGenres)).WillReturnRows(sqlmock.NewRows([]string{"album_id", "genre_id"}))
+				fakeAlbum1.Artist, fakeAlbum2.Artist = fakeArtist, fakeArtist
+				fakeAlbum1.Genres, fakeAlbum2.Genres = []entities.Genre{}, []entities.Genre{}
 
-				albums, err := repository.FindAll(0, 3)
+				albums, err := repository.FindAll(repositories.AlbumFilter{}, 0, 3)
 				Expect(err).ShouldNot(HaveOccurred())
 				Expect(albums).Should(Equal([]entities.Album{ *fakeAlbum1, *fakeAlbum2 } ))
 			})
@@ -52,21 +65,61 @@ var _ = Describe("Test Repository", func() {
 		It("not found", func() {
                 // ignore sql match
                 mock.ExpectQuery(`.+`).WillReturnRows(sqlmock.NewRows(nil))
-                albums, err := repository.FindAll(0, 3)
+                albums, err := repository.FindAll(repositories.AlbumFilter{}, 0, 3)
 				Expect(err).ShouldNot(HaveOccurred())
                 Expect(albums).Should(Equal([]entities.Album{}))
         })
+
+		It("updated since", func() {
+			const sqlSelectUpdated = `SELECT * FROM "albums" WHERE updated_at > $1 ORDER BY updated_at,id LIMIT 3`
+			since := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelectUpdated)).WithArgs(since).WillReturnRows(sqlmock.NewRows(nil))
+
+			albums, err := repository.FindAll(repositories.AlbumFilter{UpdatedSince: &since}, 0, 3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(albums).Should(Equal([]entities.Album{}))
+		})
+
+		It("after a cursor", func() {
+			const sqlSelectAfter = `SELECT * FROM "albums" WHERE updated_at > $1 AND (updated_at, id) > ($2, $3) ORDER BY updated_at,id LIMIT 3`
+			since := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+			cursor := repositories.AlbumCursor{UpdatedAt: time.Date(2022, 5, 2, 10, 30, 0, 123456000, time.UTC), Id: 7}
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAfter)).WithArgs(since, cursor.UpdatedAt, cursor.Id).WillReturnRows(sqlmock.NewRows(nil))
+
+			// the offset of page 5 is replaced by the cursor
+			albums, err := repository.FindAll(repositories.AlbumFilter{UpdatedSince: &since, After: &cursor}, 5, 3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(albums).Should(Equal([]entities.Album{}))
+		})
+	})
+
+	Context("AlbumCursor", func() {
+		It("round trips", func() {
+			cursor := repositories.AlbumCursor{UpdatedAt: time.Date(2022, 5, 2, 10, 30, 0, 123456000, time.UTC), Id: 7}
+			parsed, err := repositories.ParseAlbumCursor(cursor.String())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(parsed).Should(Equal(cursor))
+		})
+
+		It("rejects tokens it did not make", func() {
+			_, err := repositories.ParseAlbumCursor("not a cursor")
+			Expect(err).Should(MatchError(repositories.ErrInvalidAlbumCursor))
+		})
 	})
 
 	Context("FindById", func() {
 		It("found", func(){
 			const  sqlSelectAll = `SELECT * FROM "albums" WHERE "id" = $1`
-			fakeAlbum2 := &entities.Album{ Id: 2, Title: "fake title 2", Artist: "fake artist 1", Price: 20.40, HasRead: false }
+			fakeAlbum2 := &entities.Album{ Id: 2, Title: "fake title 2", ArtistId: 1, Price: decimal.RequireFromString("20.40"), Currency: "USD" }
 			rows := sqlmock.
-							NewRows([]string{"id", "title", "artist", "price", "has_read"}).
-							AddRow(fakeAlbum2.Id, fakeAlbum2.Title, fakeAlbum2.Artist, fakeAlbum2.Price, fakeAlbum2.HasRead)
+							NewRows([]string{"id", "title", "artist_id", "price", "currency"}).
+							AddRow(fakeAlbum2.Id, fakeAlbum2.Title, fakeAlbum2.ArtistId, fakeAlbum2.Price.StringFixed(2), fakeAlbum2.Currency)
 
 				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAll)).WithArgs(fakeAlbum2.Id).WillReturnRows(rows)
+				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectArtist)).WithArgs(fakeArtist.Id).WillReturnRows(artistRows())
+				mock.ExpectQuery(regexp.QuoteMeta(sqlSelectAlbumGenres)).WithArgs(fakeAlbum2.Id).WillReturnRows(sqlmock.NewRows([]string{"album_id", "genre_id"}))
+				fakeAlbum2.Artist = fakeArtist
+				fakeAlbum2.Genres = []entities.Genre{}
 
 				albums, err := repository.FindById(2)
 				Expect(err).ShouldNot(HaveOccurred())
@@ -83,21 +136,25 @@ var _ = Describe("Test Repository", func() {
 	})
 
 	Context("Create", func() {
-		fakeAlbum1 := &entities.Album{ Id: 1, Title: "fake title 1", Artist: "fake artist 1", Price: 100.20, HasRead: true }
+		fakeAlbum1 := &entities.Album{ Id: 1, Title: "fake title 1", ArtistId: 1, Price: decimal.RequireFromString("100.20"), Currency: "USD" }
 		It("created", func(){
-			const sqlInsert = `INSERT INTO "albums" ("title","artist","price","has_read","id") 
-                                        VALUES ($1,$2,$3,$4,$5) RETURNING "id"`
+			const sqlInsert = `INSERT INTO "albums" ("title","artist_id","price","currency","tags","created_at","updated_at","created_by","updated_by","id") 
+                                        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`
 			
 			rows := sqlmock.
-							NewRows([]string{"id", "title", "artist", "price", "has_read"}).
-							AddRow(fakeAlbum1.Id, fakeAlbum1.Title, fakeAlbum1.Artist, fakeAlbum1.Price, fakeAlbum1.HasRead)
+							NewRows([]string{"id", "title", "artist_id", "price", "currency"}).
+							AddRow(fakeAlbum1.Id, fakeAlbum1.Title, fakeAlbum1.ArtistId, fakeAlbum1.Price.StringFixed(2), fakeAlbum1.Currency)
 			mock.ExpectBegin() // begin transaction
-			mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WithArgs(fakeAlbum1.Title, fakeAlbum1.Artist, fakeAlbum1.Price, fakeAlbum1.HasRead, fakeAlbum1.Id).WillReturnRows(rows)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).
+				WithArgs(fakeAlbum1.Title, fakeAlbum1.ArtistId, fakeAlbum1.Price, fakeAlbum1.Currency, "{}", sqlmock.AnyArg(), sqlmock.AnyArg(), "user-1", "user-1", fakeAlbum1.Id).
+				WillReturnRows(rows)
 			mock.ExpectCommit() // commit transaction
 
-			albums, err := repository.Create(fakeAlbum1)
+			albums, err := repository.WithActor("user-1").Create(fakeAlbum1)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(albums).Should(Equal(*fakeAlbum1))
+			Expect(albums.CreatedBy).Should(Equal("user-1"))
+			Expect(albums.CreatedAt).ShouldNot(BeZero())
 		})
 	})
 })
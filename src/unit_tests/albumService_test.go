@@ -0,0 +1,213 @@
+package repository_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+var _ = Describe("Album service", func() {
+	var albumRepo *fakeAlbumRepository
+	var mongoRepo *fakeAlbumMongoRepository
+	var reviews *fakeReviewService
+	var covers *fakeCoverService
+	var albumService services.IAlbumService
+	var bus *libs.EventBus
+
+	price := decimal.RequireFromString("9.99")
+	actor := services.AuditActor{Actor: "editor"}
+
+	BeforeEach(func() {
+		albumRepo = newFakeAlbumRepository(entities.Album{Id: 1, Title: "Blue Train", ArtistId: 1, Price: price, Currency: "USD"})
+		mongoRepo = newFakeAlbumMongoRepository(entities.AlbumMongoDB{AlbumId: 1, Name: "Blue Train", Content: "blue"})
+		var repo repositories.IAlbumRepository = albumRepo
+		var contentRepo repositories.IAlbumMongoDBRepository = mongoRepo
+		reviews = &fakeReviewService{}
+		var reviewService services.IReviewService = reviews
+		covers = &fakeCoverService{}
+		var coverService services.ICoverService = covers
+		var audit services.IAuditService = &fakeAuditService{repo: albumRepo.audits}
+		bus = libs.NewEventBus(10)
+		albumService = services.AlbumService(&repo, &contentRepo, &reviewService, &coverService, &audit, bus)
+	})
+
+	Context("Create", func() {
+		newAlbum := func() (*entities.Album, *entities.AlbumMongoDB) {
+			return &entities.Album{Title: "Giant Steps", ArtistId: 1, Price: price, Currency: "USD"},
+				&entities.AlbumMongoDB{ID: primitive.NewObjectID(), Name: "Giant Steps", Content: "giant"}
+		}
+
+		It("writes the album, its content and the audit entry before publishing", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+
+			album, content := newAlbum()
+			created, err := albumService.Create(album, content, actor)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			event := <-subscription.Events
+			Expect(event.Type).To(Equal(models.AlbumEventCreated))
+			Expect(event.Data.(models.AlbumEventResponse).AlbumId).To(Equal(created.Id))
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(albumRepo.albums).To(HaveKey(created.Id))
+			Expect(mongoRepo.documents[created.Id].Content).To(Equal("giant"))
+			Expect(albumRepo.audits.actions()).To(Equal([]string{entities.AuditActionCreate}))
+		})
+
+		It("numbers the track listing per disc within the transaction", func() {
+			album, content := newAlbum()
+			album.Tracks = []entities.Track{
+				{Title: "Naima", Number: 2},
+				{Title: "Bonus", DiscNumber: 2},
+				{Title: "Giant Steps", Number: 1},
+				{Title: "Untitled"},
+			}
+			created, err := albumService.Create(album, content, actor)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			titles := []string{}
+			for _, track := range created.Tracks {
+				Expect(track.AlbumId).To(Equal(created.Id))
+				titles = append(titles, fmt.Sprintf("%d-%d %s", track.DiscNumber, track.Number, track.Title))
+			}
+			Expect(titles).To(Equal([]string{"1-1 Giant Steps", "1-2 Naima", "1-3 Untitled", "2-1 Bonus"}))
+		})
+
+		It("keeps no album when a track can not be written", func() {
+			albumRepo.tracks.failOn = "Naima"
+			album, content := newAlbum()
+			album.Tracks = []entities.Track{{Title: "Giant Steps"}, {Title: "Naima"}}
+			_, err := albumService.Create(album, content, actor)
+
+			Expect(err).Should(HaveOccurred())
+			Expect(albumRepo.albums).To(HaveLen(1))
+			Expect(albumRepo.tracks.tracks).To(BeEmpty())
+			Expect(mongoRepo.documents).To(HaveLen(1))
+			Expect(albumRepo.audits.entries).To(BeEmpty())
+		})
+
+		It("keeps neither the album nor its content when the audit entry can not be written", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+			albumRepo.audits.err = errors.New("audit table is locked")
+			album, content := newAlbum()
+			_, err := albumService.Create(album, content, actor)
+
+			Expect(err).Should(MatchError("audit table is locked"))
+			Expect(albumRepo.albums).To(HaveLen(1))
+			Expect(mongoRepo.documents).To(HaveLen(1))
+			Expect(subscription.Events).To(BeEmpty())
+		})
+	})
+
+	Context("Update", func() {
+		It("saves the genres and tags and records the change before publishing", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+
+			genres := []entities.Genre{{Id: 4, Name: "Jazz", Slug: "jazz"}}
+			updated, err := albumService.Update(&entities.Album{Id: 1, Title: "Blue Train", ArtistId: 1, Price: price, Currency: "USD", Genres: genres, Tags: []string{"hard bop"}}, actor)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(subscription.Events).To(Receive(HaveField("Type", models.AlbumEventUpdated)))
+
+			Expect(updated.Genres).To(Equal(genres))
+			Expect(albumRepo.albums[1].Genres).To(Equal(genres))
+			Expect([]string(albumRepo.albums[1].Tags)).To(Equal([]string{"hard bop"}))
+			Expect(albumRepo.audits.actions()).To(Equal([]string{entities.AuditActionUpdate}))
+		})
+
+		It("keeps the album as it was when the audit entry can not be written", func() {
+			albumRepo.audits.err = errors.New("audit table is locked")
+
+			_, err := albumService.Update(&entities.Album{Id: 1, Title: "Lush Life", ArtistId: 1, Price: price, Currency: "USD"}, actor)
+			Expect(err).Should(HaveOccurred())
+			Expect(albumRepo.albums[1].Title).To(Equal("Blue Train"))
+		})
+
+		It("reports albums that do not exist", func() {
+			_, err := albumService.Update(&entities.Album{Id: 2, Title: "Lush Life", ArtistId: 1, Price: price, Currency: "USD"}, actor)
+			Expect(err).Should(MatchError(repositories.ErrAlbumNotFound))
+			Expect(albumRepo.audits.entries).To(BeEmpty())
+		})
+	})
+
+	Context("ContentWritten", func() {
+		It("marks the album updated and records the content write in the same transaction", func() {
+			before := mongoRepo.documents[1]
+			touched, err := albumService.ContentWritten(1, &before, &entities.AlbumMongoDB{AlbumId: 1, Name: "Blue Train", Content: "train"}, entities.AuditActionUpdate, actor)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(touched.UpdatedAt).ShouldNot(BeZero())
+			Expect(albumRepo.albums[1].UpdatedAt).To(Equal(touched.UpdatedAt))
+			Expect(albumRepo.audits.actions()).To(Equal([]string{entities.AuditActionUpdate}))
+		})
+
+		It("leaves the album as it was when the audit entry can not be written", func() {
+			albumRepo.audits.err = errors.New("audit table is locked")
+
+			_, err := albumService.ContentWritten(1, nil, &entities.AlbumMongoDB{AlbumId: 1, Content: "train"}, entities.AuditActionUpdate, actor)
+			Expect(err).Should(HaveOccurred())
+			Expect(albumRepo.albums[1].UpdatedAt).To(BeZero())
+		})
+
+		It("reports albums that do not exist", func() {
+			_, err := albumService.ContentWritten(2, nil, &entities.AlbumMongoDB{AlbumId: 2, Content: "train"}, entities.AuditActionUpdate, actor)
+			Expect(err).Should(MatchError(repositories.ErrAlbumNotFound))
+			Expect(albumRepo.audits.entries).To(BeEmpty())
+		})
+	})
+
+	Context("Delete", func() {
+		It("deletes the album and its content and records what was deleted", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+
+			Expect(albumService.Delete(1, actor)).Should(Succeed())
+			Expect(subscription.Events).To(Receive(HaveField("Type", models.AlbumEventDeleted)))
+
+			Expect(albumRepo.albums).To(BeEmpty())
+			Expect(mongoRepo.documents).To(BeEmpty())
+			Expect(reviews.deleted).To(Equal([]uint{1}))
+			Expect(covers.deleted).To(Equal([]uint{1}))
+			Expect(albumRepo.audits.actions()).To(Equal([]string{entities.AuditActionDelete}))
+		})
+
+		It("deletes the album when its reviews or cover images can not be deleted", func() {
+			reviews.err = errors.New("mongodb is unavailable")
+			covers.err = errors.New("permission denied")
+
+			Expect(albumService.Delete(1, actor)).Should(Succeed())
+			Expect(albumRepo.albums).To(BeEmpty())
+		})
+
+		It("keeps the album and its content when the audit entry can not be written", func() {
+			albumRepo.audits.err = errors.New("audit table is locked")
+
+			Expect(albumService.Delete(1, actor)).ShouldNot(Succeed())
+			Expect(albumRepo.albums).To(HaveKey(uint(1)))
+			Expect(mongoRepo.documents).To(HaveKey(uint(1)))
+			Expect(reviews.deleted).To(BeEmpty())
+			Expect(covers.deleted).To(BeEmpty())
+		})
+
+		It("reports albums that do not exist", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+
+			Expect(albumService.Delete(2, actor)).Should(MatchError(repositories.ErrAlbumNotFound))
+			Expect(albumRepo.audits.entries).To(BeEmpty())
+			Expect(subscription.Events).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,183 @@
+package repository_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/middlewares"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeApiKeyRepository keeps api keys in memory by hash, its calls return err
+type fakeApiKeyRepository struct {
+	keys    map[string]entities.ApiKey
+	touched int
+	err     error
+}
+
+func (repo *fakeApiKeyRepository) FindAll() ([]entities.ApiKey, error) {
+	keys := []entities.ApiKey{}
+	for _, key := range repo.keys {
+		keys = append(keys, key)
+	}
+	return keys, repo.err
+}
+func (repo *fakeApiKeyRepository) FindByHash(keyHash string) (entities.ApiKey, error) {
+	return repo.keys[keyHash], repo.err
+}
+func (repo *fakeApiKeyRepository) Create(newKey *entities.ApiKey) (entities.ApiKey, error) {
+	newKey.Id = uint(len(repo.keys) + 1)
+	repo.keys[newKey.KeyHash] = *newKey
+	return *newKey, repo.err
+}
+func (repo *fakeApiKeyRepository) Revoke(id uint, at time.Time) error {
+	for hash, key := range repo.keys {
+		if key.Id == id && key.RevokedAt == nil {
+			key.RevokedAt = &at
+			repo.keys[hash] = key
+			return repo.err
+		}
+	}
+	return repositories.ErrApiKeyNotFound
+}
+func (repo *fakeApiKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	repo.touched++
+	for hash, key := range repo.keys {
+		if key.Id == id {
+			key.LastUsedAt = &at
+			repo.keys[hash] = key
+		}
+	}
+	return repo.err
+}
+
+var _ = Describe("Api keys", func() {
+	var repo *fakeApiKeyRepository
+	var apiKeyService services.IApiKeyService
+
+	BeforeEach(func() {
+		repo = &fakeApiKeyRepository{keys: map[string]entities.ApiKey{}}
+		var apiKeyRepo repositories.IApiKeyRepository = repo
+		apiKeyService = services.ApiKeyService(&apiKeyRepo)
+	})
+
+	create := func(expiresAt *time.Time, scopes ...string) (entities.ApiKey, string) {
+		apiKey, key, err := apiKeyService.Create("importer", scopes, expiresAt)
+		Expect(err).ShouldNot(HaveOccurred())
+		return apiKey, key
+	}
+
+	Context("Create", func() {
+		It("stores the sha256 hash and a prefix of the key, never the key", func() {
+			apiKey, key := create(nil, middlewares.RoleEditor)
+			sum := sha256.Sum256([]byte(key))
+
+			Expect(key).Should(HavePrefix("acy_"))
+			Expect(apiKey.KeyHash).Should(Equal(hex.EncodeToString(sum[:])))
+			Expect(apiKey.Prefix).Should(Equal(key[:12]))
+			Expect(repo.keys).Should(HaveKey(apiKey.KeyHash))
+			for _, stored := range repo.keys {
+				Expect(stored.KeyHash).ShouldNot(ContainSubstring(key[4:]))
+			}
+		})
+
+		It("makes a different key every time", func() {
+			_, first := create(nil)
+			_, second := create(nil)
+			Expect(first).ShouldNot(Equal(second))
+		})
+	})
+
+	Context("Authenticate", func() {
+		It("resolves a key and touches it once per interval", func() {
+			created, key := create(nil, middlewares.RoleReader)
+
+			apiKey, err := apiKeyService.Authenticate(key)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(apiKey.Id).Should(Equal(created.Id))
+			Expect(apiKey.LastUsedAt).ShouldNot(BeNil())
+
+			_, err = apiKeyService.Authenticate(key)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(repo.touched).Should(Equal(1))
+		})
+
+		It("rejects unknown, expired and revoked keys", func() {
+			_, err := apiKeyService.Authenticate("acy_unknown")
+			Expect(err).Should(Equal(services.ErrInvalidApiKey))
+
+			expired := time.Now().Add(-time.Minute)
+			_, key := create(&expired)
+			_, err = apiKeyService.Authenticate(key)
+			Expect(err).Should(Equal(services.ErrInvalidApiKey))
+
+			future := time.Now().Add(time.Hour)
+			revoked, key := create(&future)
+			Expect(apiKeyService.Revoke(revoked.Id)).Should(Succeed())
+			_, err = apiKeyService.Authenticate(key)
+			Expect(err).Should(Equal(services.ErrInvalidApiKey))
+			Expect(apiKeyService.Revoke(revoked.Id)).Should(Equal(repositories.ErrApiKeyNotFound))
+		})
+
+		It("returns lookup failures as they are", func() {
+			_, key := create(nil)
+			repo.err = errors.New("connection refused")
+			_, err := apiKeyService.Authenticate(key)
+			Expect(err).Should(MatchError("connection refused"))
+		})
+	})
+
+	Context("ApiKeyAuth", func() {
+		var router *gin.Engine
+
+		BeforeEach(func() {
+			gin.SetMode(gin.TestMode)
+			router = gin.New()
+			router.DELETE("/albums/:id", middlewares.Authenticate(nil, &apiKeyService), middlewares.RequireRole(middlewares.RoleEditor), func(c *gin.Context) {
+				c.String(http.StatusOK, middlewares.Subject(c))
+			})
+		})
+
+		request := func(key string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/albums/1", nil)
+			req.Header.Set(middlewares.ApiKeyHeader, key)
+			router.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		It("grants the scopes of the key as roles", func() {
+			created, key := create(nil, middlewares.RoleAdmin)
+			recorder := request(key)
+			Expect(recorder.Code).Should(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).Should(Equal(fmt.Sprintf("apikey:%d", created.Id)))
+
+			_, readerKey := create(nil, middlewares.RoleReader)
+			Expect(request(readerKey).Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("returns 401 for an invalid key", func() {
+			Expect(request("acy_unknown").Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("returns 500 when the key can not be looked up", func() {
+			_, key := create(nil, middlewares.RoleAdmin)
+			repo.err = errors.New("connection refused")
+			recorder := request(key)
+			Expect(recorder.Code).Should(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).ShouldNot(ContainSubstring("connection refused"))
+		})
+	})
+})
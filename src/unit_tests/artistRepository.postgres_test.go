@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Artist repository", func() {
+	var repository *repositories.ArtistRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+		repository = repositories.NewArtistRepository(db)
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).Should(Succeed())
+	})
+
+	It("normalizes names to their key", func() {
+		Expect(repositories.ArtistNameKey("  John   COLTRANE ")).Should(Equal("john coltrane"))
+	})
+
+	It("reports artists that do not exist", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "artists" WHERE id = $1`)).WithArgs(9).WillReturnRows(sqlmock.NewRows(nil))
+
+		_, err := repository.FindById(9)
+		Expect(err).Should(MatchError(repositories.ErrArtistNotFound))
+	})
+
+	Context("FindOrCreate", func() {
+		const sqlInsert = `INSERT INTO "artists" ("name","name_key","created_at","updated_at","created_by","updated_by") VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT DO NOTHING RETURNING "id"`
+		const sqlSelect = `SELECT * FROM "artists" WHERE name_key = $1`
+
+		BeforeEach(func() {
+			// the artist exists already, the insert does nothing
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).
+				WithArgs("John Coltrane", "john coltrane", sqlmock.AnyArg(), sqlmock.AnyArg(), "editor", "editor").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectCommit()
+		})
+
+		It("returns the existing artist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("john coltrane").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "name_key"}).AddRow(3, "John Coltrane", "john coltrane"))
+
+			artist, err := repository.FindOrCreate(" John  Coltrane ", "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(artist.Id).Should(Equal(uint(3)))
+			Expect(artist.Name).Should(Equal("John Coltrane"))
+		})
+
+		It("reports an artist that is gone before it is read", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelect)).WithArgs("john coltrane").WillReturnRows(sqlmock.NewRows(nil))
+
+			artist, err := repository.FindOrCreate("John Coltrane", "editor")
+			Expect(err).Should(MatchError(repositories.ErrArtistNotFound))
+			Expect(artist.Id).Should(BeZero())
+		})
+	})
+})
@@ -0,0 +1,199 @@
+package repository_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeContentRevisionRepository numbers the revisions of every album from 1 in memory
+type fakeContentRevisionRepository struct {
+	revisions []entities.AlbumContentRevisionMongoDB
+}
+
+func (repo *fakeContentRevisionRepository) FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error) {
+	return repo.revisions, nil
+}
+func (repo *fakeContentRevisionRepository) FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error) {
+	for _, candidate := range repo.revisions {
+		if candidate.AlbumId == albumId && candidate.Revision == revision {
+			return candidate, nil
+		}
+	}
+	return entities.AlbumContentRevisionMongoDB{}, repositories.ErrContentRevisionNotFound
+}
+func (repo *fakeContentRevisionRepository) OpenContent(albumId uint, revision int) (io.ReadSeekCloser, entities.AlbumContentRevisionMongoDB, error) {
+	found, err := repo.FindByRevision(albumId, revision)
+	if err != nil {
+		return nil, found, err
+	}
+	content := found.Content
+	found.Content = ""
+	return libs.NopReadSeekCloser(strings.NewReader(content)), found, nil
+}
+func (repo *fakeContentRevisionRepository) Latest(albumId uint) (entities.AlbumContentRevisionMongoDB, error) {
+	latest := entities.AlbumContentRevisionMongoDB{}
+	for _, candidate := range repo.revisions {
+		if candidate.AlbumId == albumId {
+			latest = candidate
+		}
+	}
+	if latest.AlbumId == 0 {
+		return latest, repositories.ErrContentRevisionNotFound
+	}
+	return latest, nil
+}
+func (repo *fakeContentRevisionRepository) Create(revision *entities.AlbumContentRevisionMongoDB, content io.Reader) (entities.AlbumContentRevisionMongoDB, error) {
+	read, err := io.ReadAll(content)
+	if err != nil {
+		return *revision, err
+	}
+	revision.Content = string(read)
+	latest, _ := repo.Latest(revision.AlbumId)
+	revision.Revision = latest.Revision + 1
+	repo.revisions = append(repo.revisions, *revision)
+	return *revision, nil
+}
+
+var _ = Describe("Content revision service", func() {
+	var revisionRepo *fakeContentRevisionRepository
+	var mongoRepo *fakeAlbumMongoRepository
+	var albumRepo *fakeAlbumRepository
+	var audits *fakeAuditRepository
+	var bus *libs.EventBus
+	var revisionService services.IContentRevisionService
+
+	album := entities.Album{Id: 1, Title: "Blue Train"}
+	actor := services.AuditActor{Actor: "editor"}
+
+	BeforeEach(func() {
+		revisionRepo = &fakeContentRevisionRepository{}
+		mongoRepo = newFakeAlbumMongoRepository(entities.AlbumMongoDB{AlbumId: 1, Name: "Blue Train", Content: "side a\nside b\n"})
+		albumRepo = newFakeAlbumRepository(album)
+		audits = albumRepo.audits
+		bus = libs.NewEventBus(10)
+
+		var repo repositories.IContentRevisionMongoDBRepository = revisionRepo
+		var contentRepo repositories.IAlbumMongoDBRepository = mongoRepo
+		var albumRepository repositories.IAlbumRepository = albumRepo
+		var reviewService services.IReviewService = &fakeReviewService{}
+		var coverService services.ICoverService = &fakeCoverService{}
+		var audit services.IAuditService = &fakeAuditService{repo: audits}
+		var albums services.IAlbumService = services.AlbumService(&albumRepository, &contentRepo, &reviewService, &coverService, &audit, bus)
+		revisionService = services.ContentRevisionService(&repo, &contentRepo, &albums, bus)
+
+		Expect(revisionService.Record(entities.AlbumMongoDB{}, entities.AlbumMongoDB{AlbumId: 1, Content: "side a\n"}, "editor")).Should(Succeed())
+		Expect(revisionService.Record(entities.AlbumMongoDB{AlbumId: 1, Content: "side a\n"}, mongoRepo.documents[1], "editor")).Should(Succeed())
+	})
+
+	Context("Record", func() {
+		It("compares and keeps the content that is kept in GridFS", func() {
+			fileId := primitive.NewObjectID()
+			mongoRepo.files[1] = "side a\nside b\n"
+			offloaded := entities.AlbumMongoDB{AlbumId: 1, ContentFileId: &fileId, ContentSize: 14}
+
+			Expect(revisionService.Record(offloaded, offloaded, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(2))
+
+			Expect(revisionService.Record(offloaded, entities.AlbumMongoDB{AlbumId: 1, Content: "side c\n"}, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(3))
+			Expect(revisionRepo.revisions[2].Content).Should(Equal("side c\n"))
+
+			Expect(revisionService.Record(entities.AlbumMongoDB{AlbumId: 1, Content: "side c\n"}, offloaded, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions[3].Content).Should(Equal("side a\nside b\n"))
+		})
+
+		It("compares with the latest revision past the first chunk", func() {
+			long := strings.Repeat("take the a train\n", 4096)
+			Expect(revisionService.Record(mongoRepo.documents[1], entities.AlbumMongoDB{AlbumId: 1, Content: long + "side a\n"}, "editor")).Should(Succeed())
+			Expect(revisionService.Record(mongoRepo.documents[1], entities.AlbumMongoDB{AlbumId: 1, Content: long + "side a\n"}, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(3))
+
+			Expect(revisionService.Record(mongoRepo.documents[1], entities.AlbumMongoDB{AlbumId: 1, Content: long + "side b\n"}, "editor")).Should(Succeed())
+			Expect(revisionService.Record(mongoRepo.documents[1], entities.AlbumMongoDB{AlbumId: 1, Content: long}, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(5))
+			Expect(revisionRepo.revisions[4].Content).Should(Equal(long))
+		})
+
+		It("keeps content written before revisions were kept as the first revision", func() {
+			Expect(revisionService.Record(entities.AlbumMongoDB{AlbumId: 2, Content: "giant", UpdatedBy: "importer"}, entities.AlbumMongoDB{AlbumId: 2, Content: "giant"}, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(2))
+
+			Expect(revisionService.Record(entities.AlbumMongoDB{AlbumId: 2, Content: "giant", UpdatedBy: "importer"}, entities.AlbumMongoDB{AlbumId: 2, Content: "steps"}, "editor")).Should(Succeed())
+			Expect(revisionRepo.revisions).Should(HaveLen(4))
+			Expect(revisionRepo.revisions[2]).Should(And(HaveField("Revision", 1), HaveField("Content", "giant"), HaveField("Author", "importer")))
+			Expect(revisionRepo.revisions[3]).Should(And(HaveField("Revision", 2), HaveField("Content", "steps"), HaveField("Author", "editor")))
+		})
+	})
+
+	Context("Diff", func() {
+		It("counts the changed lines and writes a unified diff", func() {
+			diff, err := revisionService.Diff(1, 1, 2)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(diff.Additions).Should(Equal(1))
+			Expect(diff.Deletions).Should(Equal(0))
+			Expect(diff.Unified).Should(ContainSubstring("--- revision 1"))
+			Expect(diff.Unified).Should(ContainSubstring("+++ revision 2"))
+			Expect(diff.Unified).Should(ContainSubstring("\n side a\n+side b\n"))
+		})
+
+		It("reports revisions that do not exist", func() {
+			_, err := revisionService.Diff(1, 1, 3)
+			Expect(err).Should(MatchError(repositories.ErrContentRevisionNotFound))
+		})
+	})
+
+	Context("Restore", func() {
+		It("writes the revision back and records it as a new one", func() {
+			subscription := bus.Subscribe(0)
+			defer subscription.Close()
+
+			restored, err := revisionService.Restore(album, 1, actor)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(restored.Revision).Should(Equal(3))
+			Expect(restored.RestoredFrom).Should(Equal(1))
+			Expect(restored.Content).Should(Equal("side a\n"))
+			Expect(mongoRepo.documents[1].Content).Should(Equal("side a\n"))
+			Expect(albumRepo.albums[1].UpdatedAt).ShouldNot(BeZero())
+			Expect(audits.actions()).Should(Equal([]string{entities.AuditActionRestore}))
+			var event libs.Event
+			Expect(subscription.Events).Should(Receive(&event))
+			Expect(event.Type).Should(Equal(models.AlbumEventUpdated))
+			Expect(event.Data.(models.AlbumEventResponse).Album.UpdatedAt).Should(Equal(albumRepo.albums[1].UpdatedAt))
+		})
+
+		It("does not restore the content the album has", func() {
+			_, err := revisionService.Restore(album, 2, actor)
+			Expect(err).Should(MatchError(services.ErrContentUnchanged))
+			Expect(revisionRepo.revisions).Should(HaveLen(2))
+		})
+
+		It("leaves the content as it was when it can not be written", func() {
+			mongoRepo.failOn[1] = true
+
+			_, err := revisionService.Restore(album, 1, actor)
+			Expect(err).Should(HaveOccurred())
+			Expect(mongoRepo.documents[1].Content).Should(Equal("side a\nside b\n"))
+			Expect(revisionRepo.revisions).Should(HaveLen(2))
+		})
+
+		It("keeps the restored content when the audit log can not be written", func() {
+			audits.err = errors.New("audit table is locked")
+
+			restored, err := revisionService.Restore(album, 1, actor)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(restored.Revision).Should(Equal(3))
+			Expect(mongoRepo.documents[1].Content).Should(Equal("side a\n"))
+		})
+	})
+})
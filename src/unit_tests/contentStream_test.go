@@ -0,0 +1,138 @@
+package repository_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+var _ = Describe("Content streams", func() {
+	const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+	var reopened []int64
+	var stream io.ReadSeekCloser
+
+	BeforeEach(func() {
+		reopened = []int64{}
+		stream = libs.NewSeekableStream(io.NopCloser(strings.NewReader(content)), int64(len(content)), func(offset int64) (io.ReadCloser, error) {
+			reopened = append(reopened, offset)
+			return io.NopCloser(strings.NewReader(content[offset:])), nil
+		})
+	})
+
+	read := func(n int) string {
+		buffer := make([]byte, n)
+		read, err := io.ReadFull(stream, buffer)
+		Expect(err).ShouldNot(HaveOccurred())
+		return string(buffer[:read])
+	}
+
+	Context("Seek and Read", func() {
+		It("reads forward from the stream it was opened with", func() {
+			Expect(read(4)).Should(Equal("0123"))
+			Expect(read(4)).Should(Equal("4567"))
+			Expect(reopened).Should(BeEmpty())
+		})
+
+		It("reopens the stream at the offset a read after a seek starts from", func() {
+			Expect(stream.Seek(10, io.SeekStart)).Should(Equal(int64(10)))
+			Expect(read(3)).Should(Equal("abc"))
+			Expect(stream.Seek(-3, io.SeekCurrent)).Should(Equal(int64(10)))
+			Expect(stream.Seek(3, io.SeekCurrent)).Should(Equal(int64(13)))
+			Expect(read(3)).Should(Equal("def"))
+			Expect(stream.Seek(-2, io.SeekEnd)).Should(Equal(int64(34)))
+			Expect(read(2)).Should(Equal("yz"))
+			Expect(reopened).Should(Equal([]int64{10, 34}))
+		})
+
+		It("ends at the size and rejects negative positions", func() {
+			_, err := stream.Seek(-1, io.SeekStart)
+			Expect(err).Should(HaveOccurred())
+			Expect(stream.Seek(100, io.SeekStart)).Should(Equal(int64(100)))
+			_, err = stream.Read(make([]byte, 1))
+			Expect(err).Should(Equal(io.EOF))
+			Expect(stream.Close()).Should(Succeed())
+		})
+	})
+
+	Context("Range serving", func() {
+		serve := func(ranges string) *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/albums/1/content", nil)
+			if ranges != "" {
+				request.Header.Set("Range", ranges)
+			}
+			recorder := httptest.NewRecorder()
+			http.ServeContent(recorder, request, "", time.Now(), stream)
+			return recorder
+		}
+
+		It("serves the whole content without a range", func() {
+			response := serve("")
+			Expect(response.Code).Should(Equal(http.StatusOK))
+			Expect(response.Body.String()).Should(Equal(content))
+		})
+
+		It("serves a range from its offset", func() {
+			response := serve("bytes=10-15")
+			Expect(response.Code).Should(Equal(http.StatusPartialContent))
+			Expect(response.Header().Get("Content-Range")).Should(Equal("bytes 10-15/36"))
+			Expect(response.Body.String()).Should(Equal("abcdef"))
+			Expect(reopened).Should(Equal([]int64{10}))
+		})
+
+		It("serves the end of the content", func() {
+			response := serve("bytes=-3")
+			Expect(response.Code).Should(Equal(http.StatusPartialContent))
+			Expect(response.Body.String()).Should(Equal("xyz"))
+		})
+
+		It("serves several ranges as parts", func() {
+			response := serve("bytes=0-1,20-21")
+			Expect(response.Code).Should(Equal(http.StatusPartialContent))
+			Expect(response.Header().Get("Content-Type")).Should(HavePrefix("multipart/byteranges"))
+			Expect(response.Body.String()).Should(ContainSubstring("\r\n\r\n01\r\n"))
+			Expect(response.Body.String()).Should(ContainSubstring("\r\n\r\nkl\r\n"))
+		})
+
+		It("rejects a range past the end", func() {
+			Expect(serve("bytes=100-200").Code).Should(Equal(http.StatusRequestedRangeNotSatisfiable))
+		})
+	})
+
+	Context("Body limits", func() {
+		upload := func(limits middlewares.BodyLimits, body string) (int, error) {
+			var readErr error
+			router := gin.New()
+			router.Use(middlewares.MaxBodySize(limits))
+			router.PUT("/api/v1/albums/:id/content", func(c *gin.Context) {
+				_, readErr = io.ReadAll(c.Request.Body)
+				c.Status(http.StatusOK)
+			})
+			// a reader that is not a strings.Reader leaves the length unknown, like a chunked body
+			request := httptest.NewRequest(http.MethodPut, "/api/v1/albums/1/content", io.MultiReader(strings.NewReader(body)))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			return recorder.Code, readErr
+		}
+
+		It("reads bodies of unknown length ahead unless the route is streamed", func() {
+			code, _ := upload(middlewares.BodyLimits{Default: 8}, content)
+			Expect(code).Should(Equal(http.StatusRequestEntityTooLarge))
+
+			code, err := upload(middlewares.BodyLimits{Default: 8, Streamed: map[string]bool{"PUT /api/v1/albums/:id/content": true}}, content)
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(middlewares.IsBodyTooLarge(err)).Should(BeTrue())
+
+			_, err = upload(middlewares.BodyLimits{Default: 64, Streamed: map[string]bool{"PUT /api/v1/albums/:id/content": true}}, content)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+})
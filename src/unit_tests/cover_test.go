@@ -0,0 +1,234 @@
+package repository_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeCoverRepository keeps covers in memory, saving fails with saveErr
+type fakeCoverRepository struct {
+	covers  map[uint]entities.AlbumCover
+	saveErr error
+}
+
+func (repo *fakeCoverRepository) FindByAlbumIds(albumIds []uint) ([]entities.AlbumCover, error) {
+	covers := []entities.AlbumCover{}
+	for _, albumId := range albumIds {
+		if cover, ok := repo.covers[albumId]; ok {
+			covers = append(covers, cover)
+		}
+	}
+	return covers, nil
+}
+func (repo *fakeCoverRepository) FindByAlbumId(albumId uint) (entities.AlbumCover, error) {
+	cover, ok := repo.covers[albumId]
+	if !ok {
+		return cover, repositories.ErrCoverNotFound
+	}
+	return cover, nil
+}
+func (repo *fakeCoverRepository) Save(cover *entities.AlbumCover) (entities.AlbumCover, error) {
+	if repo.saveErr != nil {
+		return *cover, repo.saveErr
+	}
+	repo.covers[cover.AlbumId] = *cover
+	return *cover, nil
+}
+func (repo *fakeCoverRepository) Delete(albumId uint) error {
+	if _, ok := repo.covers[albumId]; !ok {
+		return repositories.ErrCoverNotFound
+	}
+	delete(repo.covers, albumId)
+	return nil
+}
+
+// failingBlobStorage stores in IBlobStorage until puts blobs are stored, the puts after fail
+type failingBlobStorage struct {
+	libs.IBlobStorage
+	puts int
+}
+
+func (storage *failingBlobStorage) Put(key string, reader io.Reader) error {
+	if storage.puts == 0 {
+		return errors.New("no space left on device")
+	}
+	storage.puts--
+	return storage.IBlobStorage.Put(key, reader)
+}
+
+// pngImage encodes a width by height image
+func pngImage(width, height int) []byte {
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		source.Set(x, x%height, color.RGBA{R: 200, A: 255})
+	}
+	var encoded bytes.Buffer
+	Expect(png.Encode(&encoded, source)).Should(Succeed())
+	return encoded.Bytes()
+}
+
+var _ = Describe("Covers", func() {
+	var root string
+
+	BeforeEach(func() {
+		base, err := os.MkdirTemp("", "covers")
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, base)
+		// keys that escape the root would land next to it in base
+		root = filepath.Join(base, "blobs")
+	})
+
+	Context("local blob storage", func() {
+		It("stores, opens and deletes blobs below its root", func() {
+			storage := libs.NewLocalBlobStorage(root)
+			Expect(storage.Put("covers/1/v1/original.png", strings.NewReader("image"))).Should(Succeed())
+
+			blob, info, err := storage.Open("covers/1/v1/original.png")
+			Expect(err).ShouldNot(HaveOccurred())
+			content, _ := io.ReadAll(blob)
+			blob.Close()
+			Expect(string(content)).Should(Equal("image"))
+			Expect(info.Size).Should(Equal(int64(5)))
+
+			Expect(storage.Delete("covers/1")).Should(Succeed())
+			_, _, err = storage.Open("covers/1/v1/original.png")
+			Expect(err).Should(MatchError(libs.ErrBlobNotFound))
+		})
+
+		It("rejects keys that leave its root", func() {
+			Expect(os.WriteFile(filepath.Join(filepath.Dir(root), "outside"), []byte("keep"), 0o644)).Should(Succeed())
+			storage := libs.NewLocalBlobStorage(root)
+
+			for _, key := range []string{"", "../outside", "covers/../../outside", "/etc/passwd", "covers//1", "covers/./1", `covers\..\1`} {
+				Expect(storage.Put(key, strings.NewReader("x"))).Should(MatchError(libs.ErrInvalidBlobKey), key)
+				_, _, err := storage.Open(key)
+				Expect(err).Should(MatchError(libs.ErrInvalidBlobKey), key)
+				Expect(storage.Delete(key)).Should(MatchError(libs.ErrInvalidBlobKey), key)
+			}
+			Expect(filepath.Join(filepath.Dir(root), "outside")).Should(BeARegularFile())
+		})
+	})
+
+	Context("cover service", func() {
+		var repo *fakeCoverRepository
+		var storage libs.IBlobStorage
+		var coverService services.ICoverService
+
+		BeforeEach(func() {
+			repo = &fakeCoverRepository{covers: map[uint]entities.AlbumCover{}}
+			storage = libs.NewLocalBlobStorage(root)
+			var coverRepo repositories.ICoverRepository = repo
+			coverService = services.CoverService(&coverRepo, storage, services.CoverConfig{MaxBytes: 1 << 20, MaxDimension: 1000}, libs.NewEventBus(10))
+		})
+
+		size := func(cover entities.AlbumCover, name string) image.Point {
+			blob, _, contentType, err := coverService.Open(cover, name)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer blob.Close()
+			Expect(contentType).Should(Equal("image/png"))
+			decoded, err := png.DecodeConfig(blob)
+			Expect(err).ShouldNot(HaveOccurred())
+			return image.Point{X: decoded.Width, Y: decoded.Height}
+		}
+
+		It("stores the image with thumbnails that keep its aspect ratio", func() {
+			cover, err := coverService.Upload(1, bytes.NewReader(pngImage(800, 400)), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cover.ContentType).Should(Equal("image/png"))
+			Expect(repo.covers).Should(HaveKey(uint(1)))
+
+			Expect(size(cover, "")).Should(Equal(image.Point{X: 800, Y: 400}))
+			Expect(size(cover, "small")).Should(Equal(image.Point{X: 150, Y: 75}))
+			Expect(size(cover, "large")).Should(Equal(image.Point{X: 600, Y: 300}))
+		})
+
+		It("never scales a small image up", func() {
+			cover, err := coverService.Upload(1, bytes.NewReader(pngImage(100, 40)), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(size(cover, "large")).Should(Equal(image.Point{X: 100, Y: 40}))
+		})
+
+		It("rejects what is not an image it takes", func() {
+			_, err := coverService.Upload(1, strings.NewReader("%PDF-1.4 not an image"), "editor")
+			Expect(err).Should(MatchError(services.ErrUnsupportedCoverType))
+			_, err = coverService.Upload(1, bytes.NewReader(append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)), "editor")
+			Expect(err).Should(MatchError(services.ErrInvalidCover))
+			Expect(repo.covers).Should(BeEmpty())
+		})
+
+		It("rejects images over the size limits", func() {
+			_, err := coverService.Upload(1, bytes.NewReader(pngImage(1001, 10)), "editor")
+			Expect(err).Should(MatchError(services.ErrCoverTooLarge))
+			_, err = coverService.Upload(1, bytes.NewReader(make([]byte, 1<<20+1)), "editor")
+			Expect(err).Should(MatchError(services.ErrCoverTooLarge))
+		})
+
+		It("keeps the live images when the same image fails to save again", func() {
+			data := pngImage(300, 300)
+			cover, err := coverService.Upload(1, bytes.NewReader(data), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			repo.saveErr = errors.New("connection reset")
+			_, err = coverService.Upload(1, bytes.NewReader(data), "editor")
+			Expect(err).Should(HaveOccurred())
+			Expect(size(cover, "small")).Should(Equal(image.Point{X: 150, Y: 150}))
+		})
+
+		It("drops the images of a new version that fails to save", func() {
+			live, err := coverService.Upload(1, bytes.NewReader(pngImage(300, 300)), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			repo.saveErr = errors.New("connection reset")
+			failed, err := coverService.Upload(1, bytes.NewReader(pngImage(200, 100)), "editor")
+			Expect(err).Should(HaveOccurred())
+			_, _, _, err = coverService.Open(failed, "")
+			Expect(err).Should(MatchError(libs.ErrBlobNotFound))
+			Expect(size(live, "")).Should(Equal(image.Point{X: 300, Y: 300}))
+		})
+
+		It("drops the images it stored when storing another one fails", func() {
+			live, err := coverService.Upload(1, bytes.NewReader(pngImage(300, 300)), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var coverRepo repositories.ICoverRepository = repo
+			failing := services.CoverService(&coverRepo, &failingBlobStorage{IBlobStorage: storage, puts: 2}, services.CoverConfig{MaxBytes: 1 << 20, MaxDimension: 1000}, libs.NewEventBus(10))
+			failed, err := failing.Upload(1, bytes.NewReader(pngImage(200, 100)), "editor")
+			Expect(err).Should(MatchError("no space left on device"))
+			for _, name := range []string{"", "small", "medium", "large"} {
+				_, _, _, err = coverService.Open(failed, name)
+				Expect(err).Should(MatchError(libs.ErrBlobNotFound), name)
+			}
+			Expect(repo.covers[1]).Should(Equal(live))
+			Expect(size(live, "small")).Should(Equal(image.Point{X: 150, Y: 150}))
+
+			// the live version uploaded again keeps its images
+			_, err = failing.Upload(1, bytes.NewReader(pngImage(300, 300)), "editor")
+			Expect(err).Should(HaveOccurred())
+			Expect(size(live, "large")).Should(Equal(image.Point{X: 300, Y: 300}))
+		})
+
+		It("removes the images of a deleted album", func() {
+			cover, err := coverService.Upload(1, bytes.NewReader(pngImage(300, 300)), "editor")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(coverService.DeleteImages(cover.AlbumId)).Should(Succeed())
+			_, _, _, err = coverService.Open(cover, "")
+			Expect(err).Should(MatchError(libs.ErrBlobNotFound))
+		})
+	})
+})
@@ -0,0 +1,124 @@
+package repository_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	libs "acy.com/api/src/lib"
+)
+
+var _ = Describe("Event bus", func() {
+	var bus *libs.EventBus
+
+	BeforeEach(func() {
+		bus = libs.NewEventBus(3)
+	})
+
+	ids := func(events []libs.Event) []uint64 {
+		result := []uint64{}
+		for _, event := range events {
+			result = append(result, event.Id)
+		}
+		return result
+	}
+
+	It("delivers events published after Subscribe", func() {
+		bus.Publish("album.created", 1)
+		subscription := bus.Subscribe(0)
+		defer subscription.Close()
+
+		bus.Publish("album.updated", 2)
+
+		Expect(subscription.Replay).To(BeEmpty())
+		event := <-subscription.Events
+		Expect(event.Id).To(Equal(uint64(2)))
+		Expect(event.Type).To(Equal("album.updated"))
+		Expect(event.Data).To(Equal(2))
+	})
+
+	It("replays the buffered events after the last event id", func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish("album.updated", i)
+		}
+		subscription := bus.Subscribe(1)
+		defer subscription.Close()
+
+		Expect(subscription.Missed).To(BeFalse())
+		Expect(ids(subscription.Replay)).To(Equal([]uint64{2, 3}))
+	})
+
+	It("reports events that are no longer buffered", func() {
+		for i := 0; i < 5; i++ {
+			bus.Publish("album.updated", i)
+		}
+		subscription := bus.Subscribe(1)
+		defer subscription.Close()
+
+		Expect(subscription.Missed).To(BeTrue())
+		Expect(ids(subscription.Replay)).To(Equal([]uint64{3, 4, 5}))
+	})
+
+	It("reports a last event id from before a restart", func() {
+		bus.Publish("album.updated", 1)
+		subscription := bus.Subscribe(42)
+		defer subscription.Close()
+
+		Expect(subscription.Missed).To(BeTrue())
+		Expect(subscription.Replay).To(BeEmpty())
+	})
+
+	It("resumes after an event id of its own epoch", func() {
+		first := bus.Publish("album.created", 1)
+		bus.Publish("album.updated", 2)
+		subscription := bus.SubscribeAfter(bus.EventId(first))
+		defer subscription.Close()
+
+		Expect(subscription.Missed).To(BeFalse())
+		Expect(ids(subscription.Replay)).To(Equal([]uint64{2}))
+	})
+
+	It("reports an event id of another process even when it is not ahead", func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish("album.updated", i)
+		}
+		previous := libs.NewEventBus(3)
+		previousId := previous.EventId(previous.Publish("album.updated", 0))
+		Expect(previousId).NotTo(Equal(bus.EventId(libs.Event{Id: 1})))
+
+		for _, lastEventId := range []string{previousId, "1", "garbage"} {
+			subscription := bus.SubscribeAfter(lastEventId)
+			Expect(subscription.Missed).To(BeTrue(), lastEventId)
+			Expect(subscription.Replay).To(BeEmpty(), lastEventId)
+			subscription.Close()
+		}
+
+		subscription := bus.SubscribeAfter("")
+		defer subscription.Close()
+		Expect(subscription.Missed).To(BeFalse())
+	})
+
+	It("drops a subscriber that falls behind instead of blocking", func() {
+		subscription := bus.Subscribe(0)
+		for i := 0; i < 100; i++ {
+			bus.Publish("album.updated", i)
+		}
+
+		received := 0
+		for range subscription.Events {
+			received++
+		}
+		Expect(received).To(BeNumerically("<", 100))
+		// closing a dropped subscription again is harmless
+		subscription.Close()
+	})
+
+	It("closes the events of a closed subscription", func() {
+		subscription := bus.Subscribe(0)
+		subscription.Close()
+		subscription.Close()
+
+		bus.Publish("album.updated", 1)
+		_, open := <-subscription.Events
+		Expect(open).To(BeFalse())
+	})
+})
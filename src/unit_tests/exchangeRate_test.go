@@ -0,0 +1,169 @@
+package repository_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/shopspring/decimal"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeExchangeRateRepository keeps the rate table in memory and counts how often it is read
+type fakeExchangeRateRepository struct {
+	rates []entities.ExchangeRate
+	reads int
+}
+
+func (repo *fakeExchangeRateRepository) FindAll() ([]entities.ExchangeRate, error) {
+	repo.reads++
+	return repo.rates, nil
+}
+
+func (repo *fakeExchangeRateRepository) Replace(rates []entities.ExchangeRate) error {
+	repo.rates = rates
+	return nil
+}
+
+var _ = Describe("Exchange rates", func() {
+	amount := decimal.RequireFromString
+	older := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	table := services.ExchangeRateTable{
+		"USD": {Currency: "USD", Rate: amount("1"), AsOf: newer},
+		"EUR": {Currency: "EUR", Rate: amount("0.8"), AsOf: older},
+		"JPY": {Currency: "JPY", Rate: amount("130"), AsOf: newer},
+		"CHF": {Currency: "CHF", Rate: amount("0.97"), AsOf: newer},
+	}
+	halfUp := libs.RoundingRules{Mode: libs.RoundHalfUp}
+
+	Context("Convert", func() {
+		It("converts through the base currency and keeps the older quote", func() {
+			converted, err := table.Convert(amount("10"), "EUR", "JPY", halfUp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(converted.Price.String()).Should(Equal("1625"))
+			Expect(converted.Currency).Should(Equal("JPY"))
+			Expect(converted.Rate.String()).Should(Equal("162.5"))
+			Expect(converted.AsOf).Should(Equal(older))
+		})
+
+		It("rounds to the minor unit of the target currency", func() {
+			converted, err := table.Convert(amount("10"), "JPY", "USD", halfUp)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(converted.Price.String()).Should(Equal("0.08"))
+		})
+
+		It("reports currencies without a rate", func() {
+			_, err := table.Convert(amount("10"), "USD", "GBP", halfUp)
+			Expect(err).Should(MatchError(services.ErrUnknownCurrency))
+		})
+	})
+
+	Context("RoundingRules", func() {
+		It("rounds by mode", func() {
+			Expect(libs.RoundingRules{Mode: libs.RoundHalfUp}.Round(amount("2.345"), "USD").String()).Should(Equal("2.35"))
+			Expect(libs.RoundingRules{Mode: libs.RoundHalfEven}.Round(amount("2.345"), "USD").String()).Should(Equal("2.34"))
+			Expect(libs.RoundingRules{Mode: libs.RoundUp}.Round(amount("2.341"), "USD").String()).Should(Equal("2.35"))
+			Expect(libs.RoundingRules{Mode: libs.RoundDown}.Round(amount("2.349"), "USD").String()).Should(Equal("2.34"))
+		})
+
+		It("rounds to the increment of a currency", func() {
+			rules := libs.RoundingRules{Mode: libs.RoundHalfUp, Increments: map[string]decimal.Decimal{"CHF": amount("0.05")}}
+			Expect(rules.Round(amount("2.37"), "CHF").String()).Should(Equal("2.35"))
+			Expect(rules.Round(amount("2.38"), "CHF").String()).Should(Equal("2.4"))
+		})
+	})
+
+	Context("AcceptedCurrencies", func() {
+		It("orders by quality and leaves out q=0", func() {
+			Expect(services.AcceptedCurrencies("usd;q=0.5, EUR, jpy;q=0, chf;q=0.8")).Should(Equal([]string{"EUR", "CHF", "USD"}))
+		})
+
+		It("keeps the header order for equal qualities", func() {
+			Expect(services.AcceptedCurrencies("GBP,EUR")).Should(Equal([]string{"GBP", "EUR"}))
+			Expect(services.AcceptedCurrencies(" , ")).Should(BeEmpty())
+		})
+	})
+
+	Context("ParseExchangeRates", func() {
+		It("reads json and gives the base currency rate 1", func() {
+			rates, err := services.ParseExchangeRates(strings.NewReader(`{"base": "usd", "as_of": "2022-05-01T00:00:00Z", "rates": {"eur": "0.8", "USD": "2"}}`), "json")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rates).Should(ConsistOf(
+				entities.ExchangeRate{Currency: "USD", Rate: decimal.NewFromInt(1), AsOf: older},
+				entities.ExchangeRate{Currency: "EUR", Rate: amount("0.8"), AsOf: older},
+			))
+		})
+
+		It("reads csv with its columns in any order", func() {
+			rates, err := services.ParseExchangeRates(strings.NewReader("rate,currency,as_of\n0.8,eur,2022-05-01T00:00:00Z\n1,USD,\n"), "csv")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rates).Should(HaveLen(2))
+			Expect(rates[0]).Should(Equal(entities.ExchangeRate{Currency: "EUR", Rate: amount("0.8"), AsOf: older}))
+			Expect(rates[1].Currency).Should(Equal("USD"))
+		})
+
+		It("rejects malformed tables", func() {
+			for format, input := range map[string]string{
+				"json": `{"base": `,
+				"csv":  "currency,rate\nEUR,abc\n",
+			} {
+				_, err := services.ParseExchangeRates(strings.NewReader(input), format)
+				Expect(err).Should(MatchError(services.ErrInvalidExchangeRates), format)
+			}
+			_, err := services.ParseExchangeRates(strings.NewReader("currency,as_of\nEUR,\n"), "csv")
+			Expect(err).Should(MatchError(services.ErrInvalidExchangeRates))
+		})
+	})
+
+	Context("Table", func() {
+		var repo *fakeExchangeRateRepository
+		var cache *services.ExchangeRateCache
+		var service services.IExchangeRateService
+
+		BeforeEach(func() {
+			repo = &fakeExchangeRateRepository{rates: []entities.ExchangeRate{{Currency: "USD", Rate: amount("1")}}}
+			cache = &services.ExchangeRateCache{TTL: time.Hour}
+			var rateRepo repositories.IExchangeRateRepository = repo
+			service = services.ExchangeRateService(&rateRepo, cache)
+		})
+
+		It("reads the rates once while the cache is fresh", func() {
+			for i := 0; i < 3; i++ {
+				table, err := service.Table()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(table).Should(HaveKey("USD"))
+			}
+			Expect(repo.reads).Should(Equal(1))
+		})
+
+		It("serves the replaced rates right away", func() {
+			_, err := service.Table()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = service.Replace([]entities.ExchangeRate{{Currency: "EUR", Rate: amount("0.8")}}, "admin")
+			Expect(err).ShouldNot(HaveOccurred())
+			reads := repo.reads
+
+			table, err := service.Table()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(table).Should(HaveKey("EUR"))
+			Expect(table).ShouldNot(HaveKey("USD"))
+			Expect(repo.reads).Should(Equal(reads))
+		})
+
+		It("reads the rates again once the cache is stale", func() {
+			cache.TTL = time.Nanosecond
+			service.Table()
+			time.Sleep(time.Millisecond)
+			service.Table()
+			Expect(repo.reads).Should(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,451 @@
+package repository_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeAlbumRepository keeps albums, their tracks and audit entries in memory, a transaction that fails puts them back
+// as they were. Deleting an album deletes its tracks, like the foreign keys of its child rows.
+type fakeAlbumRepository struct {
+	albums map[uint]entities.Album
+	nextId uint
+	audits *fakeAuditRepository
+	tracks *fakeTrackRepository
+}
+
+func newFakeAlbumRepository(albums ...entities.Album) *fakeAlbumRepository {
+	repo := &fakeAlbumRepository{albums: map[uint]entities.Album{}, nextId: 100, audits: &fakeAuditRepository{}, tracks: &fakeTrackRepository{}}
+	for _, album := range albums {
+		repo.albums[album.Id] = album
+	}
+	return repo
+}
+
+func (repo *fakeAlbumRepository) FindAll(filter repositories.AlbumFilter, page, pageSize int) ([]entities.Album, error) {
+	albums := []entities.Album{}
+	for _, album := range repo.albums {
+		albums = append(albums, album)
+	}
+	return albums, nil
+}
+
+func (repo *fakeAlbumRepository) FindById(id uint) (entities.Album, error) {
+	return repo.albums[id], nil
+}
+
+func (repo *fakeAlbumRepository) Facets(filter repositories.AlbumFilter) (repositories.AlbumFacets, error) {
+	return repositories.AlbumFacets{}, nil
+}
+
+func (repo *fakeAlbumRepository) Create(newAlbum *entities.Album) (entities.Album, error) {
+	if newAlbum.Id == 0 {
+		repo.nextId++
+		newAlbum.Id = repo.nextId
+	}
+	repo.albums[newAlbum.Id] = *newAlbum
+	return *newAlbum, nil
+}
+
+func (repo *fakeAlbumRepository) Update(id uint, column string, value interface{}) {}
+
+func (repo *fakeAlbumRepository) Save(album *entities.Album) (entities.Album, error) {
+	if _, ok := repo.albums[album.Id]; !ok {
+		return *album, repositories.ErrAlbumNotFound
+	}
+	repo.albums[album.Id] = *album
+	return *album, nil
+}
+
+func (repo *fakeAlbumRepository) Touch(id uint) (entities.Album, error) {
+	album, ok := repo.albums[id]
+	if !ok {
+		return album, repositories.ErrAlbumNotFound
+	}
+	album.UpdatedAt = time.Now()
+	repo.albums[id] = album
+	return album, nil
+}
+
+func (repo *fakeAlbumRepository) Delete(id uint) error {
+	delete(repo.albums, id)
+	tracks := []entities.Track{}
+	for _, track := range repo.tracks.tracks {
+		if track.AlbumId != id {
+			tracks = append(tracks, track)
+		}
+	}
+	repo.tracks.tracks = tracks
+	return nil
+}
+
+func (repo *fakeAlbumRepository) Transaction(fn func(txRepo repositories.IAlbumRepository) error) error {
+	snapshot := map[uint]entities.Album{}
+	for id, album := range repo.albums {
+		snapshot[id] = album
+	}
+	entries, tracks := len(repo.audits.entries), append([]entities.Track{}, repo.tracks.tracks...)
+	err := fn(repo)
+	if err != nil {
+		repo.albums = snapshot
+		repo.audits.entries = repo.audits.entries[:entries]
+		repo.tracks.tracks = tracks
+	}
+	return err
+}
+
+func (repo *fakeAlbumRepository) WithActor(actor string) repositories.IAlbumRepository {
+	return repo
+}
+
+func (repo *fakeAlbumRepository) Audits() repositories.IAuditRepository {
+	return repo.audits
+}
+
+func (repo *fakeAlbumRepository) Tracks() repositories.ITrackRepository {
+	return repo.tracks
+}
+
+// fakeTrackRepository appends tracks in memory, inserting the track titled failOn fails
+type fakeTrackRepository struct {
+	tracks []entities.Track
+	failOn string
+}
+
+func (repo *fakeTrackRepository) FindByAlbumIds(albumIds []uint) ([]entities.Track, error) {
+	return repo.tracks, nil
+}
+func (repo *fakeTrackRepository) FindById(albumId, id uint) (entities.Track, error) {
+	return entities.Track{}, repositories.ErrTrackNotFound
+}
+func (repo *fakeTrackRepository) Insert(track *entities.Track) (entities.Track, error) {
+	if track.Title == repo.failOn {
+		return *track, errors.New("insert failed")
+	}
+	track.Id = uint(len(repo.tracks) + 1)
+	track.Number = 1
+	for _, other := range repo.tracks {
+		if other.AlbumId == track.AlbumId && other.DiscNumber == track.DiscNumber {
+			track.Number++
+		}
+	}
+	repo.tracks = append(repo.tracks, *track)
+	return *track, nil
+}
+func (repo *fakeTrackRepository) Move(track *entities.Track) (entities.Track, error) {
+	return *track, nil
+}
+func (repo *fakeTrackRepository) Delete(albumId, id uint) error { return nil }
+func (repo *fakeTrackRepository) TotalDurations(albumIds []uint) (map[uint]uint, error) {
+	return map[uint]uint{}, nil
+}
+func (repo *fakeTrackRepository) Transaction(fn func(txRepo repositories.ITrackRepository) error) error {
+	return fn(repo)
+}
+
+// fakeAuditRepository keeps entries in memory, creating an entry fails with err
+type fakeAuditRepository struct {
+	entries []entities.AuditEntry
+	err     error
+}
+
+func (repo *fakeAuditRepository) Create(entry *entities.AuditEntry) error {
+	if repo.err != nil {
+		return repo.err
+	}
+	repo.entries = append(repo.entries, *entry)
+	return nil
+}
+func (repo *fakeAuditRepository) Find(filter repositories.AuditFilter, page, pageSize int) ([]entities.AuditEntry, error) {
+	return repo.entries, nil
+}
+func (repo *fakeAuditRepository) Each(filter repositories.AuditFilter, fn func(entry entities.AuditEntry) error) error {
+	return nil
+}
+
+// actions lists the actions of the entries
+func (repo *fakeAuditRepository) actions() []string {
+	actions := []string{}
+	for _, entry := range repo.entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+// fakeAlbumMongoRepository keeps content documents in memory, writes of the albums in failOn fail. files holds the
+// content of documents with a content file id, which ReadContent reads.
+type fakeAlbumMongoRepository struct {
+	documents map[uint]entities.AlbumMongoDB
+	files     map[uint]string
+	failOn    map[uint]bool
+	revertErr error
+	// before is what the bulk writes changed since the last Release or Revert, Revert puts it back
+	before   map[uint]entities.AlbumMongoDB
+	reverted int
+	released int
+}
+
+func newFakeAlbumMongoRepository(documents ...entities.AlbumMongoDB) *fakeAlbumMongoRepository {
+	repo := &fakeAlbumMongoRepository{documents: map[uint]entities.AlbumMongoDB{}, files: map[uint]string{}, failOn: map[uint]bool{}}
+	for _, document := range documents {
+		repo.documents[document.AlbumId] = document
+	}
+	return repo
+}
+
+func (repo *fakeAlbumMongoRepository) FindAll() []entities.AlbumMongoDB {
+	documents := []entities.AlbumMongoDB{}
+	for _, document := range repo.documents {
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+func (repo *fakeAlbumMongoRepository) FindById(albumId uint) entities.AlbumMongoDB {
+	return repo.documents[albumId]
+}
+
+func (repo *fakeAlbumMongoRepository) FindByAlbumIds(albumIds []uint) []entities.AlbumMongoDB {
+	documents := []entities.AlbumMongoDB{}
+	for _, albumId := range albumIds {
+		if document, ok := repo.documents[albumId]; ok {
+			documents = append(documents, document)
+		}
+	}
+	return documents
+}
+
+func (repo *fakeAlbumMongoRepository) Create(newAlbum *entities.AlbumMongoDB) string {
+	repo.documents[newAlbum.AlbumId] = *newAlbum
+	return newAlbum.ID.Hex()
+}
+
+func (repo *fakeAlbumMongoRepository) Delete(albumId uint) bool {
+	_, ok := repo.documents[albumId]
+	delete(repo.documents, albumId)
+	return ok
+}
+
+func (repo *fakeAlbumMongoRepository) BulkWrite(writes []repositories.AlbumMongoDBWrite, ordered bool) (repositories.AlbumMongoDBBulkResult, error) {
+	result := repositories.AlbumMongoDBBulkResult{Failed: map[int]error{}}
+	if repo.before == nil {
+		repo.before = map[uint]entities.AlbumMongoDB{}
+	}
+	for i, write := range writes {
+		if repo.failOn[write.Album.AlbumId] {
+			result.Failed[i] = errors.New("write failed")
+			if ordered {
+				for skipped := i + 1; skipped < len(writes); skipped++ {
+					result.Failed[skipped] = repositories.ErrBulkWriteSkipped
+				}
+				break
+			}
+			continue
+		}
+		if _, ok := repo.before[write.Album.AlbumId]; !ok {
+			repo.before[write.Album.AlbumId] = repo.documents[write.Album.AlbumId]
+		}
+		if write.Op == repositories.AlbumMongoDBWriteDelete {
+			delete(repo.documents, write.Album.AlbumId)
+		} else {
+			repo.documents[write.Album.AlbumId] = write.Album
+		}
+	}
+	return result, nil
+}
+
+func (repo *fakeAlbumMongoRepository) Release(result repositories.AlbumMongoDBBulkResult) error {
+	repo.released++
+	repo.before = nil
+	return nil
+}
+
+func (repo *fakeAlbumMongoRepository) Revert(result repositories.AlbumMongoDBBulkResult) error {
+	repo.reverted++
+	if repo.revertErr != nil {
+		return repo.revertErr
+	}
+	for albumId, document := range repo.before {
+		if document.AlbumId == 0 {
+			delete(repo.documents, albumId)
+		} else {
+			repo.documents[albumId] = document
+		}
+	}
+	repo.before = nil
+	return nil
+}
+
+func (repo *fakeAlbumMongoRepository) WithActor(actor string) repositories.IAlbumMongoDBRepository {
+	return repo
+}
+
+func (repo *fakeAlbumMongoRepository) ReadContent(album entities.AlbumMongoDB) (io.ReadSeekCloser, error) {
+	if album.ContentFileId != nil {
+		return libs.NopReadSeekCloser(strings.NewReader(repo.files[album.AlbumId])), nil
+	}
+	return libs.NopReadSeekCloser(strings.NewReader(album.Content)), nil
+}
+
+func (repo *fakeAlbumMongoRepository) OpenContent(albumId uint, html bool) (io.ReadSeekCloser, entities.AlbumMongoDB, error) {
+	return nil, entities.AlbumMongoDB{}, repositories.ErrAlbumContentNotFound
+}
+
+func (repo *fakeAlbumMongoRepository) WriteContent(album *entities.AlbumMongoDB, reader io.Reader) error {
+	if repo.failOn[album.AlbumId] {
+		return errors.New("write failed")
+	}
+	content, err := io.ReadAll(reader)
+	album.Content = string(content)
+	repo.documents[album.AlbumId] = *album
+	return err
+}
+
+// fakeArtistService resolves every artist to the id it is given, or to artist 1
+type fakeArtistService struct{}
+
+func (fakeArtistService) FindAll(name string, page, pageSize int) ([]entities.Artist, error) {
+	return nil, nil
+}
+func (fakeArtistService) FindById(id uint) (entities.Artist, error) {
+	return entities.Artist{Id: id}, nil
+}
+func (fakeArtistService) Create(name string, actor string) (entities.Artist, error) {
+	return entities.Artist{Id: 1, Name: name}, nil
+}
+func (fakeArtistService) Update(id uint, name string, actor string) (entities.Artist, error) {
+	return entities.Artist{Id: id, Name: name}, nil
+}
+func (fakeArtistService) Delete(id uint) error { return nil }
+func (fakeArtistService) Resolve(artistId uint, name string, actor string) (entities.Artist, error) {
+	if artistId == 0 {
+		artistId = 1
+	}
+	return entities.Artist{Id: artistId, Name: name}, nil
+}
+
+// fakeGenreService knows no genres
+type fakeGenreService struct{}
+
+func (fakeGenreService) FindAll() ([]entities.Genre, error) { return nil, nil }
+func (fakeGenreService) FindBySlugs(slugs []string) ([]entities.Genre, error) {
+	return []entities.Genre{}, nil
+}
+func (fakeGenreService) Create(name string, actor string) (entities.Genre, error) {
+	return entities.Genre{}, nil
+}
+func (fakeGenreService) Delete(slug string) error { return nil }
+
+// fakeAuditService writes an entry holding only the action to its repository
+type fakeAuditService struct {
+	repo repositories.IAuditRepository
+}
+
+func (audit *fakeAuditService) RecordAlbum(actor services.AuditActor, action string, before, after *services.AlbumSnapshot) error {
+	return audit.repo.Create(&entities.AuditEntry{Entity: entities.AuditEntityAlbum, Action: action, Actor: actor.Actor})
+}
+func (audit *fakeAuditService) Find(filter repositories.AuditFilter, page, pageSize int) ([]entities.AuditEntry, error) {
+	return audit.repo.Find(filter, page, pageSize)
+}
+func (audit *fakeAuditService) Export(filter repositories.AuditFilter, fn func(entry entities.AuditEntry) error) error {
+	return nil
+}
+func (audit *fakeAuditService) WithRepository(repo repositories.IAuditRepository) services.IAuditService {
+	return &fakeAuditService{repo: repo}
+}
+
+// fakeRevisionService keeps the contents it records, recording fails with err
+type fakeRevisionService struct {
+	recorded []string
+	err      error
+}
+
+func (revisions *fakeRevisionService) FindByAlbum(albumId uint, page, pageSize int) ([]entities.AlbumContentRevisionMongoDB, error) {
+	return nil, nil
+}
+func (revisions *fakeRevisionService) FindByRevision(albumId uint, revision int) (entities.AlbumContentRevisionMongoDB, error) {
+	return entities.AlbumContentRevisionMongoDB{}, nil
+}
+func (revisions *fakeRevisionService) Record(before, after entities.AlbumMongoDB, actor string) error {
+	if revisions.err != nil {
+		return revisions.err
+	}
+	revisions.recorded = append(revisions.recorded, after.Content)
+	return nil
+}
+func (revisions *fakeRevisionService) Diff(albumId uint, from, to int) (services.ContentDiff, error) {
+	return services.ContentDiff{}, nil
+}
+func (revisions *fakeRevisionService) Restore(album entities.Album, revision int, actor services.AuditActor) (entities.AlbumContentRevisionMongoDB, error) {
+	return entities.AlbumContentRevisionMongoDB{}, nil
+}
+
+// fakeReviewService keeps the ids of the albums whose reviews were deleted, deleting fails with err
+type fakeReviewService struct {
+	deleted []uint
+	err     error
+}
+
+func (reviews *fakeReviewService) FindByAlbum(albumId uint, page, pageSize int) ([]entities.ReviewMongoDB, error) {
+	return nil, nil
+}
+func (reviews *fakeReviewService) FindById(albumId uint, id primitive.ObjectID) (entities.ReviewMongoDB, error) {
+	return entities.ReviewMongoDB{}, repositories.ErrReviewNotFound
+}
+func (reviews *fakeReviewService) Create(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	return *review, nil
+}
+func (reviews *fakeReviewService) Update(review *entities.ReviewMongoDB) (entities.ReviewMongoDB, error) {
+	return *review, nil
+}
+func (reviews *fakeReviewService) Delete(albumId uint, id primitive.ObjectID) error {
+	return nil
+}
+func (reviews *fakeReviewService) Ratings(albumIds []uint) (map[uint]entities.AlbumRatingMongoDB, error) {
+	return map[uint]entities.AlbumRatingMongoDB{}, nil
+}
+func (reviews *fakeReviewService) DeleteByAlbum(albumId uint) error {
+	if reviews.err != nil {
+		return reviews.err
+	}
+	reviews.deleted = append(reviews.deleted, albumId)
+	return nil
+}
+
+// fakeCoverService keeps the ids of the albums whose cover images were deleted, deleting fails with err
+type fakeCoverService struct {
+	deleted []uint
+	err     error
+}
+
+func (covers *fakeCoverService) FindByAlbumIds(albumIds []uint) (map[uint]entities.AlbumCover, error) {
+	return map[uint]entities.AlbumCover{}, nil
+}
+func (covers *fakeCoverService) FindByAlbumId(albumId uint) (entities.AlbumCover, error) {
+	return entities.AlbumCover{}, repositories.ErrCoverNotFound
+}
+func (covers *fakeCoverService) Upload(albumId uint, reader io.Reader, actor string) (entities.AlbumCover, error) {
+	return entities.AlbumCover{AlbumId: albumId}, nil
+}
+func (covers *fakeCoverService) Open(cover entities.AlbumCover, size string) (io.ReadSeekCloser, libs.BlobInfo, string, error) {
+	return nil, libs.BlobInfo{}, "", libs.ErrBlobNotFound
+}
+func (covers *fakeCoverService) Delete(albumId uint) error {
+	return nil
+}
+func (covers *fakeCoverService) DeleteImages(albumId uint) error {
+	if covers.err != nil {
+		return covers.err
+	}
+	covers.deleted = append(covers.deleted, albumId)
+	return nil
+}
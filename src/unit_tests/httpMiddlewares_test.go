@@ -0,0 +1,169 @@
+package repository_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+
+	"acy.com/api/src/middlewares"
+)
+
+var _ = Describe("HTTP middlewares", func() {
+	serve := func(router *gin.Engine, request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	Context("Cors", func() {
+		config := middlewares.CorsConfig{
+			AllowedOrigins:   []string{"https://app.example.org", "https://*.example.com"},
+			AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			ExposedHeaders:   []string{"X-Next-Cursor"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		}
+
+		router := func(config middlewares.CorsConfig) *gin.Engine {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middlewares.Cors(config))
+			router.GET("/api/v1/albums", func(c *gin.Context) { c.String(http.StatusOK, "albums") })
+			return router
+		}
+
+		request := func(method, origin string) *http.Request {
+			request := httptest.NewRequest(method, "/api/v1/albums", nil)
+			request.Header.Set("Origin", origin)
+			if method == http.MethodOptions {
+				request.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			}
+			return request
+		}
+
+		It("answers preflights of allowed origins", func() {
+			response := serve(router(config), request(http.MethodOptions, "https://app.example.org"))
+			Expect(response.Code).Should(Equal(http.StatusNoContent))
+			Expect(response.Header().Get("Access-Control-Allow-Origin")).Should(Equal("https://app.example.org"))
+			Expect(response.Header().Get("Access-Control-Allow-Credentials")).Should(Equal("true"))
+			Expect(response.Header().Get("Access-Control-Allow-Methods")).Should(Equal("GET, PUT"))
+			Expect(response.Header().Get("Access-Control-Allow-Headers")).Should(Equal("Authorization, Content-Type"))
+			Expect(response.Header().Get("Access-Control-Max-Age")).Should(Equal("600"))
+			Expect(response.Header().Values("Vary")).Should(ContainElement("Origin"))
+		})
+
+		It("adds the headers to requests of allowed origins", func() {
+			response := serve(router(config), request(http.MethodGet, "https://app.example.org"))
+			Expect(response.Code).Should(Equal(http.StatusOK))
+			Expect(response.Header().Get("Access-Control-Allow-Origin")).Should(Equal("https://app.example.org"))
+			Expect(response.Header().Get("Access-Control-Expose-Headers")).Should(Equal("X-Next-Cursor"))
+		})
+
+		It("leaves the headers out for other origins and refuses their preflights", func() {
+			response := serve(router(config), request(http.MethodGet, "https://evil.example.net"))
+			Expect(response.Code).Should(Equal(http.StatusOK))
+			Expect(response.Header().Get("Access-Control-Allow-Origin")).Should(BeEmpty())
+			Expect(response.Header().Get("Access-Control-Allow-Credentials")).Should(BeEmpty())
+
+			Expect(serve(router(config), request(http.MethodOptions, "https://evil.example.net")).Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("allows subdomains of a wildcard over its scheme only", func() {
+			for origin, allowed := range map[string]bool{
+				"https://shop.example.com":     true,
+				"https://a.b.example.com":      true,
+				"https://example.com":          false,
+				"http://shop.example.com":      false,
+				"https://shopexample.com":      false,
+				"https://example.com.evil.net": false,
+			} {
+				response := serve(router(config), request(http.MethodGet, origin))
+				Expect(response.Header().Get("Access-Control-Allow-Origin") == origin).Should(Equal(allowed), origin)
+			}
+		})
+
+		It("answers any origin with a literal * and no credentials", func() {
+			response := serve(router(middlewares.CorsConfig{AllowedOrigins: []string{"*"}}), request(http.MethodGet, "https://anywhere.example.net"))
+			Expect(response.Header().Get("Access-Control-Allow-Origin")).Should(Equal("*"))
+			Expect(response.Header().Get("Access-Control-Allow-Credentials")).Should(BeEmpty())
+		})
+
+		It("refuses credentials for any origin", func() {
+			Expect(middlewares.CorsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}.Validate()).Should(HaveOccurred())
+			Expect(middlewares.CorsConfig{AllowedOrigins: []string{"*"}}.Validate()).Should(Succeed())
+			Expect(config.Validate()).Should(Succeed())
+
+			response := serve(router(middlewares.CorsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}), request(http.MethodGet, "https://anywhere.example.net"))
+			Expect(response.Header().Get("Access-Control-Allow-Origin")).Should(Equal("*"))
+			Expect(response.Header().Get("Access-Control-Allow-Credentials")).Should(BeEmpty())
+		})
+	})
+
+	Context("SecurityHeaders", func() {
+		It("sets the headers of the config and HSTS only with a max age", func() {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middlewares.SecurityHeaders(middlewares.SecurityHeadersConfig{
+				HSTSMaxAge:            3600,
+				ContentSecurityPolicy: "default-src 'none'",
+				FrameOptions:          "DENY",
+				ReferrerPolicy:        "no-referrer",
+			}))
+			router.GET("/api/v1/albums", func(c *gin.Context) { c.String(http.StatusOK, "albums") })
+			router.GET("/swagger/*any", middlewares.SecurityHeaders(middlewares.SecurityHeadersConfig{FrameOptions: "SAMEORIGIN"}), func(c *gin.Context) {
+				c.String(http.StatusOK, "docs")
+			})
+
+			response := serve(router, httptest.NewRequest(http.MethodGet, "/api/v1/albums", nil))
+			Expect(response.Header().Get("X-Content-Type-Options")).Should(Equal("nosniff"))
+			Expect(response.Header().Get("Strict-Transport-Security")).Should(Equal("max-age=3600; includeSubDomains"))
+			Expect(response.Header().Get("Content-Security-Policy")).Should(Equal("default-src 'none'"))
+			Expect(response.Header().Get("X-Frame-Options")).Should(Equal("DENY"))
+			Expect(response.Header().Get("Referrer-Policy")).Should(Equal("no-referrer"))
+
+			// a route overrides what it sets and keeps the rest
+			response = serve(router, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+			Expect(response.Header().Get("X-Frame-Options")).Should(Equal("SAMEORIGIN"))
+			Expect(response.Header().Get("Content-Security-Policy")).Should(Equal("default-src 'none'"))
+		})
+	})
+
+	Context("MaxBodySize", func() {
+		var router *gin.Engine
+
+		BeforeEach(func() {
+			gin.SetMode(gin.TestMode)
+			router = gin.New()
+			router.Use(middlewares.MaxBodySize(middlewares.BodyLimits{Default: 16, Routes: map[string]int64{"POST /api/v1/albums/batch": 64}}))
+			handler := func(c *gin.Context) {
+				body, _ := c.GetRawData()
+				c.String(http.StatusOK, "%d", len(body))
+			}
+			router.POST("/api/v1/albums/", handler)
+			router.POST("/api/v1/albums/batch", handler)
+		})
+
+		It("answers 413 when the content length is over the limit of the route", func() {
+			response := serve(router, httptest.NewRequest(http.MethodPost, "/api/v1/albums/", strings.NewReader(strings.Repeat("a", 17))))
+			Expect(response.Code).Should(Equal(http.StatusRequestEntityTooLarge))
+			Expect(response.Body.String()).Should(ContainSubstring("Request body exceeds 16 bytes"))
+
+			response = serve(router, httptest.NewRequest(http.MethodPost, "/api/v1/albums/batch", strings.NewReader(strings.Repeat("a", 17))))
+			Expect(response.Code).Should(Equal(http.StatusOK))
+			Expect(response.Body.String()).Should(Equal("17"))
+		})
+
+		It("passes bodies within the limit on", func() {
+			response := serve(router, httptest.NewRequest(http.MethodPost, "/api/v1/albums/", strings.NewReader(strings.Repeat("a", 16))))
+			Expect(response.Code).Should(Equal(http.StatusOK))
+			Expect(response.Body.String()).Should(Equal("16"))
+		})
+	})
+})
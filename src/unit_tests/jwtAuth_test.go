@@ -0,0 +1,148 @@
+package repository_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+)
+
+var _ = Describe("Test JWT Auth", func() {
+	secret := []byte("a-very-secret-hmac-key-for-tests")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var keySet *libs.JwtKeySet
+
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		Expect(err).ShouldNot(HaveOccurred())
+		return signed
+	}
+
+	validClaims := func(roles ...string) jwt.MapClaims {
+		return jwt.MapClaims{"sub": "user-1", "roles": roles, "exp": time.Now().Add(time.Hour).Unix()}
+	}
+
+	BeforeEach(func() {
+		document := fmt.Sprintf(`{"keys": [
+			{"kid": "hmac", "kty": "oct", "k": "%s"},
+			{"kid": "rsa", "kty": "RSA", "n": "%s", "e": "%s"}
+		]}`,
+			base64.RawURLEncoding.EncodeToString(secret),
+			base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		)
+		var err error
+		keySet, err = libs.ParseJwtKeySet([]byte(document), "", "")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	Context("ParseJwtKeySet", func() {
+		It("rejects a key set without keys", func() {
+			_, err := libs.ParseJwtKeySet([]byte(`{"keys": []}`), "", "")
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("rejects short HS256 secrets", func() {
+			document := fmt.Sprintf(`{"keys": [{"kid": "short", "kty": "oct", "k": "%s"}]}`, base64.RawURLEncoding.EncodeToString([]byte("secret")))
+			_, err := libs.ParseJwtKeySet([]byte(document), "", "")
+			Expect(err).Should(MatchError(ContainSubstring("at least 32 bytes")))
+		})
+
+		It("rejects retired key ids", func() {
+			document := fmt.Sprintf(`{"keys": [{"kid": "local-dev", "kty": "oct", "k": "%s"}]}`, base64.RawURLEncoding.EncodeToString(secret))
+			_, err := libs.ParseJwtKeySet([]byte(document), "", "")
+			Expect(err).Should(MatchError(ContainSubstring("retired")))
+		})
+	})
+
+	Context("Verify", func() {
+		It("accepts HS256 and RS256 tokens", func() {
+			claims, err := keySet.Verify(sign(jwt.SigningMethodHS256, secret, "hmac", validClaims("reader")))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(claims.Subject).Should(Equal("user-1"))
+			Expect(claims.Roles).Should(Equal([]string{"reader"}))
+
+			claims, err = keySet.Verify(sign(jwt.SigningMethodRS256, rsaKey, "rsa", validClaims("admin")))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(claims.Roles).Should(Equal([]string{"admin"}))
+		})
+
+		It("rejects tokens signed with another key", func() {
+			_, err := keySet.Verify(sign(jwt.SigningMethodHS256, []byte("another-secret"), "hmac", validClaims("reader")))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("rejects an RSA key id used with HS256", func() {
+			_, err := keySet.Verify(sign(jwt.SigningMethodHS256, secret, "rsa", validClaims("reader")))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("rejects expired tokens", func() {
+			claims := validClaims("reader")
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			_, err := keySet.Verify(sign(jwt.SigningMethodHS256, secret, "hmac", claims))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("rejects unsigned tokens", func() {
+			_, err := keySet.Verify(sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims("admin")))
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("RequireRole", func() {
+		var router *gin.Engine
+
+		BeforeEach(func() {
+			gin.SetMode(gin.TestMode)
+			router = gin.New()
+			router.DELETE("/albums/:id", middlewares.JwtAuth(keySet), middlewares.RequireRole(middlewares.RoleEditor), func(c *gin.Context) {
+				c.String(http.StatusOK, middlewares.Subject(c))
+			})
+		})
+
+		request := func(authorization string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/albums/1", nil)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			router.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		It("returns 401 without a token", func() {
+			Expect(request("").Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("returns 403 for a lower role", func() {
+			token := sign(jwt.SigningMethodHS256, secret, "hmac", validClaims("reader"))
+			Expect(request("Bearer " + token).Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("grants higher roles", func() {
+			token := sign(jwt.SigningMethodHS256, secret, "hmac", validClaims("admin"))
+			recorder := request("Bearer " + token)
+			Expect(recorder.Code).Should(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).Should(Equal("user-1"))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package repository_test
+
+import (
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	libs "acy.com/api/src/lib"
+)
+
+var _ = Describe("Test Markdown Content", func() {
+	render := func(source string) string {
+		rendered, err := libs.RenderMarkdown(source)
+		Expect(err).ShouldNot(HaveOccurred())
+		return rendered
+	}
+
+	It("renders github flavoured markdown", func() {
+		rendered := render("# Blue Train\n\nRecorded *1957* at [Van Gelder](https://example.com/studio).\n\n```go\nfmt.Println(1)\n```\n\n- [x] remastered\n\n| side | tracks |\n|---|---|\n| A | 2 |")
+
+		Expect(rendered).To(ContainSubstring("<h1>Blue Train</h1>"))
+		Expect(rendered).To(ContainSubstring("<em>1957</em>"))
+		Expect(rendered).To(ContainSubstring(`<a href="https://example.com/studio" rel="nofollow noreferrer">Van Gelder</a>`))
+		Expect(rendered).To(ContainSubstring(`<code class="language-go">`))
+		Expect(rendered).To(ContainSubstring(`<input checked="" disabled="" type="checkbox">`))
+		Expect(rendered).To(ContainSubstring("<td>A</td>"))
+	})
+
+	It("streams the same html it renders into memory", func() {
+		source := "# Blue Train\n\n<script>alert(1)</script>[Van Gelder](javascript:alert(1)) and [studio](https://example.com)"
+		var streamed strings.Builder
+		Expect(libs.RenderMarkdownTo(&streamed, []byte(source))).Should(Succeed())
+		Expect(streamed.String()).Should(Equal(render(source)))
+	})
+
+	It("derives plain text from the rendered content", func() {
+		text := libs.ContentText(render("# Blue Train\n\nTom &amp; Jerry, *Moment's Notice*\n\n`<b>`"))
+
+		Expect(text).To(Equal("Blue Train\nTom & Jerry, Moment's Notice\n<b>"))
+	})
+
+	It("streams the plain text from any offset", func() {
+		rendered := render("# Blue Train\n\nTom &amp; Jerry, *Moment's Notice*\n\n" + strings.Repeat("Recorded at Van Gelder.\n\n", 100))
+		text := libs.ContentText(rendered)
+
+		stream, err := libs.OpenContentText(libs.NopReadSeekCloser(strings.NewReader(rendered)))
+		Expect(err).ShouldNot(HaveOccurred())
+		defer stream.Close()
+		Expect(stream.Seek(0, io.SeekEnd)).Should(Equal(int64(len(text))))
+		Expect(stream.Seek(11, io.SeekStart)).Should(Equal(int64(11)))
+		part := make([]byte, 11)
+		_, err = io.ReadFull(stream, part)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(part)).Should(Equal("Tom & Jerry"))
+		Expect(stream.Seek(0, io.SeekStart)).Should(Equal(int64(0)))
+		Expect(io.ReadAll(stream)).Should(Equal([]byte(text)))
+	})
+
+	DescribeTable("sanitizes known xss payloads",
+		func(payload string, forbidden ...string) {
+			rendered := strings.ToLower(render(payload))
+
+			for _, fragment := range append(forbidden, "<script", "javascript:", "onerror", "onload") {
+				Expect(rendered).NotTo(ContainSubstring(fragment))
+			}
+		},
+		Entry("script tag", "<script>alert(1)</script>"),
+		Entry("script tag inside a paragraph", "intro <script>alert(1)</script> outro"),
+		Entry("image error handler", `<img src=x onerror=alert(1)>`, "<img"),
+		Entry("svg load handler", `<svg onload=alert(1)>`, "<svg"),
+		Entry("details toggle handler", `<details open ontoggle=alert(1)>`, "ontoggle"),
+		Entry("iframe", `<iframe src="https://evil.example"></iframe>`, "<iframe"),
+		Entry("object embed", `<object data="https://evil.example/x.swf"></object>`, "<object"),
+		Entry("form", `<form action="https://evil.example"><input type="submit"></form>`, "<form", "submit"),
+		Entry("inline style", `<div style="background:url(javascript:alert(1))">x</div>`, "style="),
+		Entry("html javascript link", `<a href="javascript:alert(1)">x</a>`),
+		Entry("markdown javascript link", "[click](javascript:alert(1))"),
+		Entry("mixed case javascript link", "[click](JaVaScRiPt:alert(1))"),
+		Entry("entity obfuscated javascript link", "[click](jav&#x09;ascript:alert(1))"),
+		Entry("javascript image", "![x](javascript:alert(1))"),
+		Entry("vbscript link", "[click](vbscript:msgbox(1))", "vbscript:"),
+		Entry("data uri link", "[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)", "data:"),
+		Entry("attribute breakout in a link title", `[x](https://example.com "title\" onmouseover=alert(1)")`, "onmouseover="),
+		Entry("autolink with markup", "<https://example.com/a?b=<script>>"),
+		Entry("reference link", "[click][x]\n\n[x]: javascript:alert(1)"),
+	)
+
+	It("keeps code that shows markup as text", func() {
+		rendered := render("`<script>alert(1)</script>`")
+
+		Expect(rendered).To(ContainSubstring("<code>&lt;script&gt;alert(1)&lt;/script&gt;</code>"))
+	})
+})
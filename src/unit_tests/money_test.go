@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/shopspring/decimal"
+
+	libs "acy.com/api/src/lib"
+)
+
+var _ = Describe("Money", func() {
+	price := decimal.RequireFromString
+
+	Context("ValidatePrice", func() {
+		It("accepts prices with up to the decimal places of their currency", func() {
+			Expect(libs.ValidatePrice(price("56.9"), "USD")).Should(Succeed())
+			Expect(libs.ValidatePrice(price("1200"), "JPY")).Should(Succeed())
+			Expect(libs.ValidatePrice(price("1.250"), "KWD")).Should(Succeed())
+			Expect(libs.ValidatePrice(decimal.Zero, "EUR")).Should(Succeed())
+		})
+
+		It("rejects negative prices", func() {
+			Expect(libs.ValidatePrice(price("-0.01"), "USD")).Should(MatchError(libs.ErrNegativePrice))
+		})
+
+		It("rejects more decimal places than the currency has", func() {
+			Expect(libs.ValidatePrice(price("9.999"), "USD")).ShouldNot(Succeed())
+			Expect(libs.ValidatePrice(price("1200.5"), "JPY")).ShouldNot(Succeed())
+		})
+	})
+
+	Context("ParseDefaultCurrency", func() {
+		It("reads ISO 4217 codes and falls back to USD", func() {
+			Expect(libs.ParseDefaultCurrency(" eur ")).Should(Equal("EUR"))
+			Expect(libs.ParseDefaultCurrency("JPY")).Should(Equal("JPY"))
+			Expect(libs.ParseDefaultCurrency("")).Should(Equal("USD"))
+		})
+
+		It("refuses codes that are not ISO 4217 currencies", func() {
+			for _, value := range []string{"ABC", "XXX", "XAU", "US", "USDT", "€"} {
+				_, err := libs.ParseDefaultCurrency(value)
+				Expect(err).Should(HaveOccurred(), value)
+			}
+		})
+	})
+
+	Context("FormatPrice", func() {
+		It("writes the decimal places of the currency", func() {
+			Expect(libs.FormatPrice(price("56.9"), "USD")).Should(Equal("56.90"))
+			Expect(libs.FormatPrice(price("1200"), "JPY")).Should(Equal("1200"))
+			Expect(libs.FormatPrice(price("1.5"), "KWD")).Should(Equal("1.500"))
+		})
+
+		It("keeps the places of legacy prices rather than rounding them", func() {
+			Expect(libs.FormatPrice(price("9.999"), "USD")).Should(Equal("9.999"))
+			Expect(libs.FormatPrice(price("1200.5"), "JPY")).Should(Equal("1200.5"))
+		})
+	})
+})
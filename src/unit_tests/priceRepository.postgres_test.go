@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Price repository", func() {
+	var repository *repositories.PriceRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repository = repositories.NewPriceRepository(db)
+	})
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ShouldNot(HaveOccurred())
+	})
+
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "album_id", "price", "currency", "effective_from", "status", "created_by"}
+
+	Context("DuePrices", func() {
+		It("returns the latest due pending price of each album", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT ON (album_id) * FROM scheduled_prices`)).
+				WithArgs(1, 2, entities.ScheduledPricePending, now).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(7, 1, "8.99", "USD", now.Add(-time.Hour), entities.ScheduledPricePending, "editor"))
+
+			prices, err := repository.DuePrices([]uint{1, 2}, now)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(prices).Should(HaveLen(1))
+			Expect(prices[1].Id).Should(Equal(uint(7)))
+			Expect(prices[1].Price.Equal(decimal.RequireFromString("8.99"))).Should(BeTrue())
+		})
+
+		It("does not query without albums", func() {
+			prices, err := repository.DuePrices(nil, now)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(prices).Should(BeEmpty())
+		})
+	})
+
+	Context("ApplyDue", func() {
+		const sqlUpdateAlbum = `UPDATE "albums" SET "currency"=$1,"price"=$2,"updated_at"=$3,"updated_by"=$4 WHERE id = $5`
+		const sqlUpdateStatus = `UPDATE "scheduled_prices" SET "applied_at"=$1,"status"=$2 WHERE id = $3`
+
+		It("applies the latest due price of an album and supersedes the others", func() {
+			mock.ExpectBegin()
+			// the prices the scheduler writes do not supersede the prices it claimed
+			mock.ExpectExec(regexp.QuoteMeta(`SELECT set_config('app.applying_scheduled_prices', 'on', true)`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+				WithArgs(entities.ScheduledPricePending, now, 10).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(3, 1, "9.99", "USD", now.Add(-2*time.Hour), entities.ScheduledPricePending, "editor").
+					AddRow(4, 1, "7.99", "EUR", now.Add(-time.Hour), entities.ScheduledPricePending, "admin"))
+			mock.ExpectExec(regexp.QuoteMeta(sqlUpdateStatus)).
+				WithArgs(nil, entities.ScheduledPriceSuperseded, 3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(sqlUpdateAlbum)).
+				WithArgs("EUR", decimal.RequireFromString("7.99"), now, "admin", 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(sqlUpdateStatus)).
+				WithArgs(now, entities.ScheduledPriceApplied, 4).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			claimed, err := repository.ApplyDue(now, 10)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(claimed).Should(HaveLen(2))
+			Expect(claimed[0].Status).Should(Equal(entities.ScheduledPriceSuperseded))
+			Expect(claimed[1].Status).Should(Equal(entities.ScheduledPriceApplied))
+			Expect(*claimed[1].AppliedAt).Should(Equal(now))
+		})
+
+		It("leaves every price pending when an album can not be written", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "9.99", "USD", now.Add(-time.Hour), entities.ScheduledPricePending, "editor"))
+			mock.ExpectExec(regexp.QuoteMeta(sqlUpdateAlbum)).WillReturnError(sql.ErrConnDone)
+			mock.ExpectRollback()
+
+			_, err := repository.ApplyDue(now, 10)
+			Expect(err).Should(MatchError(sql.ErrConnDone))
+		})
+	})
+})
@@ -0,0 +1,145 @@
+package repository_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(key string, policy libs.RateLimitPolicy) (libs.RateLimitResult, error) {
+	return libs.RateLimitResult{}, errors.New("connection refused")
+}
+
+var _ = Describe("Test Rate Limit", func() {
+	policy := libs.RateLimitPolicy{Name: "test", Limit: 2, Period: time.Minute}
+
+	var store libs.IRateLimitStore
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		store = libs.NewMemoryRateLimitStore()
+	})
+
+	request := func(r *gin.Engine, path string, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if subject != "" {
+			req.Header.Set("X-Subject", subject)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	Context("memory store", func() {
+		It("empties the bucket after Limit requests", func() {
+			for i := 0; i < policy.Limit; i++ {
+				result, err := store.Take("client", policy)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Allowed).Should(BeTrue())
+				Expect(result.Remaining).Should(Equal(policy.Limit - i - 1))
+			}
+
+			result, err := store.Take("client", policy)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Allowed).Should(BeFalse())
+			Expect(result.RetryAfter).Should(BeNumerically(">", 0))
+			Expect(result.RetryAfter).Should(BeNumerically("<=", policy.Period/time.Duration(policy.Limit)))
+		})
+
+		It("keeps a bucket per key", func() {
+			for i := 0; i < policy.Limit; i++ {
+				store.Take("first", policy)
+			}
+			result, _ := store.Take("second", policy)
+			Expect(result.Allowed).Should(BeTrue())
+		})
+	})
+
+	Context("IpRateLimit", func() {
+		It("turns away an ip before authentication runs", func() {
+			authenticated := 0
+			r := gin.New()
+			r.GET("/albums",
+				middlewares.IpRateLimit(store, policy, zap.NewNop()),
+				func(c *gin.Context) {
+					authenticated++
+					c.AbortWithStatus(http.StatusUnauthorized)
+				},
+			)
+
+			Expect(request(r, "/albums", "").Code).Should(Equal(http.StatusUnauthorized))
+			Expect(request(r, "/albums", "").Code).Should(Equal(http.StatusUnauthorized))
+
+			w := request(r, "/albums", "")
+			Expect(w.Code).Should(Equal(http.StatusTooManyRequests))
+			Expect(w.Header().Get("Retry-After")).ShouldNot(BeEmpty())
+			Expect(authenticated).Should(Equal(2))
+		})
+	})
+
+	Context("RateLimit", func() {
+		var r *gin.Engine
+
+		BeforeEach(func() {
+			policies := middlewares.RateLimitPolicies{
+				Default: policy,
+				Routes: map[string]libs.RateLimitPolicy{
+					"GET /search": {Name: "search", Limit: 1, Period: time.Minute},
+				},
+			}
+			authenticate := func(c *gin.Context) {
+				if subject := c.GetHeader("X-Subject"); subject != "" {
+					c.Set(middlewares.SubjectKey, subject)
+				}
+			}
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+			r = gin.New()
+			r.Use(authenticate, middlewares.RateLimit(store, policies, zap.NewNop()))
+			r.GET("/albums", ok)
+			r.GET("/search", ok)
+		})
+
+		It("uses the route policy and reports it in the headers", func() {
+			w := request(r, "/search", "user-1")
+			Expect(w.Code).Should(Equal(http.StatusOK))
+			Expect(w.Header().Get("RateLimit-Limit")).Should(Equal("1"))
+			Expect(w.Header().Get("RateLimit-Remaining")).Should(Equal("0"))
+			Expect(w.Header().Get("RateLimit-Policy")).Should(Equal("1;w=60"))
+
+			Expect(request(r, "/search", "user-1").Code).Should(Equal(http.StatusTooManyRequests))
+			// the default policy has its own bucket
+			Expect(request(r, "/albums", "user-1").Code).Should(Equal(http.StatusOK))
+		})
+
+		It("limits authenticated callers by subject rather than ip", func() {
+			Expect(request(r, "/search", "user-1").Code).Should(Equal(http.StatusOK))
+			Expect(request(r, "/search", "user-2").Code).Should(Equal(http.StatusOK))
+			Expect(request(r, "/search", "").Code).Should(Equal(http.StatusOK))
+			Expect(request(r, "/search", "").Code).Should(Equal(http.StatusTooManyRequests))
+		})
+
+		It("lets requests through when the store is unavailable", func() {
+			r := gin.New()
+			r.GET("/albums", middlewares.RateLimit(failingRateLimitStore{}, middlewares.RateLimitPolicies{Default: policy}, zap.NewNop()), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			for i := 0; i <= policy.Limit; i++ {
+				Expect(request(r, "/albums", "").Code).Should(Equal(http.StatusOK))
+			}
+		})
+	})
+})
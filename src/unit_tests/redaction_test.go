@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/middlewares"
+)
+
+var _ = Describe("Test Redaction", func() {
+	secrets := []string{
+		"Bearer eyJhbGciOiJIUzI1NiJ9.secret-jwt",
+		"session=secret-cookie",
+		"acy_secret-api-key",
+		"secret-query-token",
+		"secret-body-password",
+		"secret-nested-token",
+		"jane.doe@example.com",
+	}
+
+	var logs *observer.ObservedLogs
+	var router *gin.Engine
+
+	setup := func(config libs.RedactorConfig) {
+		var core zapcore.Core
+		core, logs = observer.New(zap.InfoLevel)
+		logger := zap.New(core)
+		redactor := libs.NewRedactor(config)
+
+		gin.SetMode(gin.TestMode)
+		router = gin.New()
+		router.Use(middlewares.GinLogger(logger, redactor), middlewares.GinRecovery(logger, true, redactor))
+		router.POST("/albums", func(c *gin.Context) {
+			io.ReadAll(c.Request.Body)
+			panic("boom")
+		})
+		router.GET("/albums", func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+	}
+
+	send := func(method string) *httptest.ResponseRecorder {
+		body := `{"title": "Blue Train", "password": "secret-body-password", "owner": {"email": "jane.doe@example.com", "session": {"token": "secret-nested-token"}}}`
+		request := httptest.NewRequest(method, "/albums?page=2&token=secret-query-token", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", secrets[0])
+		request.Header.Set("Cookie", secrets[1])
+		request.Header.Set("X-API-Key", secrets[2])
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// everything zap would write for the observed entries
+	output := func() string {
+		var builder strings.Builder
+		for _, entry := range logs.All() {
+			builder.WriteString(entry.Message)
+			builder.WriteString(fmt.Sprint(entry.ContextMap()))
+		}
+		return builder.String()
+	}
+
+	expectNoSecrets := func() {
+		Expect(logs.Len()).Should(BeNumerically(">", 0))
+		for _, secret := range secrets {
+			Expect(output()).ShouldNot(ContainSubstring(secret))
+		}
+	}
+
+	Context("deny list", func() {
+		BeforeEach(func() {
+			setup(libs.RedactorConfig{
+				Mode:        libs.RedactDenyList,
+				Headers:     []string{"authorization", "cookie", "x-api-key"},
+				QueryParams: []string{"token"},
+				BodyFields:  []string{"password", "email", "token"},
+			})
+		})
+
+		It("keeps secrets out of the recovery dump", func() {
+			Expect(send(http.MethodPost).Code).Should(Equal(http.StatusInternalServerError))
+			expectNoSecrets()
+			Expect(output()).Should(ContainSubstring("[Recovery from panic]"))
+			Expect(output()).Should(ContainSubstring("Blue Train"))
+			Expect(output()).Should(ContainSubstring(libs.RedactedValue))
+		})
+
+		It("keeps secrets out of the access log", func() {
+			Expect(send(http.MethodGet).Code).Should(Equal(http.StatusOK))
+			expectNoSecrets()
+			Expect(logs.All()[0].ContextMap()["query"]).Should(ContainSubstring("page=2"))
+		})
+	})
+
+	Context("allow list", func() {
+		BeforeEach(func() {
+			setup(libs.RedactorConfig{
+				Mode:        libs.RedactAllowList,
+				Headers:     []string{"Content-Type"},
+				QueryParams: []string{"page"},
+				BodyFields:  []string{"title"},
+			})
+		})
+
+		It("only logs listed values", func() {
+			Expect(send(http.MethodPost).Code).Should(Equal(http.StatusInternalServerError))
+			expectNoSecrets()
+			Expect(output()).Should(ContainSubstring("Blue Train"))
+			Expect(output()).Should(ContainSubstring("application/json"))
+		})
+	})
+
+	Context("JSONBody", func() {
+		It("drops documents that can not be parsed", func() {
+			redactor := libs.NewRedactor(libs.RedactorConfig{Mode: libs.RedactDenyList, BodyFields: []string{"password"}})
+			Expect(redactor.JSONBody([]byte(`{"password": "secret-body-password"`))).Should(Equal(libs.RedactedValue))
+		})
+	})
+})
@@ -0,0 +1,36 @@
+package repository_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Album ratings", func() {
+	It("recomputes the rating from the reviews of the album", func() {
+		pipeline := repositories.AlbumRatingPipeline(7)
+
+		Expect(pipeline).To(HaveLen(3))
+		Expect(pipeline[0]).To(Equal(bson.D{{Key: "$match", Value: bson.M{"albumId": uint(7)}}}))
+		Expect(pipeline[1][0].Value).To(HaveKeyWithValue("sum", bson.M{"$sum": "$rating"}))
+	})
+
+	It("writes a recount over earlier ones only", func() {
+		filter, update := repositories.AlbumRatingUpdate(7, 5, entities.AlbumRatingMongoDB{Count: 4, Sum: 14})
+
+		Expect(filter).To(HaveKeyWithValue("albumId", uint(7)))
+		// a rating counted at version 5 or later is newer, one never counted has no countedVersion and matches
+		Expect(filter).To(HaveKeyWithValue("countedVersion", bson.M{"$not": bson.M{"$gte": int64(5)}}))
+		// the rating replaces the stored one rather than adding to it
+		Expect(update).To(Equal(bson.M{"$set": bson.M{"count": int64(4), "sum": int64(14), "countedVersion": int64(5)}}))
+	})
+
+	It("averages the reviews", func() {
+		Expect(entities.AlbumRatingMongoDB{Count: 4, Sum: 14}.Average()).To(HaveValue(Equal(3.5)))
+		Expect(entities.AlbumRatingMongoDB{}.Average()).To(BeNil())
+	})
+})
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Album search in PostgreSQL", func() {
+	var repository *repositories.AlbumSearchRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repository = repositories.NewAlbumSearchRepository(db)
+	})
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ShouldNot(HaveOccurred())
+	})
+
+	parse := func(q string) libs.SearchQuery {
+		query, err := libs.ParseSearchQuery(q)
+		Expect(err).ShouldNot(HaveOccurred())
+		return query
+	}
+
+	It("finds candidates with any word and ranks them on every term", func() {
+		// the candidates match any word, a prefix stays on the last word of its phrase
+		any := "(love) | (supreme) | (colt:*) | (blue) | (tr:*)"
+		all := "(love <-> supreme) & (colt:*) & (blue <-> tr:*)"
+		mock.ExpectQuery(`WITH candidates AS`).WithArgs(any, any, all, all, 50).
+			WillReturnRows(sqlmock.NewRows([]string{"album_id", "score"}).AddRow(7, 0.6).AddRow(3, 0.2))
+
+		hits, err := repository.Search(parse(`"Love Supreme" colt* "blue tr"*`), 50)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hits).Should(Equal([]repositories.SearchHit{{AlbumId: 7, Score: 0.6}, {AlbumId: 3, Score: 0.2}}))
+	})
+
+	It("reads words joined by punctuation as a phrase", func() {
+		all := "(rock <-> n <-> roll)"
+		mock.ExpectQuery(`WITH candidates AS`).WithArgs("(rock) | (n) | (roll)", "(rock) | (n) | (roll)", all, all, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"album_id", "score"}))
+
+		hits, err := repository.Search(parse(`Rock'n'Roll`), 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hits).Should(BeEmpty())
+	})
+
+	It("picks suggestion candidates starting with the first word before the others", func() {
+		query, err := libs.ParsePrefixQuery("Love Sup")
+		Expect(err).ShouldNot(HaveOccurred())
+		all := "(love) & (sup:*)"
+		mock.ExpectQuery(`(?s)FROM albums WHERE .+ ORDER BY lower\(title\) LIKE \$3 DESC, length\(title\), title, id LIMIT \$4\).+`+
+			`FROM artists WHERE .+ ORDER BY lower\(name\) LIKE \$7 DESC, length\(name\), name, id LIMIT \$8\)`).
+			WithArgs(repositories.SuggestionAlbum, all, "love%", 1000, repositories.SuggestionArtist, all, "love%", 1000, "love%", 5).
+			WillReturnRows(sqlmock.NewRows([]string{"type", "id", "text"}).AddRow(repositories.SuggestionAlbum, 1, "A Love Supreme"))
+
+		suggestions, err := repository.Suggest(query, 5)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(suggestions).Should(Equal([]repositories.Suggestion{{Type: repositories.SuggestionAlbum, Id: 1, Text: "A Love Supreme"}}))
+	})
+})
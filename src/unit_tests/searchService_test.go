@@ -0,0 +1,112 @@
+package repository_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeAlbumSearchRepository answers every search with hits
+type fakeAlbumSearchRepository struct {
+	hits []repositories.SearchHit
+}
+
+func (repo *fakeAlbumSearchRepository) Search(query libs.SearchQuery, limit int) ([]repositories.SearchHit, error) {
+	return repo.hits, nil
+}
+func (repo *fakeAlbumSearchRepository) Fuzzy(text string, threshold float64, limit int) ([]repositories.SearchHit, error) {
+	return repo.hits, nil
+}
+func (repo *fakeAlbumSearchRepository) Catalogue() ([]repositories.CatalogueEntry, error) {
+	return nil, nil
+}
+func (repo *fakeAlbumSearchRepository) Suggest(query libs.SearchQuery, limit int) ([]repositories.Suggestion, error) {
+	return nil, nil
+}
+
+// fakeAlbumSearchMongoRepository answers every search with hits and has the content of sources
+type fakeAlbumSearchMongoRepository struct {
+	hits    []repositories.SearchHit
+	sources []entities.AlbumMongoDB
+}
+
+func (repo *fakeAlbumSearchMongoRepository) Search(query libs.SearchQuery, limit int) ([]repositories.SearchHit, error) {
+	return repo.hits, nil
+}
+func (repo *fakeAlbumSearchMongoRepository) Sources(albumIds []uint) ([]entities.AlbumMongoDB, error) {
+	return repo.sources, nil
+}
+
+var _ = Describe("Search service", func() {
+	var metadata *fakeAlbumSearchRepository
+	var content *fakeAlbumSearchMongoRepository
+	var searchService services.ISearchService
+
+	BeforeEach(func() {
+		metadata = &fakeAlbumSearchRepository{}
+		content = &fakeAlbumSearchMongoRepository{}
+		albums := newFakeAlbumRepository(
+			entities.Album{Id: 1, Title: "Blue Train"},
+			entities.Album{Id: 2, Title: "Giant Steps"},
+			entities.Album{Id: 3, Title: "Ballads"},
+		)
+
+		var repo repositories.IAlbumSearchRepository = metadata
+		var contentRepo repositories.IAlbumSearchMongoDBRepository = content
+		var albumRepo repositories.IAlbumRepository = albums
+		searchService = services.SearchService(&repo, &contentRepo, &albumRepo, services.FuzzySearchConfig{Threshold: 0.5})
+	})
+
+	ids := func(results services.SearchResults) []uint {
+		albumIds := []uint{}
+		for _, result := range results.Results {
+			albumIds = append(albumIds, result.Album.Id)
+		}
+		return albumIds
+	}
+
+	It("adds up the scores of both backends, content matches weigh less", func() {
+		metadata.hits = []repositories.SearchHit{{AlbumId: 1, Score: 0.5}, {AlbumId: 2, Score: 0.2}}
+		// text scores are mapped between 0 and 1 and halved: 1 counts 0.25, 3 counts 0.375
+		content.hits = []repositories.SearchHit{{AlbumId: 2, Score: 1}, {AlbumId: 3, Score: 3}}
+
+		results, err := searchService.Search("train", 1, 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results.Total).Should(Equal(3))
+		Expect(ids(results)).Should(Equal([]uint{1, 2, 3}))
+		Expect(results.Results[1].Score).Should(BeNumerically("~", 0.45, 0.0001))
+		Expect(results.Results[2].Score).Should(BeNumerically("~", 0.375, 0.0001))
+	})
+
+	It("pages the merged results and breaks ties by album id", func() {
+		metadata.hits = []repositories.SearchHit{{AlbumId: 3, Score: 0.5}, {AlbumId: 1, Score: 0.5}}
+		content.hits = []repositories.SearchHit{{AlbumId: 2, Score: 1}}
+
+		results, err := searchService.Search("train", 1, 2)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results.Total).Should(Equal(3))
+		Expect(ids(results)).Should(Equal([]uint{1, 3}))
+
+		results, _ = searchService.Search("train", 2, 2)
+		Expect(ids(results)).Should(Equal([]uint{2}))
+	})
+
+	It("highlights inline content and the text of content kept in GridFS", func() {
+		fileId := primitive.NewObjectID()
+		content.hits = []repositories.SearchHit{{AlbumId: 1, Score: 1}, {AlbumId: 2, Score: 1}}
+		content.sources = []entities.AlbumMongoDB{
+			{AlbumId: 1, ContentHtml: "<p>Recorded at <em>Van Gelder</em></p>"},
+			{AlbumId: 2, ContentFileId: &fileId, ContentText: "Recorded in one take\nat Atlantic"},
+		}
+
+		results, err := searchService.Search("recorded", 1, 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results.Results[0].Highlights["content"]).Should(Equal("<mark>Recorded</mark> at Van Gelder"))
+		Expect(results.Results[1].Highlights["content"]).Should(Equal("<mark>Recorded</mark> in one take at Atlantic"))
+	})
+})
@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	libs "acy.com/api/src/lib"
+)
+
+var _ = Describe("Test Search Queries", func() {
+	parse := func(q string) libs.SearchQuery {
+		query, err := libs.ParseSearchQuery(q)
+		Expect(err).ShouldNot(HaveOccurred())
+		return query
+	}
+
+	It("parses words, phrases and prefixes", func() {
+		query := parse(`"A Love Supreme" colt* Rock-n-Roll "blue tr"*`)
+
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{
+			{Words: []string{"a", "love", "supreme"}},
+			{Words: []string{"colt"}, Prefix: true},
+			{Words: []string{"rock", "n", "roll"}},
+			{Words: []string{"blue", "tr"}, Prefix: true},
+		}))
+	})
+
+	It("rejects a query without words", func() {
+		_, err := libs.ParseSearchQuery(` "" * -- `)
+
+		Expect(err).To(MatchError(libs.ErrInvalidSearchQuery))
+	})
+
+	It("highlights matches and escapes the text", func() {
+		highlighted, ok := libs.HighlightSearch("<b>A Love Supreme</b> by John Coltrane", parse(`"love supreme" colt*`), 0)
+
+		Expect(ok).To(BeTrue())
+		Expect(highlighted).To(Equal("&lt;b&gt;A <mark>Love Supreme</mark>&lt;/b&gt; by John <mark>Coltrane</mark>"))
+	})
+
+	It("cuts long text to a snippet around the first match", func() {
+		text := "Side one opens with a long modal vamp before the band settles into the theme of Acknowledgement, the first part of the suite"
+		highlighted, ok := libs.HighlightSearch(text, parse("acknowledgement"), 60)
+
+		Expect(ok).To(BeTrue())
+		Expect(highlighted).To(HavePrefix("…"))
+		Expect(highlighted).To(HaveSuffix("…"))
+		Expect(highlighted).To(ContainSubstring("<mark>Acknowledgement</mark>"))
+	})
+
+	It("tells when nothing matches", func() {
+		highlighted, ok := libs.HighlightSearch("Blue Train", parse("supreme"), 0)
+
+		Expect(ok).To(BeFalse())
+		Expect(highlighted).To(Equal("Blue Train"))
+	})
+
+	It("collects the distinct words of texts for prefixes", func() {
+		Expect(libs.SearchTerms("A Love Supreme", "Love, love me do\nRock-n-Roll")).To(Equal([]string{"a", "do", "love", "me", "n", "rock", "roll", "supreme"}))
+		Expect(libs.SearchTerms("")).To(BeEmpty())
+	})
+
+	It("reads a prefix as words with the last one unfinished", func() {
+		query, err := libs.ParsePrefixQuery("Love Sup")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{{Words: []string{"love"}}, {Words: []string{"sup"}, Prefix: true}}))
+
+		query, _ = libs.ParsePrefixQuery("love ")
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{{Words: []string{"love"}}}))
+	})
+
+	It("completes a word being typed from its third letter", func() {
+		_, err := libs.ParsePrefixQuery("lo")
+		Expect(err).To(MatchError(libs.ErrShortSearchPrefix))
+		_, err = libs.ParsePrefixQuery("é")
+		Expect(err).To(MatchError(libs.ErrShortSearchPrefix))
+
+		query, err := libs.ParsePrefixQuery("love s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{{Words: []string{"love"}}}))
+
+		query, err = libs.ParsePrefixQuery("a ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{{Words: []string{"a"}}}))
+		query, _ = libs.ParsePrefixQuery("lov")
+		Expect(query.Terms).To(Equal([]libs.SearchTerm{{Words: []string{"lov"}, Prefix: true}}))
+	})
+
+	It("scores misspelt words by their shared trigrams", func() {
+		Expect(libs.WordSimilarity("coltrane", "John Coltrane")).To(Equal(1.0))
+		Expect(libs.WordSimilarity("coltrain", "John Coltrane")).To(BeNumerically("~", 6.0/9, 0.001))
+		Expect(libs.WordSimilarity("love supreme", "A Love Supreme")).To(Equal(1.0))
+		Expect(libs.WordSimilarity("coltrane", "Blue Train")).To(BeNumerically("<", 0.5))
+	})
+
+	It("highlights words similar to the query", func() {
+		highlighted, ok := libs.HighlightSimilar("A Love Supreme", "supreem", 0.5, 0)
+
+		Expect(ok).To(BeTrue())
+		Expect(highlighted).To(Equal("A Love <mark>Supreme</mark>"))
+	})
+})
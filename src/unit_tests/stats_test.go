@@ -0,0 +1,216 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeStatsRepository counts the stats it computes, failing with err
+type fakeStatsRepository struct {
+	computed int
+	err      error
+}
+
+func (repo *fakeStatsRepository) AlbumStats(groupBy string) (repositories.AlbumStats, error) {
+	repo.computed++
+	return repositories.AlbumStats{Total: 3}, repo.err
+}
+
+// fakeStatsMongoRepository counts the stats it computes
+type fakeStatsMongoRepository struct {
+	computed int
+}
+
+func (repo *fakeStatsMongoRepository) ContentStats() (repositories.ContentStats, error) {
+	repo.computed++
+	return repositories.ContentStats{WithContent: 2}, nil
+}
+
+// fakeUserAlbumReadRepository has read counts of users
+type fakeUserAlbumReadRepository struct {
+	counts map[string]int64
+}
+
+func (repo *fakeUserAlbumReadRepository) FindByUser(userId string, albumIds []uint) ([]entities.UserAlbumRead, error) {
+	return nil, nil
+}
+func (repo *fakeUserAlbumReadRepository) Upsert(read *entities.UserAlbumRead) (entities.UserAlbumRead, error) {
+	return *read, nil
+}
+func (repo *fakeUserAlbumReadRepository) Delete(userId string, albumId uint) error { return nil }
+func (repo *fakeUserAlbumReadRepository) CountByUser(userId string) (int64, error) {
+	return repo.counts[userId], nil
+}
+
+var _ = Describe("Album stats in PostgreSQL", func() {
+	var repository *repositories.StatsRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repository = repositories.NewStatsRepository(db)
+	})
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ShouldNot(HaveOccurred())
+	})
+
+	expectPrices := func() {
+		mock.ExpectQuery(`percentile_disc\(\$1::float8\[\]\) WITHIN GROUP \(ORDER BY price\) AS percentiles FROM "albums" GROUP BY "currency" ORDER BY count DESC, currency`).
+			WithArgs(pq.Float64Array{0.25, 0.5, 0.75, 0.9, 0.99}).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "min", "max", "avg", "percentiles"}).
+				AddRow("USD", 3, "5.00", "60.00", "25.0033333333333333", "{5.00,10.00,60.00,60.00,60.00}").
+				AddRow("JPY", 1, "1500", "1500", "1500.0000000000000000", "{1500,1500,1500,1500,1500}"))
+		mock.ExpectQuery(`SELECT count\(DISTINCT artist_id\) FROM "albums"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	}
+
+	It("picks the percentiles of every currency and rounds averages to its minor units", func() {
+		expectPrices()
+		mock.ExpectQuery(`JOIN artists ON artists.id = albums.artist_id GROUP BY artists.id, artists.name ORDER BY count DESC, artists.name LIMIT 100`).
+			WillReturnRows(sqlmock.NewRows([]string{"value", "name", "count"}).AddRow("1", "John Coltrane", 4))
+
+		stats, err := repository.AlbumStats(repositories.StatsGroupByArtist)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stats.Total).Should(Equal(int64(4)))
+		Expect(stats.ArtistCount).Should(Equal(int64(2)))
+		Expect(stats.Prices[0].Currency).Should(Equal("USD"))
+		Expect(stats.Prices[0].Avg.String()).Should(Equal("25"))
+		Expect(stats.Prices[0].Percentiles).Should(Equal(pq.StringArray{"5.00", "10.00", "60.00", "60.00", "60.00"}))
+		Expect(stats.Prices[1].Avg.String()).Should(Equal("1500"))
+		Expect(stats.Artists).Should(Equal([]repositories.FacetCount{{Value: "1", Name: "John Coltrane", Count: 4}}))
+		Expect(stats.PriceBuckets).Should(BeNil())
+	})
+
+	It("buckets the prices of every currency on their own, in the order of the prices", func() {
+		expectPrices()
+		mock.ExpectQuery(`SELECT currency, width_bucket\(albums.price, \$1::numeric\[\]\) AS bucket, count\(\*\) AS count FROM "albums" GROUP BY currency, bucket`).
+			WithArgs(pq.StringArray{"10", "20", "50", "100"}).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "bucket", "count"}).
+				AddRow("JPY", 4, 1).AddRow("USD", 0, 1).AddRow("USD", 4, 2))
+
+		stats, err := repository.AlbumStats(repositories.StatsGroupByPriceBucket)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stats.PriceBuckets).Should(HaveLen(2))
+		counts := func(buckets repositories.CurrencyPriceBuckets) []int64 {
+			values := []int64{}
+			for _, bucket := range buckets.Buckets {
+				values = append(values, bucket.Count)
+			}
+			return values
+		}
+		Expect(stats.PriceBuckets[0].Currency).Should(Equal("USD"))
+		Expect(counts(stats.PriceBuckets[0])).Should(Equal([]int64{1, 0, 0, 0, 2}))
+		Expect(stats.PriceBuckets[1].Currency).Should(Equal("JPY"))
+		Expect(counts(stats.PriceBuckets[1])).Should(Equal([]int64{0, 0, 0, 0, 1}))
+		Expect(stats.PriceBuckets[1].Buckets[0].From).Should(Equal(decimal.Zero))
+		Expect(stats.PriceBuckets[1].Buckets[4].To).Should(BeNil())
+	})
+})
+
+var _ = Describe("Content stats pipeline", func() {
+	It("sums up the sizes in one document with a summary and length buckets", func() {
+		pipeline := repositories.ContentStatsPipeline()
+		Expect(pipeline).Should(HaveLen(2))
+		Expect(pipeline[0][0].Key).Should(Equal("$project"))
+		Expect(pipeline[0][0].Value).Should(HaveKey("size"))
+		Expect(pipeline[1][0].Key).Should(Equal("$facet"))
+
+		facet := pipeline[1][0].Value.(bson.M)
+		Expect(facet).Should(HaveKey("summary"))
+		bucket := facet["lengths"].(bson.A)[0].(bson.M)["$bucket"].(bson.M)
+		Expect(bucket).Should(HaveKeyWithValue("groupBy", "$size"))
+		boundaries := bucket["boundaries"].(bson.A)
+		Expect(boundaries).Should(HaveLen(len(repositories.ContentLengthBuckets) + 2))
+		Expect(boundaries[0]).Should(Equal(int64(0)))
+		Expect(boundaries[1]).Should(Equal(repositories.ContentLengthBuckets[0]))
+		Expect(boundaries[len(boundaries)-1]).Should(Equal(int64(math.MaxInt64)))
+	})
+})
+
+var _ = Describe("Stats service", func() {
+	var albums *fakeStatsRepository
+	var content *fakeStatsMongoRepository
+	var reads *fakeUserAlbumReadRepository
+
+	newService := func(refresh time.Duration) services.IStatsService {
+		var repo repositories.IStatsRepository = albums
+		var contentRepo repositories.IStatsMongoDBRepository = content
+		var readRepo repositories.IUserAlbumReadRepository = reads
+		return services.StatsService(&repo, &contentRepo, &readRepo, services.StatsConfig{Refresh: refresh})
+	}
+
+	BeforeEach(func() {
+		albums = &fakeStatsRepository{}
+		content = &fakeStatsMongoRepository{}
+		reads = &fakeUserAlbumReadRepository{counts: map[string]int64{"reader": 2, "collector": 5}}
+	})
+
+	It("shares the stats of a group_by until they are refreshed", func() {
+		statsService := newService(time.Hour)
+		first, err := statsService.Stats(repositories.StatsGroupByArtist, "")
+		Expect(err).ShouldNot(HaveOccurred())
+		second, _ := statsService.Stats(repositories.StatsGroupByArtist, "reader")
+		Expect(second.ComputedAt).Should(Equal(first.ComputedAt))
+		Expect(albums.computed).Should(Equal(1))
+		Expect(content.computed).Should(Equal(1))
+
+		statsService.Stats(repositories.StatsGroupByPriceBucket, "")
+		Expect(albums.computed).Should(Equal(2))
+	})
+
+	It("computes the stats again once they are older than the refresh", func() {
+		statsService := newService(time.Millisecond)
+		statsService.Stats(repositories.StatsGroupByArtist, "")
+		time.Sleep(2 * time.Millisecond)
+		statsService.Stats(repositories.StatsGroupByArtist, "")
+		Expect(albums.computed).Should(Equal(2))
+	})
+
+	It("does not keep stats that failed", func() {
+		statsService := newService(time.Hour)
+		albums.err = errors.New("connection refused")
+		_, err := statsService.Stats(repositories.StatsGroupByArtist, "")
+		Expect(err).Should(HaveOccurred())
+		Expect(content.computed).Should(BeZero())
+
+		albums.err = nil
+		_, err = statsService.Stats(repositories.StatsGroupByArtist, "")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(albums.computed).Should(Equal(2))
+	})
+
+	It("counts the albums the caller has read, at most every album of the stats", func() {
+		statsService := newService(time.Hour)
+		stats, _ := statsService.Stats(repositories.StatsGroupByArtist, "reader")
+		Expect(stats.Read).Should(Equal(int64(2)))
+		stats, _ = statsService.Stats(repositories.StatsGroupByArtist, "collector")
+		Expect(stats.Read).Should(Equal(int64(3)))
+		stats, _ = statsService.Stats(repositories.StatsGroupByArtist, "")
+		Expect(stats.Read).Should(BeZero())
+	})
+
+	It("rejects an unknown group_by", func() {
+		_, err := newService(time.Hour).Stats("genre", "")
+		Expect(err).Should(Equal(services.ErrInvalidStatsGroupBy))
+		Expect(albums.computed).Should(BeZero())
+	})
+})
@@ -0,0 +1,156 @@
+package repository_test
+
+import (
+	"database/sql"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"acy.com/api/src/entities"
+	"acy.com/api/src/repositories"
+)
+
+var _ = Describe("Track repository", func() {
+	var repository *repositories.TrackRepository
+	var mock sqlmock.Sqlmock
+
+	BeforeEach(func() {
+		var db *sql.DB
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).ShouldNot(HaveOccurred())
+		repository = repositories.NewTrackRepository(db)
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).Should(Succeed())
+	})
+
+	const (
+		sqlLockAlbum   = `SELECT id FROM albums WHERE id = $1 FOR UPDATE`
+		sqlLastNumber  = `SELECT COALESCE(MAX(number), 0) FROM "tracks" WHERE album_id = $1 AND disc_number = $2 AND id <> $3`
+		sqlShift       = `UPDATE "tracks" SET "number"=number + $1,"updated_at"=$2 WHERE album_id = $3 AND disc_number = $4 AND number >= $5 AND id <> $6`
+		sqlInsert      = `INSERT INTO "tracks"`
+		sqlSelectTrack = `SELECT * FROM "tracks" WHERE album_id = $1 AND id = $2`
+	)
+
+	lockAlbum := func(albumId uint) {
+		mock.ExpectQuery(regexp.QuoteMeta(sqlLockAlbum)).WithArgs(albumId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(albumId))
+	}
+	lastNumber := func(albumId, disc, excluded uint, last int) {
+		mock.ExpectQuery(regexp.QuoteMeta(sqlLastNumber)).WithArgs(albumId, disc, excluded).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(last))
+	}
+	// shift expects the tracks of the disc from number on, but the one excluded, to move by delta
+	shift := func(delta int, albumId, disc, from, excluded uint) {
+		mock.ExpectExec(regexp.QuoteMeta(sqlShift)).WithArgs(delta, sqlmock.AnyArg(), albumId, disc, from, excluded).WillReturnResult(sqlmock.NewResult(0, 2))
+	}
+	selectTrack := func(track entities.Track) {
+		mock.ExpectQuery(regexp.QuoteMeta(sqlSelectTrack)).WithArgs(track.AlbumId, track.Id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "album_id", "disc_number", "number", "title"}).
+				AddRow(track.Id, track.AlbumId, track.DiscNumber, track.Number, track.Title))
+	}
+
+	Context("Insert", func() {
+		It("shifts the following tracks down to make room", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			lastNumber(1, 1, 0, 5)
+			shift(1, 1, 1, 2, 0)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectCommit()
+
+			track, err := repository.Insert(&entities.Track{AlbumId: 1, DiscNumber: 1, Number: 2, Title: "Naima"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(track.Id).Should(Equal(uint(7)))
+			Expect(track.Number).Should(Equal(uint(2)))
+		})
+
+		It("appends a track numbered past the last one", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			lastNumber(1, 1, 0, 5)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectCommit()
+
+			track, err := repository.Insert(&entities.Track{AlbumId: 1, DiscNumber: 1, Number: 9, Title: "Naima"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(track.Number).Should(Equal(uint(6)))
+		})
+
+		It("fails for albums that do not exist", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(sqlLockAlbum)).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectRollback()
+
+			_, err := repository.Insert(&entities.Track{AlbumId: 9, DiscNumber: 1, Title: "Naima"})
+			Expect(err).Should(MatchError(repositories.ErrAlbumNotFound))
+		})
+	})
+
+	Context("Move", func() {
+		It("closes the gap it leaves and shifts the tracks at its new place", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			selectTrack(entities.Track{Id: 3, AlbumId: 1, DiscNumber: 1, Number: 2, Title: "Naima"})
+			shift(-1, 1, 1, 3, 3)
+			lastNumber(1, 2, 3, 4)
+			shift(1, 1, 2, 1, 3)
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "tracks" SET "disc_number"=$1,"number"=$2`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			track, err := repository.Move(&entities.Track{Id: 3, AlbumId: 1, DiscNumber: 2, Number: 1, Title: "Naima"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(track.DiscNumber).Should(Equal(uint(2)))
+			Expect(track.Number).Should(Equal(uint(1)))
+		})
+
+		It("moves a track numbered past the last one to the end", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			selectTrack(entities.Track{Id: 3, AlbumId: 1, DiscNumber: 1, Number: 2, Title: "Naima"})
+			shift(-1, 1, 1, 3, 3)
+			lastNumber(1, 1, 3, 4)
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "tracks" SET "disc_number"=$1,"number"=$2`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			track, err := repository.Move(&entities.Track{Id: 3, AlbumId: 1, DiscNumber: 1, Number: 20, Title: "Naima"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(track.Number).Should(Equal(uint(5)))
+		})
+
+		It("fails for tracks of another album", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelectTrack)).WithArgs(1, 3).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectRollback()
+
+			_, err := repository.Move(&entities.Track{Id: 3, AlbumId: 1, DiscNumber: 1, Number: 1})
+			Expect(err).Should(MatchError(repositories.ErrTrackNotFound))
+		})
+	})
+
+	Context("Delete", func() {
+		It("moves the following tracks up", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			selectTrack(entities.Track{Id: 3, AlbumId: 1, DiscNumber: 1, Number: 2, Title: "Naima"})
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tracks" WHERE "tracks"."id" = $1`)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+			shift(-1, 1, 1, 3, 0)
+			mock.ExpectCommit()
+
+			Expect(repository.Delete(1, 3)).Should(Succeed())
+		})
+
+		It("fails for tracks that do not exist", func() {
+			mock.ExpectBegin()
+			lockAlbum(1)
+			mock.ExpectQuery(regexp.QuoteMeta(sqlSelectTrack)).WithArgs(1, 3).WillReturnRows(sqlmock.NewRows(nil))
+			mock.ExpectRollback()
+
+			Expect(repository.Delete(1, 3)).Should(MatchError(repositories.ErrTrackNotFound))
+		})
+	})
+})
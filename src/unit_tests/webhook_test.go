@@ -0,0 +1,308 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"acy.com/api/src/entities"
+	libs "acy.com/api/src/lib"
+	"acy.com/api/src/models"
+	"acy.com/api/src/repositories"
+	"acy.com/api/src/services"
+)
+
+// fakeWebhookRepository keeps subscriptions and deliveries in memory
+type fakeWebhookRepository struct {
+	subscriptions []entities.WebhookSubscription
+	deliveries    []entities.WebhookDelivery
+}
+
+func (repo *fakeWebhookRepository) FindSubscriptions() ([]entities.WebhookSubscription, error) {
+	return repo.subscriptions, nil
+}
+
+func (repo *fakeWebhookRepository) FindSubscription(id uint) (entities.WebhookSubscription, error) {
+	for _, subscription := range repo.subscriptions {
+		if subscription.Id == id {
+			return subscription, nil
+		}
+	}
+	return entities.WebhookSubscription{}, repositories.ErrWebhookNotFound
+}
+
+func (repo *fakeWebhookRepository) FindSubscriptionsByEvent(eventType string) ([]entities.WebhookSubscription, error) {
+	var found []entities.WebhookSubscription
+	for _, subscription := range repo.subscriptions {
+		for _, listening := range subscription.EventTypes {
+			if subscription.Active && (listening == eventType || listening == "*") {
+				found = append(found, subscription)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (repo *fakeWebhookRepository) CreateSubscription(subscription *entities.WebhookSubscription) (entities.WebhookSubscription, error) {
+	subscription.Id = uint(len(repo.subscriptions) + 1)
+	repo.subscriptions = append(repo.subscriptions, *subscription)
+	return *subscription, nil
+}
+
+func (repo *fakeWebhookRepository) DeleteSubscription(id uint) error {
+	return nil
+}
+
+func (repo *fakeWebhookRepository) CreateDelivery(delivery *entities.WebhookDelivery) (entities.WebhookDelivery, error) {
+	delivery.Id = uint(len(repo.deliveries) + 1)
+	repo.deliveries = append(repo.deliveries, *delivery)
+	return *delivery, nil
+}
+
+func (repo *fakeWebhookRepository) FindDelivery(id uint) (entities.WebhookDelivery, error) {
+	for _, delivery := range repo.deliveries {
+		if delivery.Id == id {
+			return delivery, nil
+		}
+	}
+	return entities.WebhookDelivery{}, repositories.ErrWebhookDeliveryNotFound
+}
+
+func (repo *fakeWebhookRepository) FindDeliveries(subscriptionId uint, page, pageSize int) ([]entities.WebhookDelivery, error) {
+	return repo.deliveries, nil
+}
+
+func (repo *fakeWebhookRepository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]entities.WebhookDelivery, error) {
+	var due []entities.WebhookDelivery
+	for i, delivery := range repo.deliveries {
+		if delivery.Status == entities.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) && len(due) < limit {
+			repo.deliveries[i].NextAttemptAt = now.Add(lease)
+			due = append(due, repo.deliveries[i])
+		}
+	}
+	return due, nil
+}
+
+func (repo *fakeWebhookRepository) SaveDelivery(delivery *entities.WebhookDelivery) error {
+	repo.deliveries[delivery.Id-1] = *delivery
+	return nil
+}
+
+// makeDue moves every pending delivery's next attempt into the past, as if the backoff elapsed
+func (repo *fakeWebhookRepository) makeDue() {
+	for i := range repo.deliveries {
+		repo.deliveries[i].NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+var _ = Describe("Test Webhooks", func() {
+	const secret = "a-webhook-secret-for-tests"
+
+	config := services.WebhookConfig{
+		BatchSize:   10,
+		Timeout:     time.Second,
+		BackoffBase: time.Minute,
+		BackoffMax:  10 * time.Minute,
+		MaxAttempts: 3,
+		// the receiver listens on localhost
+		AllowPrivateNetworks: true,
+	}
+
+	var repo *fakeWebhookRepository
+	var service services.IWebhookService
+	var receiver *httptest.Server
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	var statuses []int
+
+	// receive answers with the next queued status, 200 once they are used up
+	receive := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received, bodies = append(received, r), append(bodies, body)
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}
+
+	publish := func(eventType string) {
+		event := libs.Event{Id: 1, Type: eventType, Time: time.Now(), Data: models.AlbumEventResponse{AlbumId: 42}}
+		Expect(service.Enqueue(event)).Should(Succeed())
+	}
+
+	deliverDue := func() int {
+		sent, err := service.DeliverDue()
+		Expect(err).ShouldNot(HaveOccurred())
+		return sent
+	}
+
+	BeforeEach(func() {
+		received, bodies, statuses = nil, nil, nil
+		receiver = httptest.NewServer(http.HandlerFunc(receive))
+
+		repo = &fakeWebhookRepository{}
+		var repository repositories.IWebhookRepository = repo
+		service = services.WebhookService(&repository, config)
+
+		_, _, err := service.Create(receiver.URL, []string{models.AlbumEventCreated, models.AlbumEventDeleted}, secret, "admin-1")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		receiver.Close()
+	})
+
+	It("posts signed payloads of subscribed events only", func() {
+		publish(models.AlbumEventCreated)
+		publish(models.AlbumEventRead)
+		Expect(deliverDue()).Should(Equal(1))
+
+		Expect(received).Should(HaveLen(1))
+		request, body := received[0], bodies[0]
+		Expect(request.Header.Get("Content-Type")).Should(Equal("application/json"))
+		Expect(request.Header.Get(services.WebhookEventHeader)).Should(Equal(models.AlbumEventCreated))
+		Expect(request.Header.Get(services.WebhookDeliveryHeader)).Should(Equal("1"))
+
+		timestamp, err := strconv.ParseInt(request.Header.Get(services.WebhookTimestampHeader), 10, 64)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(request.Header.Get(services.WebhookSignatureHeader)).Should(Equal(services.SignWebhookPayload(secret, timestamp, body)))
+		Expect(request.Header.Get(services.WebhookSignatureHeader)).ShouldNot(Equal(services.SignWebhookPayload("another-secret", timestamp, body)))
+
+		var payload map[string]interface{}
+		Expect(json.Unmarshal(body, &payload)).Should(Succeed())
+		Expect(payload["type"]).Should(Equal(models.AlbumEventCreated))
+		Expect(payload["id"]).Should(HavePrefix("evt_"))
+		Expect(payload["data"]).Should(HaveKeyWithValue("album_id", BeNumerically("==", 42)))
+
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliverySucceeded))
+		Expect(repo.deliveries[0].Attempts).Should(Equal(1))
+		Expect(repo.deliveries[0].LastStatusCode).Should(Equal(http.StatusOK))
+		Expect(repo.deliveries[0].DeliveredAt).ShouldNot(BeNil())
+	})
+
+	It("retries failed deliveries with exponential backoff", func() {
+		statuses = []int{http.StatusInternalServerError, http.StatusServiceUnavailable}
+		publish(models.AlbumEventCreated)
+
+		before := time.Now()
+		Expect(deliverDue()).Should(Equal(1))
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliveryPending))
+		Expect(repo.deliveries[0].LastStatusCode).Should(Equal(http.StatusInternalServerError))
+		Expect(repo.deliveries[0].NextAttemptAt).Should(BeTemporally("~", before.Add(time.Minute), time.Second))
+
+		// not due before the backoff elapsed
+		Expect(deliverDue()).Should(Equal(0))
+
+		repo.makeDue()
+		before = time.Now()
+		Expect(deliverDue()).Should(Equal(1))
+		Expect(repo.deliveries[0].NextAttemptAt).Should(BeTemporally("~", before.Add(2*time.Minute), time.Second))
+
+		repo.makeDue()
+		Expect(deliverDue()).Should(Equal(1))
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliverySucceeded))
+		Expect(repo.deliveries[0].Attempts).Should(Equal(3))
+		Expect(received).Should(HaveLen(3))
+		// every attempt sends the same payload
+		Expect(bodies[2]).Should(Equal(bodies[0]))
+	})
+
+	It("dead-letters a delivery after the last attempt and redelivers it on request", func() {
+		statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadRequest}
+		publish(models.AlbumEventDeleted)
+
+		for i := 0; i < config.MaxAttempts; i++ {
+			repo.makeDue()
+			Expect(deliverDue()).Should(Equal(1))
+		}
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliveryDead))
+		Expect(repo.deliveries[0].LastStatusCode).Should(Equal(http.StatusBadRequest))
+		Expect(repo.deliveries[0].LastError).Should(ContainSubstring("400"))
+
+		repo.makeDue()
+		Expect(deliverDue()).Should(Equal(0))
+
+		history, err := service.Deliveries(1, 1, 50)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(history).Should(HaveLen(1))
+		Expect(history[0].Status).Should(Equal(entities.WebhookDeliveryDead))
+
+		delivery, err := service.Redeliver(1, 1)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(delivery.Status).Should(Equal(entities.WebhookDeliveryPending))
+		Expect(delivery.Attempts).Should(Equal(0))
+
+		Expect(deliverDue()).Should(Equal(1))
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliverySucceeded))
+		Expect(received).Should(HaveLen(4))
+	})
+
+	It("does not redeliver a delivery of another webhook", func() {
+		publish(models.AlbumEventCreated)
+		_, err := service.Redeliver(2, 1)
+		Expect(err).Should(MatchError(repositories.ErrWebhookDeliveryNotFound))
+	})
+
+	It("leaves out who read an album", func() {
+		_, _, err := service.Create(receiver.URL, []string{models.AlbumEventRead}, secret, "admin-1")
+		Expect(err).ShouldNot(HaveOccurred())
+		readAt := time.Now()
+		event := libs.Event{Id: 1, Type: models.AlbumEventRead, Time: readAt, Data: models.AlbumEventResponse{AlbumId: 42, UserId: "user-1", ReadAt: &readAt}}
+		Expect(service.Enqueue(event)).Should(Succeed())
+
+		Expect(repo.deliveries).Should(HaveLen(1))
+		Expect(repo.deliveries[0].Payload).ShouldNot(ContainSubstring("user-1"))
+		Expect(repo.deliveries[0].Payload).ShouldNot(ContainSubstring("user_id"))
+	})
+
+	It("does not follow redirects", func() {
+		redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusFound))
+		defer redirect.Close()
+		repo.subscriptions[0].Url = redirect.URL
+		publish(models.AlbumEventCreated)
+
+		Expect(deliverDue()).Should(Equal(1))
+		Expect(received).Should(BeEmpty())
+		Expect(repo.deliveries[0].LastStatusCode).Should(Equal(http.StatusFound))
+		Expect(repo.deliveries[0].Status).Should(Equal(entities.WebhookDeliveryPending))
+	})
+
+	It("refuses private addresses unless they are allowed", func() {
+		publicOnly := config
+		publicOnly.AllowPrivateNetworks = false
+		var repository repositories.IWebhookRepository = repo
+		service = services.WebhookService(&repository, publicOnly)
+
+		repo.subscriptions = nil
+		for _, url := range []string{receiver.URL, "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/hook", "http://[::1]:8080/hook"} {
+			_, _, err := service.Create(url, []string{models.AlbumEventCreated}, secret, "admin-1")
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		publish(models.AlbumEventCreated)
+
+		Expect(deliverDue()).Should(Equal(4))
+		Expect(received).Should(BeEmpty())
+		for _, delivery := range repo.deliveries {
+			Expect(delivery.LastError).Should(ContainSubstring(services.ErrWebhookAddressBlocked.Error()))
+		}
+	})
+
+	It("caps the backoff", func() {
+		Expect(services.WebhookBackoff(config, 1)).Should(Equal(time.Minute))
+		Expect(services.WebhookBackoff(config, 3)).Should(Equal(4 * time.Minute))
+		Expect(services.WebhookBackoff(config, 20)).Should(Equal(10 * time.Minute))
+	})
+})